@@ -13,6 +13,7 @@ type AssignExpression struct {
 
 func (ae *AssignExpression) expressionNode()      {}
 func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) Pos() token.Position  { return ae.Token.Pos }
 func (ae *AssignExpression) String() string {
 	var out bytes.Buffer
 
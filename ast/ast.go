@@ -2,11 +2,14 @@ package ast
 
 import (
 	"bytes"
+	"mutant/token"
 )
 
 type Node interface {
 	TokenLiteral() string
 	String() string
+	// Pos returns the source position of the node's leading token
+	Pos() token.Position
 }
 
 type Statement interface {
@@ -21,6 +24,7 @@ type Expression interface {
 
 type Program struct {
 	Statements []Statement
+	Comments   []token.Token // comment trivia collected by the lexer, in source order
 }
 
 func (p *Program) TokenLiteral() string {
@@ -30,6 +34,13 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
 func (p *Program) String() string {
 	var out bytes.Buffer
 
@@ -8,6 +8,7 @@ type BreakStatement struct {
 
 func (bs *BreakStatement) statementNode()       {}
 func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) Pos() token.Position  { return bs.Token.Pos }
 func (bs *BreakStatement) String() string {
 	return bs.TokenLiteral() + ";"
 }
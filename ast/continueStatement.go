@@ -8,6 +8,7 @@ type ContinueStatement struct {
 
 func (cs *ContinueStatement) statementNode()       {}
 func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) Pos() token.Position  { return cs.Token.Pos }
 func (cs *ContinueStatement) String() string {
 	return cs.TokenLiteral() + ";"
 }
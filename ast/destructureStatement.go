@@ -0,0 +1,62 @@
+package ast
+
+import (
+	"bytes"
+	"mutant/token"
+	"strings"
+)
+
+// PatternKind selects how a DestructureStatement unpacks its value
+type PatternKind int
+
+const (
+	ArrayPattern PatternKind = iota
+	HashPattern
+	StructPattern
+)
+
+// DestructureStatement binds several names from one value:
+//
+//	let [a, b, ...rest] = arr;
+//	let {status, body} = resp;
+//	let Point {x, y} = p;
+//
+// In array patterns the name `_` skips an element.
+type DestructureStatement struct {
+	Token  token.Token // LET token
+	Kind   PatternKind
+	Struct *Identifier // struct type of a StructPattern
+	Names  []*Identifier
+	Rest   *Identifier // optional `...rest` of an ArrayPattern
+	Value  Expression
+}
+
+func (ds *DestructureStatement) statementNode()       {}
+func (ds *DestructureStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DestructureStatement) Pos() token.Position  { return ds.Token.Pos }
+func (ds *DestructureStatement) String() string {
+	names := []string{}
+	for _, name := range ds.Names {
+		names = append(names, name.String())
+	}
+	if ds.Rest != nil {
+		names = append(names, "..."+ds.Rest.String())
+	}
+
+	var out bytes.Buffer
+	out.WriteString(ds.TokenLiteral() + " ")
+	switch ds.Kind {
+	case ArrayPattern:
+		out.WriteString("[" + strings.Join(names, ", ") + "]")
+	case HashPattern:
+		out.WriteString("{" + strings.Join(names, ", ") + "}")
+	case StructPattern:
+		out.WriteString(ds.Struct.String() + " {" + strings.Join(names, ", ") + "}")
+	}
+	out.WriteString(" = ")
+	if ds.Value != nil {
+		out.WriteString(ds.Value.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
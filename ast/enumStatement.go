@@ -10,15 +10,21 @@ type EnumStatement struct {
 	Token    token.Token
 	Name     *Identifier
 	Variants []*Identifier
+	Payloads map[string]*Identifier // payload name of each variant declared as Tag(name)
 }
 
 func (es *EnumStatement) statementNode()       {}
 func (es *EnumStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *EnumStatement) Pos() token.Position  { return es.Token.Pos }
 func (es *EnumStatement) String() string {
 	var out bytes.Buffer
 	variants := []string{}
 
 	for _, v := range es.Variants {
+		if payload, ok := es.Payloads[v.Value]; ok {
+			variants = append(variants, v.String()+"("+payload.String()+")")
+			continue
+		}
 		variants = append(variants, v.String())
 	}
 
@@ -13,6 +13,7 @@ type FieldExpression struct {
 
 func (fe *FieldExpression) expressionNode()      {}
 func (fe *FieldExpression) TokenLiteral() string { return fe.Token.Literal }
+func (fe *FieldExpression) Pos() token.Position  { return fe.Token.Pos }
 func (fe *FieldExpression) String() string {
 	var out bytes.Buffer
 
@@ -0,0 +1,42 @@
+package ast
+
+import (
+	"bytes"
+	"mutant/token"
+)
+
+// ForInStatement is `for (v in xs) { ... }` or `for (k, v in xs) { ... }`.
+// Key is nil in the single-variable form.
+type ForInStatement struct {
+	Token    token.Token
+	Key      *Identifier
+	Value    *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForInStatement) statementNode()       {}
+func (fs *ForInStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForInStatement) Pos() token.Position  { return fs.Token.Pos }
+func (fs *ForInStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	if fs.Key != nil {
+		out.WriteString(fs.Key.String())
+		out.WriteString(", ")
+	}
+	if fs.Value != nil {
+		out.WriteString(fs.Value.String())
+	}
+	out.WriteString(" in ")
+	if fs.Iterable != nil {
+		out.WriteString(fs.Iterable.String())
+	}
+	out.WriteString(") ")
+	if fs.Body != nil {
+		out.WriteString(fs.Body.String())
+	}
+
+	return out.String()
+}
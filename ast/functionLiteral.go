@@ -7,22 +7,57 @@ import (
 	"strings"
 )
 
+// FunctionLiteral is `fn(params) { body }`. Defaults runs parallel to
+// Parameters with a nil entry for each required parameter; only a suffix of
+// the parameters may have defaults. Rest, when set, collects the remaining
+// arguments into an array.
 type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
+	Defaults   []Expression
+	Rest       *Identifier
 	Body       *BlockStatement
 	Name       string
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() token.Position  { return fl.Token.Pos }
+
+// DefaultFor returns the default value of the i-th parameter, or nil if the
+// parameter is required.
+func (fl *FunctionLiteral) DefaultFor(i int) Expression {
+	if i < 0 || i >= len(fl.Defaults) {
+		return nil
+	}
+	return fl.Defaults[i]
+}
+
+// NumDefaults returns how many parameters have a default value.
+func (fl *FunctionLiteral) NumDefaults() int {
+	n := 0
+	for i := range fl.Parameters {
+		if fl.DefaultFor(i) != nil {
+			n++
+		}
+	}
+	return n
+}
+
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 	params := []string{}
 
-	for _, p := range fl.Parameters {
+	for i, p := range fl.Parameters {
+		if def := fl.DefaultFor(i); def != nil {
+			params = append(params, p.String()+" = "+def.String())
+			continue
+		}
 		params = append(params, p.String())
 	}
+	if fl.Rest != nil {
+		params = append(params, "..."+fl.Rest.String())
+	}
 
 	out.WriteString(fl.TokenLiteral())
 	if fl.Name != "" {
@@ -3,23 +3,48 @@ package ast
 import (
 	"bytes"
 	"mutant/token"
+	"sort"
 	"strings"
 )
 
+// HashLiteral is a {key: value} literal. Keys lists the keys in source
+// order; keys in Pairs but not in Keys come after them, sorted by String().
 type HashLiteral struct {
 	Token token.Token
 	Pairs map[Expression]Expression
+	Keys  []Expression
 }
 
 func (hl *HashLiteral) expressionNode()      {}
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Pos() token.Position  { return hl.Token.Pos }
+
+// OrderedKeys returns the keys of Pairs in source order
+func (hl *HashLiteral) OrderedKeys() []Expression {
+	keys := make([]Expression, 0, len(hl.Pairs))
+	seen := make(map[Expression]bool, len(hl.Keys))
+	for _, key := range hl.Keys {
+		if _, ok := hl.Pairs[key]; ok && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	rest := make([]Expression, 0, len(hl.Pairs)-len(keys))
+	for key := range hl.Pairs {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Slice(rest, func(i, j int) bool { return rest[i].String() < rest[j].String() })
+	return append(keys, rest...)
+}
 
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 	pairs := []string{}
 
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, key := range hl.OrderedKeys() {
+		pairs = append(pairs, key.String()+":"+hl.Pairs[key].String())
 	}
 
 	out.WriteString("{")
@@ -0,0 +1,30 @@
+package ast
+
+import (
+	"bytes"
+	"mutant/token"
+	"strconv"
+)
+
+// ImportStatement is `import "lib/util.mut"`, `import util` or either form
+// followed by `as name`. Name is the namespace the module's globals live under.
+type ImportStatement struct {
+	Token token.Token // IMPORT token
+	Path  string
+	Name  *Identifier
+}
+
+func (is *ImportStatement) statementNode()       {}
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) Pos() token.Position  { return is.Token.Pos }
+func (is *ImportStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(is.TokenLiteral() + " ")
+	out.WriteString(strconv.Quote(is.Path))
+	if is.Name != nil {
+		out.WriteString(" as ")
+		out.WriteString(is.Name.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
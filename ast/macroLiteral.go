@@ -14,6 +14,7 @@ type MacroLiteral struct {
 
 func (ml *MacroLiteral) expressionNode()      {}
 func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) Pos() token.Position  { return ml.Token.Pos }
 func (ml *MacroLiteral) String() string {
 	var out bytes.Buffer
 
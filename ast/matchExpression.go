@@ -0,0 +1,158 @@
+package ast
+
+import (
+	"bytes"
+	"fmt"
+	"mutant/token"
+	"strings"
+)
+
+// MatchExpression is `match (subject) { pattern => body, ... }`. Arms are
+// tried in order and the value of the first matching body is the result.
+type MatchExpression struct {
+	Token   token.Token // MATCH token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) Pos() token.Position  { return me.Token.Pos }
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	out.WriteString("match (")
+	out.WriteString(me.Subject.String())
+	out.WriteString(") { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// MatchArm is one `pattern => body` of a match. Exactly one pattern form is
+// set: Wildcard for `_`, Enum and Tag for `Enum.Tag` or `Enum.Tag(binding)`,
+// or Literal for an integer, float, string or boolean literal.
+type MatchArm struct {
+	Token    token.Token // first token of the pattern
+	Wildcard bool
+	Enum     *Identifier
+	Tag      *Identifier
+	Binding  *Identifier // nil when the payload is not bound
+	Literal  Expression
+	Body     *BlockStatement
+}
+
+func (ma *MatchArm) TokenLiteral() string { return ma.Token.Literal }
+func (ma *MatchArm) Pos() token.Position  { return ma.Token.Pos }
+func (ma *MatchArm) String() string {
+	return ma.Pattern() + " => " + ma.Body.String()
+}
+
+// Pattern renders the arm's pattern as written
+func (ma *MatchArm) Pattern() string {
+	switch {
+	case ma.Wildcard:
+		return "_"
+	case ma.Tag != nil:
+		pattern := ma.Enum.String() + "." + ma.Tag.String()
+		if ma.Binding != nil {
+			pattern += "(" + ma.Binding.String() + ")"
+		}
+		return pattern
+	default:
+		return ma.Literal.String()
+	}
+}
+
+// EnumVariants describes a declared enum for match checking: its tags in
+// declaration order and which of them carry a payload.
+type EnumVariants struct {
+	Tags     []string
+	Payloads map[string]bool
+}
+
+// Check validates the arms against the declared enums and reports the first
+// problem: unknown or repeated tags, payload bindings on bare tags, arms after
+// `_`, mixed pattern kinds, or a match that is not exhaustive. lookup returns
+// the variants of a declared enum. Node is where the problem should be reported.
+func (me *MatchExpression) Check(lookup func(enum string) (EnumVariants, bool)) (Node, string) {
+	var enumName string
+	var variants EnumVariants
+	covered := map[string]bool{}
+	hasLiteral := false
+	hasWildcard := false
+
+	for _, arm := range me.Arms {
+		if hasWildcard {
+			return arm, "unreachable match arm after `_`"
+		}
+
+		switch {
+		case arm.Wildcard:
+			hasWildcard = true
+		case arm.Tag != nil:
+			if hasLiteral {
+				return arm, "match arms mix enum and literal patterns"
+			}
+			if enumName == "" {
+				found, ok := lookup(arm.Enum.Value)
+				if !ok {
+					return arm, fmt.Sprintf("unknown enum %s", arm.Enum.Value)
+				}
+				enumName, variants = arm.Enum.Value, found
+			} else if arm.Enum.Value != enumName {
+				return arm, fmt.Sprintf("match arms mix enums %s and %s", enumName, arm.Enum.Value)
+			}
+
+			tag := arm.Tag.Value
+			if !containsString(variants.Tags, tag) {
+				return arm, fmt.Sprintf("unknown enum tag %s.%s", enumName, tag)
+			}
+			if covered[tag] {
+				return arm, fmt.Sprintf("duplicate match arm for %s.%s", enumName, tag)
+			}
+			if arm.Binding != nil && !variants.Payloads[tag] {
+				return arm, fmt.Sprintf("enum variant %s.%s has no payload to bind", enumName, tag)
+			}
+			covered[tag] = true
+		default:
+			if enumName != "" {
+				return arm, "match arms mix enum and literal patterns"
+			}
+			hasLiteral = true
+		}
+	}
+
+	if hasWildcard {
+		return nil, ""
+	}
+	if enumName == "" {
+		return me, "non-exhaustive match: add a `_` arm"
+	}
+
+	missing := []string{}
+	for _, tag := range variants.Tags {
+		if !covered[tag] {
+			missing = append(missing, tag)
+		}
+	}
+	if len(missing) > 0 {
+		return me, fmt.Sprintf("non-exhaustive match on %s: missing %s", enumName, strings.Join(missing, ", "))
+	}
+
+	return nil, ""
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
@@ -15,6 +15,8 @@ func Modify(node Node, modifier ModifierFunc) Node {
 		node.Right, _ = Modify(node.Right, modifier).(Expression)
 	case *PrefixExpression:
 		node.Right, _ = Modify(node.Right, modifier).(Expression)
+	case *StringifyExpression:
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
 	case *IndexExpression:
 		node.Left, _ = Modify(node.Left, modifier).(Expression)
 		node.Index, _ = Modify(node.Index, modifier).(Expression)
@@ -32,10 +34,17 @@ func Modify(node Node, modifier ModifierFunc) Node {
 		node.ReturnValue, _ = Modify(node.ReturnValue, modifier).(Expression)
 	case *LetStatement:
 		node.Value, _ = Modify(node.Value, modifier).(Expression)
+	case *DestructureStatement:
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
 	case *FunctionLiteral:
 		for i := range node.Parameters {
 			node.Parameters[i], _ = Modify(node.Parameters[i], modifier).(*Identifier)
 		}
+		for i := range node.Defaults {
+			if node.Defaults[i] != nil {
+				node.Defaults[i], _ = Modify(node.Defaults[i], modifier).(Expression)
+			}
+		}
 		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
 	case *ArrayLiteral:
 		for i := range node.Elements {
@@ -43,12 +52,15 @@ func Modify(node Node, modifier ModifierFunc) Node {
 		}
 	case *HashLiteral:
 		newPairs := make(map[Expression]Expression)
-		for key, val := range node.Pairs {
+		newKeys := make([]Expression, 0, len(node.Pairs))
+		for _, key := range node.OrderedKeys() {
 			newKey, _ := Modify(key, modifier).(Expression)
-			newVal, _ := Modify(val, modifier).(Expression)
+			newVal, _ := Modify(node.Pairs[key], modifier).(Expression)
 			newPairs[newKey] = newVal
+			newKeys = append(newKeys, newKey)
 		}
 		node.Pairs = newPairs
+		node.Keys = newKeys
 	}
 
 	return modifier(node)
@@ -0,0 +1,28 @@
+package ast
+
+import (
+	"bytes"
+	"mutant/token"
+)
+
+// RangeExpression is the half-open integer range `Start..End`
+type RangeExpression struct {
+	Token token.Token
+	Start Expression
+	End   Expression
+}
+
+func (re *RangeExpression) expressionNode()      {}
+func (re *RangeExpression) TokenLiteral() string { return re.Token.Literal }
+func (re *RangeExpression) Pos() token.Position  { return re.Token.Pos }
+func (re *RangeExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(re.Start.String())
+	out.WriteString("..")
+	out.WriteString(re.End.String())
+	out.WriteString(")")
+
+	return out.String()
+}
@@ -0,0 +1,24 @@
+package ast
+
+import (
+	"bytes"
+	"mutant/token"
+)
+
+// StringifyExpression converts its value to a string; the parser emits it for
+// each ${...} segment of an interpolated string literal
+type StringifyExpression struct {
+	Token token.Token // the TEMPLATE token the segment came from
+	Value Expression
+}
+
+func (se *StringifyExpression) expressionNode()      {}
+func (se *StringifyExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *StringifyExpression) Pos() token.Position  { return se.Value.Pos() }
+func (se *StringifyExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("str(")
+	out.WriteString(se.Value.String())
+	out.WriteString(")")
+	return out.String()
+}
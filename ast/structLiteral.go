@@ -19,6 +19,7 @@ type StructLiteral struct {
 
 func (sl *StructLiteral) expressionNode()      {}
 func (sl *StructLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StructLiteral) Pos() token.Position  { return sl.Token.Pos }
 func (sl *StructLiteral) String() string {
 	var out bytes.Buffer
 	fields := []string{}
@@ -7,19 +7,24 @@ import (
 )
 
 type StructStatement struct {
-	Token  token.Token
-	Name   *Identifier
-	Fields []*Identifier
+	Token   token.Token
+	Name    *Identifier
+	Fields  []*Identifier
+	Methods []*StructMethod
 }
 
 func (ss *StructStatement) statementNode()       {}
 func (ss *StructStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *StructStatement) Pos() token.Position  { return ss.Token.Pos }
 func (ss *StructStatement) String() string {
 	var out bytes.Buffer
-	fields := []string{}
+	members := []string{}
 
 	for _, f := range ss.Fields {
-		fields = append(fields, f.String())
+		members = append(members, f.String()+";")
+	}
+	for _, m := range ss.Methods {
+		members = append(members, m.String())
 	}
 
 	out.WriteString("struct ")
@@ -27,12 +32,29 @@ func (ss *StructStatement) String() string {
 		out.WriteString(ss.Name.String())
 	}
 	out.WriteString(" {")
-	if len(fields) > 0 {
+	if len(members) > 0 {
 		out.WriteString(" ")
-		out.WriteString(strings.Join(fields, "; "))
-		out.WriteString(";")
+		out.WriteString(strings.Join(members, " "))
 	}
 	out.WriteString(" }")
 
 	return out.String()
 }
+
+// StructMethod is a `fn name(params) { ... }` declared inside a struct body.
+// The receiver is bound to the implicit first parameter `self`.
+type StructMethod struct {
+	Token    token.Token
+	Name     *Identifier
+	Function *FunctionLiteral
+}
+
+func (sm *StructMethod) TokenLiteral() string { return sm.Token.Literal }
+func (sm *StructMethod) Pos() token.Position  { return sm.Token.Pos }
+func (sm *StructMethod) String() string {
+	params := []string{}
+	for _, p := range sm.Function.Parameters {
+		params = append(params, p.String())
+	}
+	return "fn " + sm.Name.String() + "(" + strings.Join(params, ", ") + ") " + sm.Function.Body.String()
+}
@@ -0,0 +1,48 @@
+package ast
+
+import (
+	"bytes"
+	"mutant/token"
+)
+
+// TryStatement is `try { } catch (e) { } finally { }`. Catch or Finally may be
+// nil, but not both; CatchParam is nil for a bare `catch { }`.
+type TryStatement struct {
+	Token      token.Token // TRY token
+	Block      *BlockStatement
+	CatchParam *Identifier
+	Catch      *BlockStatement
+	Finally    *BlockStatement
+}
+
+func (ts *TryStatement) statementNode()       {}
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *TryStatement) Pos() token.Position  { return ts.Token.Pos }
+func (ts *TryStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("try { " + ts.Block.String() + " }")
+	if ts.Catch != nil {
+		out.WriteString(" catch ")
+		if ts.CatchParam != nil {
+			out.WriteString("(" + ts.CatchParam.String() + ") ")
+		}
+		out.WriteString("{ " + ts.Catch.String() + " }")
+	}
+	if ts.Finally != nil {
+		out.WriteString(" finally { " + ts.Finally.String() + " }")
+	}
+	return out.String()
+}
+
+// ThrowStatement raises Value as an exception
+type ThrowStatement struct {
+	Token token.Token // THROW token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) Pos() token.Position  { return ts.Token.Pos }
+func (ts *ThrowStatement) String() string {
+	return ts.TokenLiteral() + " " + ts.Value.String() + ";"
+}
@@ -4,10 +4,36 @@ import (
 	"fmt"
 
 	"mutant/object"
+	"mutant/security"
 )
 
 type BuiltinFunction func(args ...object.Object) object.Object
-type BuiltIn struct{ Fn BuiltinFunction }
+
+// Caller is the running program as seen by a builtin. The VM implements it.
+type Caller interface {
+	// CallFunction runs a mutant function value (closure, bound method or
+	// builtin) to completion.
+	CallFunction(fn object.Object, args ...object.Object) (object.Object, error)
+	// Regexps returns the program's cache of compiled patterns.
+	Regexps() *RegexpCache
+	// StructFields returns the declared field names of a struct type.
+	StructFields(typeName string) ([]string, bool)
+	// EnumVariants returns the tag names of an enum type.
+	EnumVariants(typeName string) ([]string, bool)
+	// EnumHasPayload reports whether a tag of an enum type carries a payload.
+	EnumHasPayload(typeName, tag string) bool
+}
+
+// CallbackFunction is a builtin that needs the running program, to call back
+// into mutant functions or to use state kept per program. A returned error,
+// such as a value thrown by a callback, is raised in the calling program.
+type CallbackFunction func(caller Caller, args ...object.Object) (object.Object, error)
+
+// BuiltIn is a native function. Exactly one of Fn and Callback is set.
+type BuiltIn struct {
+	Fn       BuiltinFunction
+	Callback CallbackFunction
+}
 
 func (b *BuiltIn) Type() object.ObjectType { return object.BUILTIN_OBJ }
 func (b *BuiltIn) Inspect() string         { return "builtin funciton" }
@@ -17,55 +43,140 @@ var Builtins = []struct {
 	Builtin            *BuiltIn
 	RequiredCapability string
 }{
-	{"len", &BuiltIn{Len}, ""},
-	{"putf", &BuiltIn{Putf}, ""},
-	{"putln", &BuiltIn{Putln}, ""},
-	{"gets", &BuiltIn{Gets}, ""},
-	{"first", &BuiltIn{First}, ""},
-	{"last", &BuiltIn{Last}, ""},
-	{"rest", &BuiltIn{Rest}, ""},
-	{"push", &BuiltIn{Push}, ""},
-	{"pop", &BuiltIn{Pop}, ""},
-	{"debug_status", &BuiltIn{DebugStatus}, ""},
-	{"sandbox_status", &BuiltIn{SandboxStatus}, ""},
-	{"security_diagnostics", &BuiltIn{SecurityDiagnostics}, ""},
-	{"exec_string", &BuiltIn{ExecString}, "command_exec"},
-	{"cmd_builder", &BuiltIn{CmdBuilder}, "command_exec"},
-	{"cmd_add", &BuiltIn{CmdAdd}, "command_exec"},
-	{"cmd_run", &BuiltIn{CmdRun}, "command_exec"},
+	{"len", &BuiltIn{Fn: Len}, ""},
+	{"putf", &BuiltIn{Fn: Putf}, ""},
+	{"putln", &BuiltIn{Fn: Putln}, ""},
+	{"gets", &BuiltIn{Fn: Gets}, ""},
+	{"first", &BuiltIn{Fn: First}, ""},
+	{"last", &BuiltIn{Fn: Last}, ""},
+	{"rest", &BuiltIn{Fn: Rest}, ""},
+	{"push", &BuiltIn{Fn: Push}, ""},
+	{"pop", &BuiltIn{Fn: Pop}, ""},
+	{"debug_status", &BuiltIn{Fn: DebugStatus}, ""},
+	{"sandbox_status", &BuiltIn{Fn: SandboxStatus}, ""},
+	{"security_diagnostics", &BuiltIn{Fn: SecurityDiagnostics}, ""},
+	{"exec_string", &BuiltIn{Fn: ExecString}, security.CapabilityCommandExec},
+	{"cmd_builder", &BuiltIn{Fn: CmdBuilder}, security.CapabilityCommandExec},
+	{"cmd_add", &BuiltIn{Fn: CmdAdd}, security.CapabilityCommandExec},
+	{"cmd_run", &BuiltIn{Fn: CmdRun}, security.CapabilityCommandExec},
 	// file system
-	{"fs_read", &BuiltIn{FsRead}, "filesystem"},
-	{"fs_write", &BuiltIn{FsWrite}, "filesystem"},
-	{"fs_append", &BuiltIn{FsAppend}, "filesystem"},
-	{"fs_delete", &BuiltIn{FsDelete}, "filesystem"},
-	{"fs_exists", &BuiltIn{FsExists}, "filesystem"},
-	{"fs_stat", &BuiltIn{FsStat}, "filesystem"},
-	{"fs_list", &BuiltIn{FsList}, "filesystem"},
-	{"fs_mkdir", &BuiltIn{FsMkdir}, "filesystem"},
-	{"fs_copy", &BuiltIn{FsCopy}, "filesystem"},
-	{"fs_move", &BuiltIn{FsMove}, "filesystem"},
+	{"fs_read", &BuiltIn{Fn: FsRead}, security.CapabilityFilesystem},
+	{"fs_write", &BuiltIn{Fn: FsWrite}, security.CapabilityFilesystem},
+	{"fs_append", &BuiltIn{Fn: FsAppend}, security.CapabilityFilesystem},
+	{"fs_delete", &BuiltIn{Fn: FsDelete}, security.CapabilityFilesystem},
+	{"fs_exists", &BuiltIn{Fn: FsExists}, security.CapabilityFilesystem},
+	{"fs_stat", &BuiltIn{Fn: FsStat}, security.CapabilityFilesystem},
+	{"fs_list", &BuiltIn{Fn: FsList}, security.CapabilityFilesystem},
+	{"fs_mkdir", &BuiltIn{Fn: FsMkdir}, security.CapabilityFilesystem},
+	{"fs_copy", &BuiltIn{Fn: FsCopy}, security.CapabilityFilesystem},
+	{"fs_move", &BuiltIn{Fn: FsMove}, security.CapabilityFilesystem},
 	// network
-	{"net_resolve", &BuiltIn{NetResolve}, "network"},
-	{"net_dial", &BuiltIn{NetDial}, "network"},
+	{"net_resolve", &BuiltIn{Fn: NetResolve}, security.CapabilityNetwork},
+	{"net_dial", &BuiltIn{Fn: NetDial}, security.CapabilityNetwork},
 	// http
-	{"http_get", &BuiltIn{HttpGet}, "network"},
-	{"http_post", &BuiltIn{HttpPost}, "network"},
-	{"http_request", &BuiltIn{HttpRequest}, "network"},
+	{"http_get", &BuiltIn{Fn: HttpGet}, security.CapabilityNetwork},
+	{"http_post", &BuiltIn{Fn: HttpPost}, security.CapabilityNetwork},
+	{"http_request", &BuiltIn{Fn: HttpRequest}, security.CapabilityNetwork},
 	// lua
-	{"lua_run_string", &BuiltIn{LuaRunString}, ""},
-	{"lua_run_file", &BuiltIn{LuaRunFile}, "filesystem"},
-	{"lua_run_http", &BuiltIn{LuaRunHTTP}, "network"},
+	{"lua_run_string", &BuiltIn{Fn: LuaRunString}, ""},
+	{"lua_run_file", &BuiltIn{Fn: LuaRunFile}, security.CapabilityFilesystem},
+	{"lua_run_http", &BuiltIn{Fn: LuaRunHTTP}, security.CapabilityNetwork},
 	// graph db
-	{"db_open", &BuiltIn{DbOpen}, ""},
-	{"db_open_disk", &BuiltIn{DbOpenDisk}, ""},
-	{"db_close", &BuiltIn{DbClose}, ""},
-	{"db_add_node", &BuiltIn{DbAddNode}, ""},
-	{"db_add_edge", &BuiltIn{DbAddEdge}, ""},
-	{"db_index_prop", &BuiltIn{DbIndexProp}, ""},
-	{"db_query_nodes", &BuiltIn{DbQueryNodes}, ""},
-	{"db_bfs", &BuiltIn{DbBFS}, ""},
-	{"db_shortest_path", &BuiltIn{DbShortestPath}, ""},
-	{"db_stats", &BuiltIn{DbStats}, ""},
+	{"db_open", &BuiltIn{Fn: DbOpen}, ""},
+	{"db_open_disk", &BuiltIn{Fn: DbOpenDisk}, ""},
+	{"db_close", &BuiltIn{Fn: DbClose}, ""},
+	{"db_add_node", &BuiltIn{Fn: DbAddNode}, ""},
+	{"db_add_edge", &BuiltIn{Fn: DbAddEdge}, ""},
+	{"db_index_prop", &BuiltIn{Fn: DbIndexProp}, ""},
+	{"db_query_nodes", &BuiltIn{Fn: DbQueryNodes}, ""},
+	{"db_bfs", &BuiltIn{Fn: DbBFS}, ""},
+	{"db_shortest_path", &BuiltIn{Fn: DbShortestPath}, ""},
+	{"db_stats", &BuiltIn{Fn: DbStats}, ""},
+	// conversions
+	{"str", &BuiltIn{Fn: Str}, ""},
+	{"int", &BuiltIn{Fn: Int}, ""},
+	{"float", &BuiltIn{Fn: Float}, ""},
+	{"parse_int", &BuiltIn{Fn: ParseInt}, ""},
+	// math
+	{"abs", &BuiltIn{Fn: Abs}, ""},
+	{"min", &BuiltIn{Fn: Min}, ""},
+	{"max", &BuiltIn{Fn: Max}, ""},
+	{"floor", &BuiltIn{Fn: Floor}, ""},
+	{"ceil", &BuiltIn{Fn: Ceil}, ""},
+	{"round", &BuiltIn{Fn: Round}, ""},
+	{"sqrt", &BuiltIn{Fn: Sqrt}, ""},
+	{"pow", &BuiltIn{Fn: Pow}, ""},
+	{"log", &BuiltIn{Fn: Log}, ""},
+	{"exp", &BuiltIn{Fn: Exp}, ""},
+	// strings
+	{"split", &BuiltIn{Fn: Split}, ""},
+	{"join", &BuiltIn{Fn: Join}, ""},
+	{"replace", &BuiltIn{Fn: Replace}, ""},
+	{"trim", &BuiltIn{Fn: Trim}, ""},
+	{"upper", &BuiltIn{Fn: Upper}, ""},
+	{"lower", &BuiltIn{Fn: Lower}, ""},
+	{"contains", &BuiltIn{Fn: Contains}, ""},
+	{"starts_with", &BuiltIn{Fn: StartsWith}, ""},
+	{"ends_with", &BuiltIn{Fn: EndsWith}, ""},
+	{"index_of", &BuiltIn{Fn: IndexOf}, ""},
+	{"repeat", &BuiltIn{Fn: Repeat}, ""},
+	{"pad", &BuiltIn{Fn: Pad}, ""},
+	{"substr", &BuiltIn{Fn: Substr}, ""},
+	// hashes
+	{"keys", &BuiltIn{Fn: Keys}, ""},
+	{"values", &BuiltIn{Fn: Values}, ""},
+	{"items", &BuiltIn{Fn: Items}, ""},
+	{"has_key", &BuiltIn{Fn: HasKey}, ""},
+	{"delete", &BuiltIn{Fn: Delete}, ""},
+	{"merge", &BuiltIn{Fn: Merge}, ""},
+	{"hash_len", &BuiltIn{Fn: HashLen}, ""},
+	// json
+	{"json_encode", &BuiltIn{Callback: JsonEncode}, ""},
+	{"json_decode", &BuiltIn{Fn: JsonDecode}, ""},
+	// introspection
+	{"type_of", &BuiltIn{Fn: TypeOf}, ""},
+	{"is_int", &BuiltIn{Fn: IsInt}, ""},
+	{"is_float", &BuiltIn{Fn: IsFloat}, ""},
+	{"is_number", &BuiltIn{Fn: IsNumber}, ""},
+	{"is_string", &BuiltIn{Fn: IsString}, ""},
+	{"is_bool", &BuiltIn{Fn: IsBool}, ""},
+	{"is_array", &BuiltIn{Fn: IsArray}, ""},
+	{"is_hash", &BuiltIn{Fn: IsHash}, ""},
+	{"is_null", &BuiltIn{Fn: IsNull}, ""},
+	{"is_function", &BuiltIn{Fn: IsFunction}, ""},
+	{"is_struct", &BuiltIn{Fn: IsStruct}, ""},
+	{"is_enum", &BuiltIn{Fn: IsEnum}, ""},
+	{"is_error", &BuiltIn{Fn: IsError}, ""},
+	{"fields", &BuiltIn{Callback: Fields}, ""},
+	{"has_field", &BuiltIn{Fn: HasField}, ""},
+	{"get_field", &BuiltIn{Fn: GetField}, ""},
+	{"set_field", &BuiltIn{Fn: SetField}, ""},
+	{"enum_variants", &BuiltIn{Callback: EnumVariants}, ""},
+	{"enum_tag", &BuiltIn{Fn: EnumTag}, ""},
+	// regular expressions
+	{"re_match", &BuiltIn{Callback: ReMatch}, ""},
+	{"re_find_all", &BuiltIn{Callback: ReFindAll}, ""},
+	{"re_replace", &BuiltIn{Callback: ReReplace}, ""},
+	{"re_split", &BuiltIn{Callback: ReSplit}, ""},
+	{"re_captures", &BuiltIn{Callback: ReCaptures}, ""},
+	// higher-order
+	{"map", &BuiltIn{Callback: Map}, ""},
+	{"filter", &BuiltIn{Callback: Filter}, ""},
+	{"reduce", &BuiltIn{Callback: Reduce}, ""},
+	{"sort_by", &BuiltIn{Callback: SortBy}, ""},
+	{"any", &BuiltIn{Callback: Any}, ""},
+	{"all", &BuiltIn{Callback: All}, ""},
+	{"each", &BuiltIn{Callback: Each}, ""},
+}
+
+// GetBuiltinIndex returns the position of the named builtin in Builtins, or -1
+func GetBuiltinIndex(name string) int {
+	for i, fun := range Builtins {
+		if name == fun.Name {
+			return i
+		}
+	}
+	return -1
 }
 
 func GetBuiltinByName(name string) *BuiltIn {
@@ -0,0 +1,37 @@
+package builtin
+
+// registeredBuiltin is the name and capability group a builtin value was
+// registered under
+type registeredBuiltin struct {
+	name       string
+	capability string
+}
+
+// registered indexes Builtins by value, so that the capability check made on
+// every builtin call does not scan the table
+var registered = indexBuiltins()
+
+func indexBuiltins() map[*BuiltIn]registeredBuiltin {
+	index := make(map[*BuiltIn]registeredBuiltin, len(Builtins))
+	for _, fun := range Builtins {
+		index[fun.Builtin] = registeredBuiltin{name: fun.Name, capability: fun.RequiredCapability}
+	}
+	return index
+}
+
+// BuiltinCapability returns the capability group required by the named builtin.
+// The boolean result is false when no builtin with that name is registered.
+func BuiltinCapability(name string) (string, bool) {
+	for _, fun := range Builtins {
+		if name == fun.Name {
+			return fun.RequiredCapability, true
+		}
+	}
+	return "", false
+}
+
+// LookupBuiltin resolves a builtin value back to its registered name and required capability.
+func LookupBuiltin(fn *BuiltIn) (string, string, bool) {
+	entry, ok := registered[fn]
+	return entry.name, entry.capability, ok
+}
@@ -0,0 +1,56 @@
+package builtin
+
+import (
+	"testing"
+
+	"mutant/security"
+)
+
+func TestBuiltinCapabilityReportsRegisteredGroups(t *testing.T) {
+	tests := []struct {
+		name       string
+		capability string
+	}{
+		{"len", ""},
+		{"fs_read", security.CapabilityFilesystem},
+		{"http_get", security.CapabilityNetwork},
+		{"cmd_run", security.CapabilityCommandExec},
+	}
+
+	for _, tt := range tests {
+		capability, ok := BuiltinCapability(tt.name)
+		if !ok {
+			t.Fatalf("expected %s to be registered", tt.name)
+		}
+		if capability != tt.capability {
+			t.Fatalf("expected %s to require %q, got %q", tt.name, tt.capability, capability)
+		}
+	}
+
+	if _, ok := BuiltinCapability("does_not_exist"); ok {
+		t.Fatalf("expected unknown builtin lookup to fail")
+	}
+}
+
+func TestLookupBuiltinResolvesRegisteredValue(t *testing.T) {
+	name, capability, ok := LookupBuiltin(GetBuiltinByName("net_dial"))
+	if !ok {
+		t.Fatalf("expected net_dial to resolve")
+	}
+	if name != "net_dial" || capability != security.CapabilityNetwork {
+		t.Fatalf("unexpected lookup result: name=%q capability=%q", name, capability)
+	}
+
+	if _, _, ok := LookupBuiltin(&BuiltIn{Fn: Len}); ok {
+		t.Fatalf("expected unregistered builtin value to fail lookup")
+	}
+}
+
+func TestLookupBuiltinResolvesEveryRegisteredBuiltin(t *testing.T) {
+	for _, fun := range Builtins {
+		name, capability, ok := LookupBuiltin(fun.Builtin)
+		if !ok || name != fun.Name || capability != fun.RequiredCapability {
+			t.Fatalf("%s: got name=%q capability=%q ok=%v", fun.Name, name, capability, ok)
+		}
+	}
+}
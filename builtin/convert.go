@@ -0,0 +1,137 @@
+package builtin
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"mutant/object"
+)
+
+// Int converts a number or a decimal string to an integer. Floats are
+// truncated toward zero; strings must hold an integer and nothing else.
+func Int(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	switch arg := args[0].(type) {
+	case *object.Integer:
+		return arg
+	case *object.Float:
+		value, errObj := truncate("int", arg.Value)
+		if errObj != nil {
+			return errObj
+		}
+		return intObj(value)
+	case *object.String:
+		value, errObj := parseInteger("int", arg.Value, 10)
+		if errObj != nil {
+			return errObj
+		}
+		return intObj(value)
+	default:
+		return newError("argument to `int` must be INTEGER, FLOAT or STRING, got %s", keyType(args[0]))
+	}
+}
+
+// Float converts a number or a numeric string to a float
+func Float(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	switch arg := args[0].(type) {
+	case *object.Integer:
+		return floatObj(float64(arg.Value))
+	case *object.Float:
+		return arg
+	case *object.String:
+		value, errObj := parseFloat("float", arg.Value)
+		if errObj != nil {
+			return errObj
+		}
+		return floatObj(value)
+	default:
+		return newError("argument to `float` must be INTEGER, FLOAT or STRING, got %s", keyType(args[0]))
+	}
+}
+
+// ParseInt parses s as an integer in base 2 to 36, or in base 10 when base
+// is omitted. Base 0 takes the base from a 0b, 0o or 0x prefix.
+func ParseInt(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	s, errObj := stringArg("parse_int", args, 0)
+	if errObj != nil {
+		return errObj
+	}
+	base := int64(10)
+	if len(args) == 2 {
+		if base, errObj = intArg("parse_int", args, 1); errObj != nil {
+			return errObj
+		}
+		if base != 0 && (base < 2 || base > 36) {
+			return newError("parse_int base must be 0 or between 2 and 36, got %d", base)
+		}
+	}
+	value, errObj := parseInteger("parse_int", s, int(base))
+	if errObj != nil {
+		return errObj
+	}
+	return intObj(value)
+}
+
+// parseValue reads a word typed by the user as the value it spells: an
+// integer, a float, true or false, and otherwise the string itself
+func parseValue(s string) object.Object {
+	if value, errObj := parseInteger("", s, 10); errObj == nil {
+		return intObj(value)
+	}
+	if value, errObj := parseFloat("", s); errObj == nil {
+		return floatObj(value)
+	}
+	switch s {
+	case "true":
+		return boolObj(true)
+	case "false":
+		return boolObj(false)
+	}
+	return stringObj(s)
+}
+
+func parseInteger(name, s string, base int) (int64, *object.Error) {
+	value, err := strconv.ParseInt(s, base, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, newError("%s: %q is out of range for INTEGER", name, s)
+	}
+	if err != nil {
+		if base == 10 {
+			return 0, newError("%s: cannot parse %q as INTEGER", name, s)
+		}
+		return 0, newError("%s: cannot parse %q as INTEGER in base %d", name, s, base)
+	}
+	return value, nil
+}
+
+// parseFloat accepts only finite numbers, so "NaN" and "Inf" are errors
+// rather than values no arithmetic can compare
+func parseFloat(name, s string) (float64, *object.Error) {
+	value, err := strconv.ParseFloat(s, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, newError("%s: %q is out of range for FLOAT", name, s)
+	}
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, newError("%s: cannot parse %q as FLOAT", name, s)
+	}
+	return value, nil
+}
+
+// truncate drops the fraction of f, failing when the result does not fit an
+// integer
+func truncate(name string, f float64) (int64, *object.Error) {
+	f = math.Trunc(f)
+	if math.IsNaN(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, newError("%s: %v is out of range for INTEGER", name, f)
+	}
+	return int64(f), nil
+}
@@ -0,0 +1,93 @@
+package builtin
+
+import (
+	"testing"
+
+	"mutant/object"
+)
+
+func float(value float64) *object.Float { return &object.Float{Value: value} }
+
+func TestConversionBuiltins(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       BuiltinFunction
+		args     []object.Object
+		expected object.Object
+	}{
+		{"int of string", Int, []object.Object{str("-42")}, integer(-42)},
+		{"int of float", Int, []object.Object{float(-3.9)}, integer(-3)},
+		{"int of integer", Int, []object.Object{integer(7)}, integer(7)},
+		{"float of integer", Float, []object.Object{integer(2)}, float(2)},
+		{"float of string", Float, []object.Object{str("1e3")}, float(1000)},
+		{"parse_int hex", ParseInt, []object.Object{str("ff"), integer(16)}, integer(255)},
+		{"parse_int binary", ParseInt, []object.Object{str("-101"), integer(2)}, integer(-5)},
+		{"parse_int prefix", ParseInt, []object.Object{str("0o17"), integer(0)}, integer(15)},
+		{"parse_int decimal", ParseInt, []object.Object{str("099")}, integer(99)},
+		{"str of float", Str, []object.Object{float(1.5)}, str("1.500000")},
+	}
+
+	for _, tt := range tests {
+		result := tt.fn(tt.args...)
+		if errObj, ok := result.(*object.Error); ok {
+			t.Fatalf("%s: unexpected error: %s", tt.name, errObj.Message)
+		}
+		if result.Type() != tt.expected.Type() || result.Inspect() != tt.expected.Inspect() {
+			t.Errorf("%s: wrong result. got=%s %s, want=%s %s", tt.name,
+				result.Type(), result.Inspect(), tt.expected.Type(), tt.expected.Inspect())
+		}
+	}
+}
+
+func TestConversionBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		fn       BuiltinFunction
+		args     []object.Object
+		expected string
+	}{
+		{Int, []object.Object{str("4.5")}, `int: cannot parse "4.5" as INTEGER`},
+		{Int, []object.Object{str(" 42")}, `int: cannot parse " 42" as INTEGER`},
+		{Int, []object.Object{str("99999999999999999999")}, `int: "99999999999999999999" is out of range for INTEGER`},
+		{Int, []object.Object{float(1e19)}, "int: 1e+19 is out of range for INTEGER"},
+		{Int, []object.Object{&object.Boolean{Value: true}}, "argument to `int` must be INTEGER, FLOAT or STRING, got BOOLEAN"},
+		{Float, []object.Object{str("abc")}, `float: cannot parse "abc" as FLOAT`},
+		{Float, []object.Object{str("NaN")}, `float: cannot parse "NaN" as FLOAT`},
+		{Float, []object.Object{str("1e999")}, `float: "1e999" is out of range for FLOAT`},
+		{ParseInt, []object.Object{str("12"), integer(1)}, "parse_int base must be 0 or between 2 and 36, got 1"},
+		{ParseInt, []object.Object{str("g"), integer(16)}, `parse_int: cannot parse "g" as INTEGER in base 16`},
+		{ParseInt, []object.Object{integer(1)}, "argument 1 to `parse_int` must be STRING, got INTEGER"},
+		{Int, []object.Object{}, "wrong number of arguments. got=0, want=1"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := tt.fn(tt.args...).(*object.Error)
+		if !ok {
+			t.Fatalf("expected error %q", tt.expected)
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error. got=%q, want=%q", errObj.Message, tt.expected)
+		}
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected object.Object
+	}{
+		{"12", integer(12)},
+		{"-0.5", float(-0.5)},
+		{"true", &object.Boolean{Value: true}},
+		{"t", str("t")},
+		{"nan", str("nan")},
+		{"hello", str("hello")},
+	}
+
+	for _, tt := range tests {
+		result := parseValue(tt.input)
+		if result.Type() != tt.expected.Type() || result.Inspect() != tt.expected.Inspect() {
+			t.Errorf("parseValue(%q): got=%s %s, want=%s %s", tt.input,
+				result.Type(), result.Inspect(), tt.expected.Type(), tt.expected.Inspect())
+		}
+	}
+}
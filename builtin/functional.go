@@ -0,0 +1,228 @@
+package builtin
+
+import (
+	"sort"
+
+	"mutant/object"
+)
+
+// Map returns a new array holding fn(element) for every element of arr
+func Map(caller Caller, args ...object.Object) (object.Object, error) {
+	arr, fn, errObj := arrayAndFunction("map", args, 2)
+	if errObj != nil {
+		return errObj, nil
+	}
+
+	elements := make([]object.Object, len(arr.Elements))
+	for i, element := range arr.Elements {
+		result, err := caller.CallFunction(fn, element)
+		if err != nil {
+			return nil, err
+		}
+		elements[i] = result
+	}
+	return &object.Array{Elements: elements}, nil
+}
+
+// Filter returns a new array of the elements of arr for which fn is truthy
+func Filter(caller Caller, args ...object.Object) (object.Object, error) {
+	arr, fn, errObj := arrayAndFunction("filter", args, 2)
+	if errObj != nil {
+		return errObj, nil
+	}
+
+	elements := make([]object.Object, 0, len(arr.Elements))
+	for _, element := range arr.Elements {
+		result, err := caller.CallFunction(fn, element)
+		if err != nil {
+			return nil, err
+		}
+		if isTruthy(result) {
+			elements = append(elements, element)
+		}
+	}
+	return &object.Array{Elements: elements}, nil
+}
+
+// Reduce folds arr from the left: the accumulator starts as initial and
+// becomes fn(accumulator, element) for each element in turn
+func Reduce(caller Caller, args ...object.Object) (object.Object, error) {
+	arr, fn, errObj := arrayAndFunction("reduce", args, 3)
+	if errObj != nil {
+		return errObj, nil
+	}
+
+	acc := args[2]
+	for _, element := range arr.Elements {
+		result, err := caller.CallFunction(fn, acc, element)
+		if err != nil {
+			return nil, err
+		}
+		acc = result
+	}
+	return acc, nil
+}
+
+// SortBy returns a new array with the elements of arr ordered by the key
+// fn(element). Keys must be all numbers or all strings; equal keys keep
+// their original order.
+func SortBy(caller Caller, args ...object.Object) (object.Object, error) {
+	arr, fn, errObj := arrayAndFunction("sort_by", args, 2)
+	if errObj != nil {
+		return errObj, nil
+	}
+
+	type keyed struct {
+		key     object.Object
+		element object.Object
+	}
+	items := make([]keyed, len(arr.Elements))
+	for i, element := range arr.Elements {
+		key, err := caller.CallFunction(fn, element)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = keyed{key: key, element: element}
+	}
+
+	for _, item := range items {
+		if _, ok := keyLess(items[0].key, item.key); !ok {
+			return newError("sort_by keys must be all numbers or all strings, got %s and %s",
+				keyType(items[0].key), keyType(item.key)), nil
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		less, _ := keyLess(items[i].key, items[j].key)
+		return less
+	})
+
+	elements := make([]object.Object, len(items))
+	for i, item := range items {
+		elements[i] = item.element
+	}
+	return &object.Array{Elements: elements}, nil
+}
+
+// Any reports whether fn is truthy for at least one element of arr. It
+// stops calling fn at the first such element.
+func Any(caller Caller, args ...object.Object) (object.Object, error) {
+	arr, fn, errObj := arrayAndFunction("any", args, 2)
+	if errObj != nil {
+		return errObj, nil
+	}
+
+	for _, element := range arr.Elements {
+		result, err := caller.CallFunction(fn, element)
+		if err != nil {
+			return nil, err
+		}
+		if isTruthy(result) {
+			return &object.Boolean{Value: true}, nil
+		}
+	}
+	return &object.Boolean{Value: false}, nil
+}
+
+// All reports whether fn is truthy for every element of arr. It stops
+// calling fn at the first element for which it is not.
+func All(caller Caller, args ...object.Object) (object.Object, error) {
+	arr, fn, errObj := arrayAndFunction("all", args, 2)
+	if errObj != nil {
+		return errObj, nil
+	}
+
+	for _, element := range arr.Elements {
+		result, err := caller.CallFunction(fn, element)
+		if err != nil {
+			return nil, err
+		}
+		if !isTruthy(result) {
+			return &object.Boolean{Value: false}, nil
+		}
+	}
+	return &object.Boolean{Value: true}, nil
+}
+
+// Each calls fn with every element of arr for its side effects and returns
+// arr
+func Each(caller Caller, args ...object.Object) (object.Object, error) {
+	arr, fn, errObj := arrayAndFunction("each", args, 2)
+	if errObj != nil {
+		return errObj, nil
+	}
+
+	for _, element := range arr.Elements {
+		if _, err := caller.CallFunction(fn, element); err != nil {
+			return nil, err
+		}
+	}
+	return arr, nil
+}
+
+// arrayAndFunction checks the arguments shared by the higher-order builtins:
+// want arguments in total, an array first and a function second
+func arrayAndFunction(name string, args []object.Object, want int) (*object.Array, object.Object, *object.Error) {
+	if len(args) != want {
+		return nil, nil, newError("wrong number of arguments. got=%d, want=%d", len(args), want)
+	}
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, nil, newError("argument to `%s` must be ARRAY, got %s", name, keyType(args[0]))
+	}
+	if !isCallable(args[1]) {
+		return nil, nil, newError("second argument to `%s` must be a function, got %s", name, keyType(args[1]))
+	}
+	return arr, args[1], nil
+}
+
+func isCallable(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Closure, *object.BoundMethod, *BuiltIn:
+		return true
+	}
+	return false
+}
+
+func isTruthy(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value
+	case *object.Null, nil:
+		return false
+	default:
+		return true
+	}
+}
+
+// keyLess orders two sort keys. It reports false when they cannot be
+// compared.
+func keyLess(a, b object.Object) (less bool, ok bool) {
+	switch a := a.(type) {
+	case *object.Integer:
+		switch b := b.(type) {
+		case *object.Integer:
+			return a.Value < b.Value, true
+		case *object.Float:
+			return float64(a.Value) < b.Value, true
+		}
+	case *object.Float:
+		switch b := b.(type) {
+		case *object.Integer:
+			return a.Value < float64(b.Value), true
+		case *object.Float:
+			return a.Value < b.Value, true
+		}
+	case *object.String:
+		if b, ok := b.(*object.String); ok {
+			return a.Value < b.Value, true
+		}
+	}
+	return false, false
+}
+
+func keyType(obj object.Object) object.ObjectType {
+	if obj == nil {
+		return object.NULL_OBJ
+	}
+	return obj.Type()
+}
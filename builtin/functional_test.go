@@ -0,0 +1,86 @@
+package builtin
+
+import (
+	"errors"
+	"testing"
+
+	"mutant/object"
+)
+
+// fakeCaller calls BuiltIn values directly and fails on the element fail.
+// structs and payloads stand in for the program's type definitions.
+type fakeCaller struct {
+	fail     int64
+	calls    int
+	regexps  RegexpCache
+	structs  map[string][]string
+	payloads map[string]map[string]bool
+}
+
+func (c *fakeCaller) Regexps() *RegexpCache { return &c.regexps }
+
+func (c *fakeCaller) StructFields(typeName string) ([]string, bool) {
+	fields, ok := c.structs[typeName]
+	return fields, ok
+}
+
+func (c *fakeCaller) EnumVariants(typeName string) ([]string, bool) { return nil, false }
+
+func (c *fakeCaller) EnumHasPayload(typeName, tag string) bool { return c.payloads[typeName][tag] }
+
+func (c *fakeCaller) CallFunction(fn object.Object, args ...object.Object) (object.Object, error) {
+	c.calls++
+	if n, ok := args[len(args)-1].(*object.Integer); ok && n.Value == c.fail {
+		return nil, errors.New("callback failed")
+	}
+	return fn.(*BuiltIn).Fn(args...), nil
+}
+
+func integers(values ...int64) *object.Array {
+	arr := &object.Array{}
+	for _, v := range values {
+		arr.Elements = append(arr.Elements, &object.Integer{Value: v})
+	}
+	return arr
+}
+
+func TestHigherOrderBuiltinsStopAtCallbackErrors(t *testing.T) {
+	identity := &BuiltIn{Fn: func(args ...object.Object) object.Object { return args[0] }}
+	tests := []struct {
+		name string
+		fn   CallbackFunction
+		args []object.Object
+	}{
+		{"map", Map, []object.Object{integers(1, 2, 3), identity}},
+		{"filter", Filter, []object.Object{integers(1, 2, 3), identity}},
+		{"reduce", Reduce, []object.Object{integers(1, 2, 3), identity, &object.Integer{}}},
+		{"sort_by", SortBy, []object.Object{integers(1, 2, 3), identity}},
+		{"all", All, []object.Object{integers(1, 2, 3), identity}},
+		{"each", Each, []object.Object{integers(1, 2, 3), identity}},
+	}
+
+	for _, tt := range tests {
+		caller := &fakeCaller{fail: 2}
+		result, err := tt.fn(caller, tt.args...)
+		if err == nil {
+			t.Fatalf("%s: expected callback error, got result %v", tt.name, result)
+		}
+		if caller.calls != 2 {
+			t.Fatalf("%s: expected calls to stop at the failing element, got %d calls", tt.name, caller.calls)
+		}
+	}
+}
+
+func TestSortByIsStable(t *testing.T) {
+	parity := &BuiltIn{Fn: func(args ...object.Object) object.Object {
+		return &object.Integer{Value: args[0].(*object.Integer).Value % 2}
+	}}
+
+	result, err := SortBy(&fakeCaller{fail: -1}, integers(5, 2, 3, 4, 1), parity)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := result.Inspect(); got != "[2, 4, 5, 3, 1]" {
+		t.Fatalf("expected stable order by key, got %s", got)
+	}
+}
@@ -0,0 +1,132 @@
+package builtin
+
+import "mutant/object"
+
+// Hashes keep their keys in insertion order, and every builtin here that
+// lists or builds a hash follows it. Like push, delete and merge return a
+// new hash and leave their arguments unchanged.
+
+// Keys returns the keys of a hash in order
+func Keys(args ...object.Object) object.Object {
+	hash, errObj := hashArg("keys", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	pairs := hash.OrderedPairs()
+	elements := make([]object.Object, len(pairs))
+	for i, pair := range pairs {
+		elements[i] = pair.Key
+	}
+	return &object.Array{Elements: elements}
+}
+
+// Values returns the values of a hash in the order of their keys
+func Values(args ...object.Object) object.Object {
+	hash, errObj := hashArg("values", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	pairs := hash.OrderedPairs()
+	elements := make([]object.Object, len(pairs))
+	for i, pair := range pairs {
+		elements[i] = pair.Value
+	}
+	return &object.Array{Elements: elements}
+}
+
+// Items returns the entries of a hash in order, each as a [key, value] array
+func Items(args ...object.Object) object.Object {
+	hash, errObj := hashArg("items", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	pairs := hash.OrderedPairs()
+	elements := make([]object.Object, len(pairs))
+	for i, pair := range pairs {
+		elements[i] = &object.Array{Elements: []object.Object{pair.Key, pair.Value}}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// HasKey reports whether a hash holds key
+func HasKey(args ...object.Object) object.Object {
+	hash, errObj := hashArg("has_key", args, 2)
+	if errObj != nil {
+		return errObj
+	}
+	key, errObj := hashKeyArg("has_key", args[1])
+	if errObj != nil {
+		return errObj
+	}
+	_, ok := hash.Pairs[key]
+	return boolObj(ok)
+}
+
+// Delete returns a copy of a hash without key. Deleting a missing key is
+// not an error.
+func Delete(args ...object.Object) object.Object {
+	hash, errObj := hashArg("delete", args, 2)
+	if errObj != nil {
+		return errObj
+	}
+	key, errObj := hashKeyArg("delete", args[1])
+	if errObj != nil {
+		return errObj
+	}
+	result := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair, len(hash.Pairs))}
+	for _, pair := range hash.OrderedPairs() {
+		if pairKey := pair.Key.(object.Hashable).HashKey(); pairKey != key {
+			result.Set(pairKey, pair)
+		}
+	}
+	return result
+}
+
+// Merge returns a new hash with the entries of a followed by those of b.
+// Where both have a key, the value from b wins and the key keeps its
+// position from a.
+func Merge(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	result := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+	for i, arg := range args {
+		hash, ok := arg.(*object.Hash)
+		if !ok {
+			return newError("argument %d to `merge` must be HASH, got %s", i+1, keyType(arg))
+		}
+		for _, pair := range hash.OrderedPairs() {
+			result.Set(pair.Key.(object.Hashable).HashKey(), pair)
+		}
+	}
+	return result
+}
+
+// HashLen returns the number of entries in a hash
+func HashLen(args ...object.Object) object.Object {
+	hash, errObj := hashArg("hash_len", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	return intObj(int64(len(hash.Pairs)))
+}
+
+// hashArg checks that a hash builtin got want arguments, the first a hash
+func hashArg(name string, args []object.Object, want int) (*object.Hash, *object.Error) {
+	if len(args) != want {
+		return nil, newError("wrong number of arguments. got=%d, want=%d", len(args), want)
+	}
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return nil, newError("argument 1 to `%s` must be HASH, got %s", name, keyType(args[0]))
+	}
+	return hash, nil
+}
+
+func hashKeyArg(name string, key object.Object) (object.HashKey, *object.Error) {
+	hashable, ok := key.(object.Hashable)
+	if !ok {
+		return object.HashKey{}, newError("argument 2 to `%s` is unusable as a hash key: %s", name, keyType(key))
+	}
+	return hashable.HashKey(), nil
+}
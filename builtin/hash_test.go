@@ -0,0 +1,77 @@
+package builtin
+
+import (
+	"testing"
+
+	"mutant/object"
+)
+
+func orderedHash(keys ...string) *object.Hash {
+	hash := &object.Hash{}
+	for i, key := range keys {
+		keyObj := str(key)
+		hash.Set(keyObj.HashKey(), object.HashPair{Key: keyObj, Value: integer(int64(i))})
+	}
+	return hash
+}
+
+func TestHashBuiltins(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       BuiltinFunction
+		args     []object.Object
+		expected string
+	}{
+		{"keys", Keys, []object.Object{orderedHash("z", "a", "m")}, "[z, a, m]"},
+		{"values", Values, []object.Object{orderedHash("z", "a", "m")}, "[0, 1, 2]"},
+		{"items", Items, []object.Object{orderedHash("z", "a")}, "[[z, 0], [a, 1]]"},
+		{"has_key", HasKey, []object.Object{orderedHash("z"), str("z")}, "true"},
+		{"has_key other type", HasKey, []object.Object{orderedHash("1"), integer(1)}, "false"},
+		{"delete", Delete, []object.Object{orderedHash("z", "a", "m"), str("a")}, "{z: 0, m: 2}"},
+		{"delete missing", Delete, []object.Object{orderedHash("z"), str("q")}, "{z: 0}"},
+		{"merge", Merge, []object.Object{orderedHash("z", "a"), orderedHash("m", "z")}, "{z: 1, a: 1, m: 0}"},
+		{"hash_len", HashLen, []object.Object{orderedHash("z", "a")}, "2"},
+	}
+
+	for _, tt := range tests {
+		result := tt.fn(tt.args...)
+		if errObj, ok := result.(*object.Error); ok {
+			t.Fatalf("%s: unexpected error: %s", tt.name, errObj.Message)
+		}
+		if got := result.Inspect(); got != tt.expected {
+			t.Errorf("%s: wrong result. got=%q, want=%q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestDeleteLeavesHashUnchanged(t *testing.T) {
+	hash := orderedHash("z", "a")
+	Delete(hash, str("z"))
+	if got := hash.Inspect(); got != "{z: 0, a: 1}" {
+		t.Errorf("delete modified its argument: %s", got)
+	}
+}
+
+func TestHashBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		fn       BuiltinFunction
+		args     []object.Object
+		expected string
+	}{
+		{Keys, []object.Object{}, "wrong number of arguments. got=0, want=1"},
+		{Values, []object.Object{str("a")}, "argument 1 to `values` must be HASH, got STRING"},
+		{HasKey, []object.Object{orderedHash("a"), &object.Array{}}, "argument 2 to `has_key` is unusable as a hash key: ARRAY"},
+		{Delete, []object.Object{orderedHash("a")}, "wrong number of arguments. got=1, want=2"},
+		{Merge, []object.Object{orderedHash("a"), integer(1)}, "argument 2 to `merge` must be HASH, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := tt.fn(tt.args...).(*object.Error)
+		if !ok {
+			t.Fatalf("expected error %q", tt.expected)
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error. got=%q, want=%q", errObj.Message, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package builtin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"mutant/global"
+	"mutant/object"
+)
+
+// maxJSONDepth bounds how deeply json_encode and json_decode nest, so a
+// hostile document or a self-containing value cannot exhaust the Go stack
+const maxJSONDepth = 512
+
+// JsonEncode returns the JSON text for a value, indented with two spaces
+// when pretty is true. Hashes keep their insertion order and must have
+// STRING keys. Floats always carry a fraction or exponent so they decode as
+// floats again. A struct encodes as {"struct": type, "fields": {...}} with
+// its fields in declaration order, and an enum value as {"enum": type,
+// "tag": tag, "value": payload}, leaving out "value" for a tag without a
+// payload.
+func JsonEncode(caller Caller, args ...object.Object) (object.Object, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args)), nil
+	}
+	pretty := false
+	if len(args) == 2 {
+		flag, ok := args[1].(*object.Boolean)
+		if !ok {
+			return newError("argument 2 to `json_encode` must be BOOLEAN, got %s", keyType(args[1])), nil
+		}
+		pretty = flag.Value
+	}
+
+	var buf bytes.Buffer
+	if err := encodeJSON(caller, &buf, args[0], 0); err != nil {
+		return newError("json_encode: %s", err), nil
+	}
+	if !pretty {
+		return stringObj(buf.String()), nil
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return newError("json_encode: %s", err), nil
+	}
+	return stringObj(out.String()), nil
+}
+
+// JsonDecode parses JSON text. Objects become hashes in document order,
+// numbers without a fraction or exponent become integers when they fit and
+// floats otherwise, and null becomes null. Invalid input yields an error
+// value, which a try block can catch.
+func JsonDecode(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	text, errObj := stringArg("json_decode", args, 0)
+	if errObj != nil {
+		return errObj
+	}
+
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	value, err := decodeJSON(dec, 0)
+	if err == nil {
+		end := dec.InputOffset()
+		if _, extra := dec.Token(); extra != io.EOF {
+			err = fmt.Errorf("unexpected data after top-level value ending at offset %d", end)
+		}
+	}
+	if err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return newError("json_decode: %s at offset %d", syntaxErr, syntaxErr.Offset)
+		}
+		return newError("json_decode: %s", err)
+	}
+	return value
+}
+
+func encodeJSON(caller Caller, buf *bytes.Buffer, obj object.Object, depth int) error {
+	if depth > maxJSONDepth {
+		return fmt.Errorf("value nested deeper than %d levels", maxJSONDepth)
+	}
+
+	switch v := obj.(type) {
+	case nil, *object.Null:
+		buf.WriteString("null")
+	case *object.Boolean:
+		buf.WriteString(strconv.FormatBool(v.Value))
+	case *object.Integer:
+		buf.WriteString(strconv.FormatInt(v.Value, 10))
+	case *object.Float:
+		if math.IsNaN(v.Value) || math.IsInf(v.Value, 0) {
+			return fmt.Errorf("cannot encode float %v", v.Value)
+		}
+		text := strconv.FormatFloat(v.Value, 'g', -1, 64)
+		if !strings.ContainsAny(text, ".e") {
+			text += ".0"
+		}
+		buf.WriteString(text)
+	case *object.String:
+		writeJSONString(buf, v.Value)
+	case *object.Array:
+		buf.WriteByte('[')
+		for i, element := range v.Elements {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeJSON(caller, buf, element, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case *object.Hash:
+		buf.WriteByte('{')
+		for i, pair := range v.OrderedPairs() {
+			key, ok := pair.Key.(*object.String)
+			if !ok {
+				return fmt.Errorf("object keys must be STRING, got %s", keyType(pair.Key))
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, key.Value)
+			buf.WriteByte(':')
+			if err := encodeJSON(caller, buf, pair.Value, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case *object.Struct:
+		buf.WriteString(`{"struct":`)
+		writeJSONString(buf, v.TypeName)
+		buf.WriteString(`,"fields":{`)
+		for i, name := range fieldOrder(caller, v) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, name)
+			buf.WriteByte(':')
+			if err := encodeJSON(caller, buf, v.Fields[name], depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("}}")
+	case *object.EnumValue:
+		buf.WriteString(`{"enum":`)
+		writeJSONString(buf, v.TypeName)
+		buf.WriteString(`,"tag":`)
+		writeJSONString(buf, v.Tag)
+		if caller.EnumHasPayload(v.TypeName, v.Tag) {
+			buf.WriteString(`,"value":`)
+			if err := encodeJSON(caller, buf, v.Value, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("cannot encode %s", obj.Type())
+	}
+	return nil
+}
+
+// writeJSONString writes s as a JSON string without escaping HTML
+// characters, which would only obscure command output and markup
+func writeJSONString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	buf.Truncate(buf.Len() - 1) // Encode ends every value with a newline
+}
+
+func decodeJSON(dec *json.Decoder, depth int) (object.Object, error) {
+	if depth > maxJSONDepth {
+		return nil, fmt.Errorf("document nested deeper than %d levels", maxJSONDepth)
+	}
+
+	tok, err := dec.Token()
+	if err == io.EOF {
+		return nil, errors.New("unexpected end of input")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '[':
+			elements := []object.Object{}
+			for dec.More() {
+				element, err := decodeJSON(dec, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, element)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return &object.Array{Elements: elements}, nil
+		case '{':
+			hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key := stringObj(keyTok.(string))
+				value, err := decodeJSON(dec, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				hash.Set(key.HashKey(), object.HashPair{Key: key, Value: value})
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return hash, nil
+		default:
+			return nil, fmt.Errorf("unexpected %q at offset %d", t, dec.InputOffset())
+		}
+	case string:
+		return stringObj(t), nil
+	case json.Number:
+		if !strings.ContainsAny(t.String(), ".eE") {
+			if value, err := t.Int64(); err == nil {
+				return intObj(value), nil
+			}
+		}
+		value, err := t.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("number %s out of range", t)
+		}
+		return &object.Float{Value: value}, nil
+	case bool:
+		return boolObj(t), nil
+	default:
+		return global.Null, nil
+	}
+}
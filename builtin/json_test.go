@@ -0,0 +1,114 @@
+package builtin
+
+import (
+	"strings"
+	"testing"
+
+	"mutant/object"
+)
+
+func TestJsonDecode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": [1.5, 2e3, true, "x"], "c": {"z": -3, "y": 4}}`, `{"b":1,"a":[1.5,2000.0,true,"x"],"c":{"z":-3,"y":4}}`},
+		{`{"a": 1, "b": 2, "a": 3}`, `{"a":3,"b":2}`},
+		{`[null, "<tag> & é"]`, `[null,"<tag> & é"]`},
+		{`9223372036854775807`, `9223372036854775807`},
+		{`9223372036854775808`, `9.223372036854776e+18`},
+		{`  "s"  `, `"s"`},
+		{`{}`, `{}`},
+	}
+
+	for _, tt := range tests {
+		decoded := JsonDecode(str(tt.input))
+		if errObj, ok := decoded.(*object.Error); ok {
+			t.Fatalf("decode %q: %s", tt.input, errObj.Message)
+		}
+		encoded, ok := jsonEncode(decoded).(*object.String)
+		if !ok {
+			t.Fatalf("encode %q: got %s", tt.input, jsonEncode(decoded).Inspect())
+		}
+		if encoded.Value != tt.expected {
+			t.Errorf("round trip of %q. got=%s, want=%s", tt.input, encoded.Value, tt.expected)
+		}
+	}
+}
+
+func TestJsonDecodeNumberTypes(t *testing.T) {
+	arr, ok := JsonDecode(str(`[1, 1.0, 1e2, -0]`)).(*object.Array)
+	if !ok {
+		t.Fatalf("expected array")
+	}
+	want := []object.ObjectType{object.INTEGER_OBJ, object.FLOAT_OBJ, object.FLOAT_OBJ, object.INTEGER_OBJ}
+	for i, element := range arr.Elements {
+		if element.Type() != want[i] {
+			t.Errorf("element %d: got %s, want %s", i, element.Type(), want[i])
+		}
+	}
+}
+
+func TestJsonDecodeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{``, "json_decode: unexpected end of input"},
+		{`{"a" 1}`, "json_decode: invalid character '1' after object key at offset 6"},
+		{`[1,]`, "json_decode: invalid character ',' looking for beginning of value at offset 3"},
+		{`[1] [2]`, "json_decode: unexpected data after top-level value ending at offset 3"},
+		{strings.Repeat("[", maxJSONDepth+2), "json_decode: document nested deeper than 512 levels"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := JsonDecode(str(tt.input)).(*object.Error)
+		if !ok {
+			t.Fatalf("expected error for %q", tt.input)
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error for %q. got=%q, want=%q", tt.input, errObj.Message, tt.expected)
+		}
+	}
+}
+
+func TestJsonEncodeConventions(t *testing.T) {
+	caller := &fakeCaller{
+		structs:  map[string][]string{"Point": {"y", "x"}},
+		payloads: map[string]map[string]bool{"Shape": {"Square": true}},
+	}
+	point := &object.Struct{TypeName: "Point", Fields: map[string]object.Object{"x": integer(1), "y": integer(2), "label": str("p")}}
+	square := &object.EnumValue{TypeName: "Shape", Tag: "Square", Value: integer(2)}
+	empty := &object.EnumValue{TypeName: "Shape", Tag: "Empty", Value: integer(2)}
+	hash := &object.Hash{}
+	hash.Set(str("p").HashKey(), object.HashPair{Key: str("p"), Value: point})
+	hash.Set(str("s").HashKey(), object.HashPair{Key: str("s"), Value: square})
+	hash.Set(str("e").HashKey(), object.HashPair{Key: str("e"), Value: empty})
+
+	encoded, _ := JsonEncode(caller, hash)
+	expected := `{"p":{"struct":"Point","fields":{"y":2,"x":1,"label":"p"}},` +
+		`"s":{"enum":"Shape","tag":"Square","value":2},"e":{"enum":"Shape","tag":"Empty"}}`
+	if encoded.(*object.String).Value != expected {
+		t.Fatalf("wrong encoding. got=%s, want=%s", encoded.(*object.String).Value, expected)
+	}
+
+	pretty := jsonEncode(&object.Array{Elements: []object.Object{integer(1)}}, &object.Boolean{Value: true}).(*object.String).Value
+	if pretty != "[\n  1\n]" {
+		t.Fatalf("wrong pretty encoding. got=%q", pretty)
+	}
+
+	badKey := &object.Hash{}
+	badKey.Set(integer(1).HashKey(), object.HashPair{Key: integer(1), Value: integer(2)})
+	if errObj, ok := jsonEncode(badKey).(*object.Error); !ok || errObj.Message != "json_encode: object keys must be STRING, got INTEGER" {
+		t.Fatalf("expected key type error, got %s", jsonEncode(badKey).Inspect())
+	}
+	if _, ok := jsonEncode(&object.Float{Value: 1}, integer(1)).(*object.Error); !ok {
+		t.Fatalf("expected error for non-boolean pretty flag")
+	}
+}
+
+// jsonEncode runs json_encode for a program without struct or enum types
+func jsonEncode(args ...object.Object) object.Object {
+	result, _ := JsonEncode(&fakeCaller{}, args...)
+	return result
+}
@@ -0,0 +1,208 @@
+package builtin
+
+import (
+	"math"
+
+	"mutant/object"
+)
+
+// The math builtins accept integers and floats alike. Results that cannot be
+// represented, such as the square root of a negative number or an integer
+// overflow, are errors rather than NaN, infinity or a wrapped value.
+
+// Abs returns the absolute value of a number, keeping its type
+func Abs(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	switch arg := args[0].(type) {
+	case *object.Integer:
+		if arg.Value == math.MinInt64 {
+			return newError("abs: %d is out of range for INTEGER", arg.Value)
+		}
+		return intObj(max(arg.Value, -arg.Value))
+	case *object.Float:
+		return floatObj(math.Abs(arg.Value))
+	default:
+		return newError("argument to `abs` must be INTEGER or FLOAT, got %s", keyType(args[0]))
+	}
+}
+
+// Min returns the smallest of its arguments, or of the elements of a single
+// array argument
+func Min(args ...object.Object) object.Object {
+	return extreme("min", args, func(a, b float64) bool { return a < b })
+}
+
+// Max returns the largest of its arguments, or of the elements of a single
+// array argument
+func Max(args ...object.Object) object.Object {
+	return extreme("max", args, func(a, b float64) bool { return a > b })
+}
+
+// Floor returns the greatest integer not above x
+func Floor(args ...object.Object) object.Object {
+	return rounding("floor", args, math.Floor)
+}
+
+// Ceil returns the least integer not below x
+func Ceil(args ...object.Object) object.Object {
+	return rounding("ceil", args, math.Ceil)
+}
+
+// Round returns the integer nearest to x, rounding halves away from zero
+func Round(args ...object.Object) object.Object {
+	return rounding("round", args, math.Round)
+}
+
+// Sqrt returns the square root of x as a float
+func Sqrt(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	x, errObj := numberArg("sqrt", args, 0)
+	if errObj != nil {
+		return errObj
+	}
+	if x < 0 {
+		return newError("sqrt of negative number %v", x)
+	}
+	return floatObj(math.Sqrt(x))
+}
+
+// Pow returns base raised to exp. Two integers with exp >= 0 give an
+// integer; anything else gives a float.
+func Pow(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	base, baseIsInt := args[0].(*object.Integer)
+	exp, expIsInt := args[1].(*object.Integer)
+	if baseIsInt && expIsInt && exp.Value >= 0 {
+		result, ok := object.IntPow(base.Value, exp.Value)
+		if !ok {
+			return newError("pow: %d ** %d is out of range for INTEGER", base.Value, exp.Value)
+		}
+		return intObj(result)
+	}
+
+	x, errObj := numberArg("pow", args, 0)
+	if errObj != nil {
+		return errObj
+	}
+	y, errObj := numberArg("pow", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	return finite("pow", math.Pow(x, y))
+}
+
+// Log returns the natural logarithm of x, or its logarithm in base when base
+// is given
+func Log(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	x, errObj := numberArg("log", args, 0)
+	if errObj != nil {
+		return errObj
+	}
+	if x <= 0 {
+		return newError("log of non-positive number %v", x)
+	}
+	if len(args) == 1 {
+		return floatObj(math.Log(x))
+	}
+	base, errObj := numberArg("log", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	if base <= 0 || base == 1 {
+		return newError("log base must be positive and not 1, got %v", base)
+	}
+	return floatObj(math.Log(x) / math.Log(base))
+}
+
+// Exp returns e raised to x
+func Exp(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	x, errObj := numberArg("exp", args, 0)
+	if errObj != nil {
+		return errObj
+	}
+	return finite("exp", math.Exp(x))
+}
+
+func extreme(name string, args []object.Object, better func(a, b float64) bool) object.Object {
+	if len(args) == 1 {
+		if arr, ok := args[0].(*object.Array); ok {
+			args = arr.Elements
+			if len(args) == 0 {
+				return newError("`%s` of an empty array", name)
+			}
+		}
+	}
+	if len(args) == 0 {
+		return newError("wrong number of arguments. got=0, want at least 1")
+	}
+
+	best := args[0]
+	bestValue, errObj := numberArg(name, args, 0)
+	if errObj != nil {
+		return errObj
+	}
+	for i := 1; i < len(args); i++ {
+		value, errObj := numberArg(name, args, i)
+		if errObj != nil {
+			return errObj
+		}
+		if better(value, bestValue) {
+			best, bestValue = args[i], value
+		}
+	}
+	return best
+}
+
+// rounding applies round to a float and returns the result as an integer.
+// Integers are already whole and are returned unchanged.
+func rounding(name string, args []object.Object, round func(float64) float64) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	switch arg := args[0].(type) {
+	case *object.Integer:
+		return arg
+	case *object.Float:
+		value, errObj := truncate(name, round(arg.Value))
+		if errObj != nil {
+			return errObj
+		}
+		return intObj(value)
+	default:
+		return newError("argument to `%s` must be INTEGER or FLOAT, got %s", name, keyType(args[0]))
+	}
+}
+
+func finite(name string, f float64) object.Object {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return newError("%s result is not a finite number", name)
+	}
+	return floatObj(f)
+}
+
+func numberArg(name string, args []object.Object, i int) (float64, *object.Error) {
+	switch arg := args[i].(type) {
+	case *object.Integer:
+		return float64(arg.Value), nil
+	case *object.Float:
+		return arg.Value, nil
+	default:
+		return 0, newError("argument %d to `%s` must be INTEGER or FLOAT, got %s", i+1, name, keyType(args[i]))
+	}
+}
+
+func floatObj(value float64) *object.Float {
+	return &object.Float{Value: value}
+}
@@ -0,0 +1,78 @@
+package builtin
+
+import (
+	"math"
+	"testing"
+
+	"mutant/object"
+)
+
+func TestMathBuiltins(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       BuiltinFunction
+		args     []object.Object
+		expected object.Object
+	}{
+		{"abs integer", Abs, []object.Object{integer(-5)}, integer(5)},
+		{"abs float", Abs, []object.Object{float(-2.5)}, float(2.5)},
+		{"min", Min, []object.Object{integer(3), float(1.5), integer(2)}, float(1.5)},
+		{"max", Max, []object.Object{integer(3), float(1.5), integer(7)}, integer(7)},
+		{"max of array", Max, []object.Object{&object.Array{Elements: []object.Object{integer(4), integer(9)}}}, integer(9)},
+		{"min single", Min, []object.Object{integer(4)}, integer(4)},
+		{"floor", Floor, []object.Object{float(-1.5)}, integer(-2)},
+		{"ceil", Ceil, []object.Object{float(1.2)}, integer(2)},
+		{"round half", Round, []object.Object{float(2.5)}, integer(3)},
+		{"round integer", Round, []object.Object{integer(8)}, integer(8)},
+		{"sqrt", Sqrt, []object.Object{integer(16)}, float(4)},
+		{"pow integers", Pow, []object.Object{integer(2), integer(10)}, integer(1024)},
+		{"pow negative base", Pow, []object.Object{integer(-2), integer(63)}, integer(math.MinInt64)},
+		{"pow negative exponent", Pow, []object.Object{integer(2), integer(-1)}, float(0.5)},
+		{"pow float", Pow, []object.Object{float(9), float(0.5)}, float(3)},
+		{"log", Log, []object.Object{float(math.E)}, float(1)},
+		{"log base", Log, []object.Object{integer(8), integer(2)}, float(3)},
+		{"exp", Exp, []object.Object{integer(0)}, float(1)},
+	}
+
+	for _, tt := range tests {
+		result := tt.fn(tt.args...)
+		if errObj, ok := result.(*object.Error); ok {
+			t.Fatalf("%s: unexpected error: %s", tt.name, errObj.Message)
+		}
+		if result.Type() != tt.expected.Type() || result.Inspect() != tt.expected.Inspect() {
+			t.Errorf("%s: wrong result. got=%s %s, want=%s %s", tt.name,
+				result.Type(), result.Inspect(), tt.expected.Type(), tt.expected.Inspect())
+		}
+	}
+}
+
+func TestMathBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		fn       BuiltinFunction
+		args     []object.Object
+		expected string
+	}{
+		{Abs, []object.Object{integer(math.MinInt64)}, "abs: -9223372036854775808 is out of range for INTEGER"},
+		{Abs, []object.Object{str("1")}, "argument to `abs` must be INTEGER or FLOAT, got STRING"},
+		{Min, []object.Object{}, "wrong number of arguments. got=0, want at least 1"},
+		{Min, []object.Object{&object.Array{}}, "`min` of an empty array"},
+		{Max, []object.Object{integer(1), str("2")}, "argument 2 to `max` must be INTEGER or FLOAT, got STRING"},
+		{Floor, []object.Object{float(1e300)}, "floor: 1e+300 is out of range for INTEGER"},
+		{Sqrt, []object.Object{integer(-4)}, "sqrt of negative number -4"},
+		{Pow, []object.Object{integer(10), integer(19)}, "pow: 10 ** 19 is out of range for INTEGER"},
+		{Pow, []object.Object{integer(0), integer(-1)}, "pow result is not a finite number"},
+		{Log, []object.Object{integer(0)}, "log of non-positive number 0"},
+		{Log, []object.Object{integer(8), integer(1)}, "log base must be positive and not 1, got 1"},
+		{Exp, []object.Object{integer(1000)}, "exp result is not a finite number"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := tt.fn(tt.args...).(*object.Error)
+		if !ok {
+			t.Fatalf("expected error %q", tt.expected)
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error. got=%q, want=%q", errObj.Message, tt.expected)
+		}
+	}
+}
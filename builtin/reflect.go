@@ -0,0 +1,211 @@
+package builtin
+
+import (
+	"maps"
+	"sort"
+
+	"mutant/object"
+)
+
+// TypeOf returns the type name of a value: the declared type name for
+// structs and enum values and the object type, such as INTEGER or HASH, for
+// everything else
+func TypeOf(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	switch arg := args[0].(type) {
+	case *object.Struct:
+		return stringObj(arg.TypeName)
+	case *object.EnumValue:
+		return stringObj(arg.TypeName)
+	default:
+		return stringObj(string(keyType(arg)))
+	}
+}
+
+// typePredicate returns a builtin reporting whether its one argument is of
+// one of the given types
+func typePredicate(types ...object.ObjectType) BuiltinFunction {
+	return func(args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError("wrong number of arguments. got=%d, want=1", len(args))
+		}
+		actual := keyType(args[0])
+		for _, t := range types {
+			if actual == t {
+				return boolObj(true)
+			}
+		}
+		return boolObj(false)
+	}
+}
+
+var (
+	IsInt    = typePredicate(object.INTEGER_OBJ)
+	IsFloat  = typePredicate(object.FLOAT_OBJ)
+	IsNumber = typePredicate(object.INTEGER_OBJ, object.FLOAT_OBJ)
+	IsString = typePredicate(object.STRING_OBJ)
+	IsBool   = typePredicate(object.BOOLEAN_OBJ)
+	IsArray  = typePredicate(object.ARRAY_OBJ)
+	IsHash   = typePredicate(object.HASH_OBJ)
+	IsNull   = typePredicate(object.NULL_OBJ)
+	IsStruct = typePredicate(object.STRUCT_OBJ)
+	IsEnum   = typePredicate(object.ENUM_VALUE_OBJ)
+	IsError  = typePredicate(object.ERROR_OBJ)
+)
+
+// IsFunction reports whether a value can be called
+func IsFunction(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	return boolObj(isCallable(args[0]))
+}
+
+// Fields returns the field names of a struct in declaration order. Fields
+// added at run time follow, sorted by name.
+func Fields(caller Caller, args ...object.Object) (object.Object, error) {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args)), nil
+	}
+	s, errObj := structArg("fields", args, 0)
+	if errObj != nil {
+		return errObj, nil
+	}
+	return stringArray(fieldOrder(caller, s)), nil
+}
+
+// fieldOrder lists the fields of a struct in declaration order, followed by
+// fields added at run time sorted by name
+func fieldOrder(caller Caller, s *object.Struct) []string {
+	names := make([]string, 0, len(s.Fields))
+	declared := make(map[string]bool)
+	if fields, ok := caller.StructFields(s.TypeName); ok {
+		for _, name := range fields {
+			if _, ok := s.Fields[name]; ok {
+				names = append(names, name)
+				declared[name] = true
+			}
+		}
+	}
+	var extra []string
+	for name := range s.Fields {
+		if !declared[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	return append(names, extra...)
+}
+
+// HasField reports whether a struct has the named field
+func HasField(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	s, name, errObj := structAndField("has_field", args)
+	if errObj != nil {
+		return errObj
+	}
+	_, ok := s.Fields[name]
+	return boolObj(ok)
+}
+
+// GetField returns the value of the named field of a struct. Unlike s.name
+// it does not fall back to methods, and a missing field is an error.
+func GetField(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	s, name, errObj := structAndField("get_field", args)
+	if errObj != nil {
+		return errObj
+	}
+	value, ok := s.Fields[name]
+	if !ok {
+		return newError("struct %s has no field %s", s.TypeName, name)
+	}
+	return value
+}
+
+// SetField returns a copy of a struct with the named field set to value.
+// Structs are values, so like p.x = v the result has to be assigned back.
+// The field must already exist.
+func SetField(args ...object.Object) object.Object {
+	if len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=3", len(args))
+	}
+	s, name, errObj := structAndField("set_field", args)
+	if errObj != nil {
+		return errObj
+	}
+	if _, ok := s.Fields[name]; !ok {
+		return newError("struct %s has no field %s", s.TypeName, name)
+	}
+	fields := maps.Clone(s.Fields)
+	fields[name] = args[2]
+	return &object.Struct{TypeName: s.TypeName, Fields: fields}
+}
+
+// EnumVariants returns the tag names of an enum type in declaration order.
+// The type is given by name, by an enum value of it, or by the bare type
+// name, which the compiler passes as a string.
+func EnumVariants(caller Caller, args ...object.Object) (object.Object, error) {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args)), nil
+	}
+	var typeName string
+	switch arg := args[0].(type) {
+	case *object.String:
+		typeName = arg.Value
+	case *object.EnumValue:
+		typeName = arg.TypeName
+	default:
+		return newError("argument to `enum_variants` must be an enum type, STRING or ENUM_VALUE, got %s", keyType(args[0])), nil
+	}
+	tags, ok := caller.EnumVariants(typeName)
+	if !ok {
+		return newError("unknown enum type %s", typeName), nil
+	}
+	return stringArray(tags), nil
+}
+
+// TakesEnumType reports whether the named builtin accepts the bare name of an
+// enum type as an argument. Enum types are not values, so the compiler passes
+// such a name as a string.
+func TakesEnumType(name string) bool {
+	return name == "enum_variants"
+}
+
+// EnumTag returns the tag name of an enum value
+func EnumTag(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	value, ok := args[0].(*object.EnumValue)
+	if !ok {
+		return newError("argument to `enum_tag` must be ENUM_VALUE, got %s", keyType(args[0]))
+	}
+	return stringObj(value.Tag)
+}
+
+func structArg(name string, args []object.Object, i int) (*object.Struct, *object.Error) {
+	s, ok := args[i].(*object.Struct)
+	if !ok {
+		return nil, newError("argument %d to `%s` must be STRUCT, got %s", i+1, name, keyType(args[i]))
+	}
+	return s, nil
+}
+
+func structAndField(name string, args []object.Object) (*object.Struct, string, *object.Error) {
+	s, errObj := structArg(name, args, 0)
+	if errObj != nil {
+		return nil, "", errObj
+	}
+	field, errObj := stringArg(name, args, 1)
+	if errObj != nil {
+		return nil, "", errObj
+	}
+	return s, field, nil
+}
@@ -0,0 +1,85 @@
+package builtin
+
+import (
+	"testing"
+
+	"mutant/object"
+)
+
+func point(x, y int64) *object.Struct {
+	return &object.Struct{TypeName: "Point", Fields: map[string]object.Object{"x": integer(x), "y": integer(y)}}
+}
+
+func TestIntrospectionBuiltins(t *testing.T) {
+	dot := &object.EnumValue{TypeName: "Shape", Tag: "Dot", Value: integer(1)}
+	tests := []struct {
+		name     string
+		fn       BuiltinFunction
+		args     []object.Object
+		expected string
+	}{
+		{"type_of struct", TypeOf, []object.Object{point(1, 2)}, "Point"},
+		{"type_of enum", TypeOf, []object.Object{dot}, "Shape"},
+		{"type_of float", TypeOf, []object.Object{float(1)}, "FLOAT"},
+		{"type_of nil", TypeOf, []object.Object{nil}, "NULL"},
+		{"is_number", IsNumber, []object.Object{float(1)}, "true"},
+		{"is_int float", IsInt, []object.Object{float(1)}, "false"},
+		{"is_function", IsFunction, []object.Object{GetBuiltinByName("len")}, "true"},
+		{"has_field", HasField, []object.Object{point(1, 2), str("y")}, "true"},
+		{"get_field", GetField, []object.Object{point(1, 2), str("y")}, "2"},
+		{"enum_tag", EnumTag, []object.Object{dot}, "Dot"},
+	}
+
+	for _, tt := range tests {
+		result := tt.fn(tt.args...)
+		if errObj, ok := result.(*object.Error); ok {
+			t.Fatalf("%s: unexpected error: %s", tt.name, errObj.Message)
+		}
+		got := result.Inspect()
+		if s, ok := result.(*object.String); ok {
+			got = s.Value
+		}
+		if got != tt.expected {
+			t.Errorf("%s: wrong result. got=%q, want=%q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestSetFieldCopiesStruct(t *testing.T) {
+	p := point(1, 2)
+	result, ok := SetField(p, str("x"), integer(9)).(*object.Struct)
+	if !ok {
+		t.Fatalf("set_field did not return a struct")
+	}
+	if result.Fields["x"].Inspect() != "9" || result.Fields["y"].Inspect() != "2" {
+		t.Errorf("wrong fields: %v", result.Fields)
+	}
+	if p.Fields["x"].Inspect() != "1" {
+		t.Errorf("set_field modified its argument")
+	}
+}
+
+func TestIntrospectionBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		fn       BuiltinFunction
+		args     []object.Object
+		expected string
+	}{
+		{TypeOf, []object.Object{}, "wrong number of arguments. got=0, want=1"},
+		{GetField, []object.Object{point(1, 2), str("z")}, "struct Point has no field z"},
+		{SetField, []object.Object{point(1, 2), str("z"), integer(1)}, "struct Point has no field z"},
+		{HasField, []object.Object{integer(1), str("x")}, "argument 1 to `has_field` must be STRUCT, got INTEGER"},
+		{GetField, []object.Object{point(1, 2), integer(1)}, "argument 2 to `get_field` must be STRING, got INTEGER"},
+		{EnumTag, []object.Object{str("Dot")}, "argument to `enum_tag` must be ENUM_VALUE, got STRING"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := tt.fn(tt.args...).(*object.Error)
+		if !ok {
+			t.Fatalf("expected error %q", tt.expected)
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error. got=%q, want=%q", errObj.Message, tt.expected)
+		}
+	}
+}
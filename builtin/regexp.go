@@ -0,0 +1,160 @@
+package builtin
+
+import (
+	"regexp"
+
+	"mutant/global"
+	"mutant/object"
+)
+
+// maxCachedRegexps bounds a RegexpCache, so a script that builds patterns in
+// a loop cannot grow it without limit
+const maxCachedRegexps = 256
+
+// RegexpCache holds the compiled patterns of one program, evicting the
+// oldest pattern once it is full
+type RegexpCache struct {
+	compiled map[string]*regexp.Regexp
+	order    []string
+}
+
+// Compile returns pattern compiled with RE2 syntax, compiling it only the
+// first time it is seen
+func (c *RegexpCache) Compile(pattern string) (*regexp.Regexp, error) {
+	if re, ok := c.compiled[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.compiled == nil {
+		c.compiled = make(map[string]*regexp.Regexp)
+	}
+	if len(c.order) >= maxCachedRegexps {
+		delete(c.compiled, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.compiled[pattern] = re
+	c.order = append(c.order, pattern)
+	return re, nil
+}
+
+// Len returns the number of cached patterns
+func (c *RegexpCache) Len() int {
+	return len(c.order)
+}
+
+// IsPatternBuiltin reports whether the named builtin takes a regular
+// expression as its first argument
+func IsPatternBuiltin(name string) bool {
+	switch name {
+	case "re_match", "re_find_all", "re_replace", "re_split", "re_captures":
+		return true
+	}
+	return false
+}
+
+// ReMatch reports whether s contains a match of pattern
+func ReMatch(caller Caller, args ...object.Object) (object.Object, error) {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args)), nil
+	}
+	re, s, errObj := patternAndSubject(caller, "re_match", args)
+	if errObj != nil {
+		return errObj, nil
+	}
+	return boolObj(re.MatchString(s)), nil
+}
+
+// ReFindAll returns the matches of pattern in s, at most limit of them when
+// limit is given
+func ReFindAll(caller Caller, args ...object.Object) (object.Object, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args)), nil
+	}
+	re, s, errObj := patternAndSubject(caller, "re_find_all", args)
+	if errObj != nil {
+		return errObj, nil
+	}
+	limit := int64(-1)
+	if len(args) == 3 {
+		if limit, errObj = intArg("re_find_all", args, 2); errObj != nil {
+			return errObj, nil
+		}
+	}
+	return stringArray(re.FindAllString(s, int(limit))), nil
+}
+
+// ReReplace replaces every match of pattern in s with repl, in which $1 or
+// ${name} stand for the text of a capture group
+func ReReplace(caller Caller, args ...object.Object) (object.Object, error) {
+	if len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=3", len(args)), nil
+	}
+	re, s, errObj := patternAndSubject(caller, "re_replace", args)
+	if errObj != nil {
+		return errObj, nil
+	}
+	repl, errObj := stringArg("re_replace", args, 2)
+	if errObj != nil {
+		return errObj, nil
+	}
+	return stringObj(re.ReplaceAllString(s, repl)), nil
+}
+
+// ReSplit returns the parts of s between matches of pattern
+func ReSplit(caller Caller, args ...object.Object) (object.Object, error) {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args)), nil
+	}
+	re, s, errObj := patternAndSubject(caller, "re_split", args)
+	if errObj != nil {
+		return errObj, nil
+	}
+	return stringArray(re.Split(s, -1)), nil
+}
+
+// ReCaptures returns a hash from each named group of pattern to the text it
+// captured in the first match in s, in the order the groups appear. Groups
+// that took no part in the match map to "". Without a match it returns null.
+func ReCaptures(caller Caller, args ...object.Object) (object.Object, error) {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args)), nil
+	}
+	re, s, errObj := patternAndSubject(caller, "re_captures", args)
+	if errObj != nil {
+		return errObj, nil
+	}
+
+	match := re.FindStringSubmatch(s)
+	if match == nil {
+		return global.Null, nil
+	}
+	captures := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+	for i, name := range re.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		key := stringObj(name)
+		captures.Set(key.HashKey(), object.HashPair{Key: key, Value: stringObj(match[i])})
+	}
+	return captures, nil
+}
+
+func patternAndSubject(caller Caller, name string, args []object.Object) (*regexp.Regexp, string, *object.Error) {
+	pattern, errObj := stringArg(name, args, 0)
+	if errObj != nil {
+		return nil, "", errObj
+	}
+	s, errObj := stringArg(name, args, 1)
+	if errObj != nil {
+		return nil, "", errObj
+	}
+	re, err := caller.Regexps().Compile(pattern)
+	if err != nil {
+		return nil, "", newError("%s: %s", name, err)
+	}
+	return re, s, nil
+}
@@ -0,0 +1,99 @@
+package builtin
+
+import (
+	"fmt"
+	"testing"
+
+	"mutant/object"
+)
+
+func TestRegexpBuiltins(t *testing.T) {
+	log := str("GET /a 200\nPOST /login 401")
+	tests := []struct {
+		name     string
+		fn       CallbackFunction
+		args     []object.Object
+		expected string
+	}{
+		{"re_match", ReMatch, []object.Object{str(`^POST`), log}, "false"},
+		{"re_match multiline", ReMatch, []object.Object{str(`(?m)^POST`), log}, "true"},
+		{"re_find_all", ReFindAll, []object.Object{str(`\d{3}`), log}, "[200, 401]"},
+		{"re_find_all limit", ReFindAll, []object.Object{str(`/\w+`), log, integer(1)}, "[/a]"},
+		{"re_find_all none", ReFindAll, []object.Object{str(`x`), log}, "[]"},
+		{"re_replace", ReReplace, []object.Object{str(`(?P<verb>[A-Z]+) `), log, str("${verb}:")}, "GET:/a 200\nPOST:/login 401"},
+		{"re_split", ReSplit, []object.Object{str(`\s+`), str(" a  b\tc")}, "[, a, b, c]"},
+		{"re_captures", ReCaptures, []object.Object{str(`(?P<path>/\w+) (\d+)(?P<extra>!)?`), log}, `{"path":"/a","extra":""}`},
+		{"re_captures no match", ReCaptures, []object.Object{str(`(?P<x>z)`), log}, ""},
+	}
+
+	for _, tt := range tests {
+		result, err := tt.fn(&fakeCaller{}, tt.args...)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if errObj, ok := result.(*object.Error); ok {
+			t.Fatalf("%s: unexpected error value: %s", tt.name, errObj.Message)
+		}
+		got := result.Inspect()
+		switch result := result.(type) {
+		case *object.String:
+			got = result.Value
+		case *object.Hash:
+			got = jsonEncode(result).(*object.String).Value
+		}
+		if got != tt.expected {
+			t.Errorf("%s: wrong result. got=%q, want=%q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestRegexpBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		fn       CallbackFunction
+		args     []object.Object
+		expected string
+	}{
+		{ReMatch, []object.Object{str(`a(`), str("a")}, "re_match: error parsing regexp: missing closing ): `a(`"},
+		{ReSplit, []object.Object{integer(1), str("a")}, "argument 1 to `re_split` must be STRING, got INTEGER"},
+		{ReReplace, []object.Object{str(`a`), str("a")}, "wrong number of arguments. got=2, want=3"},
+		{ReFindAll, []object.Object{str(`a`), str("a"), str("1")}, "argument 3 to `re_find_all` must be INTEGER, got STRING"},
+	}
+
+	for _, tt := range tests {
+		result, _ := tt.fn(&fakeCaller{}, tt.args...)
+		errObj, ok := result.(*object.Error)
+		if !ok {
+			t.Fatalf("expected error %q, got %s", tt.expected, result.Inspect())
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error. got=%q, want=%q", errObj.Message, tt.expected)
+		}
+	}
+}
+
+func TestRegexpCacheReusesAndBoundsPatterns(t *testing.T) {
+	var cache RegexpCache
+	first, err := cache.Compile(`a+`)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	again, _ := cache.Compile(`a+`)
+	if first != again {
+		t.Fatalf("expected the cached pattern to be reused")
+	}
+	if _, err := cache.Compile(`a(`); err == nil || cache.Len() != 1 {
+		t.Fatalf("expected invalid pattern to fail without being cached, len=%d", cache.Len())
+	}
+
+	for i := 0; i < maxCachedRegexps+10; i++ {
+		if _, err := cache.Compile(fmt.Sprintf("p%d", i)); err != nil {
+			t.Fatalf("compile error: %v", err)
+		}
+	}
+	if cache.Len() != maxCachedRegexps {
+		t.Fatalf("expected cache to hold %d patterns, got %d", maxCachedRegexps, cache.Len())
+	}
+	if _, ok := cache.compiled[`a+`]; ok {
+		t.Fatalf("expected the oldest pattern to be evicted")
+	}
+}
@@ -0,0 +1,17 @@
+package builtin
+
+import "mutant/object"
+
+// Str returns the string form of any value; strings are returned unchanged
+func Str(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	if str, ok := args[0].(*object.String); ok {
+		return str
+	}
+	if args[0] == nil {
+		return &object.String{Value: "null"}
+	}
+	return &object.String{Value: args[0].Inspect()}
+}
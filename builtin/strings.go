@@ -0,0 +1,291 @@
+package builtin
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"mutant/object"
+)
+
+// The string builtins count positions and widths in runes, not bytes, so
+// multi-byte characters are never split.
+
+// Split returns the parts of s between occurrences of sep. An empty sep
+// splits s into its characters.
+func Split(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	s, errObj := stringArg("split", args, 0)
+	if errObj != nil {
+		return errObj
+	}
+	sep, errObj := stringArg("split", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	return stringArray(strings.Split(s, sep))
+}
+
+// Join concatenates the strings of an array with sep between them
+func Join(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument 1 to `join` must be ARRAY, got %s", keyType(args[0]))
+	}
+	sep, errObj := stringArg("join", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+
+	parts := make([]string, len(arr.Elements))
+	for i, element := range arr.Elements {
+		str, ok := element.(*object.String)
+		if !ok {
+			return newError("element %d of array passed to `join` must be STRING, got %s", i, keyType(element))
+		}
+		parts[i] = str.Value
+	}
+	return stringObj(strings.Join(parts, sep))
+}
+
+// Replace returns s with every occurrence of old replaced by new
+func Replace(args ...object.Object) object.Object {
+	if len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=3", len(args))
+	}
+	values, errObj := stringArgs("replace", args)
+	if errObj != nil {
+		return errObj
+	}
+	return stringObj(strings.ReplaceAll(values[0], values[1], values[2]))
+}
+
+// Trim removes leading and trailing white space from s, or the characters
+// of the optional cutset
+func Trim(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	values, errObj := stringArgs("trim", args)
+	if errObj != nil {
+		return errObj
+	}
+	if len(values) == 2 {
+		return stringObj(strings.Trim(values[0], values[1]))
+	}
+	return stringObj(strings.TrimSpace(values[0]))
+}
+
+func Upper(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	s, errObj := stringArg("upper", args, 0)
+	if errObj != nil {
+		return errObj
+	}
+	return stringObj(strings.ToUpper(s))
+}
+
+func Lower(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	s, errObj := stringArg("lower", args, 0)
+	if errObj != nil {
+		return errObj
+	}
+	return stringObj(strings.ToLower(s))
+}
+
+func Contains(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	values, errObj := stringArgs("contains", args)
+	if errObj != nil {
+		return errObj
+	}
+	return boolObj(strings.Contains(values[0], values[1]))
+}
+
+func StartsWith(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	values, errObj := stringArgs("starts_with", args)
+	if errObj != nil {
+		return errObj
+	}
+	return boolObj(strings.HasPrefix(values[0], values[1]))
+}
+
+func EndsWith(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	values, errObj := stringArgs("ends_with", args)
+	if errObj != nil {
+		return errObj
+	}
+	return boolObj(strings.HasSuffix(values[0], values[1]))
+}
+
+// IndexOf returns the character position of the first occurrence of sub in
+// s, or -1 when there is none
+func IndexOf(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	values, errObj := stringArgs("index_of", args)
+	if errObj != nil {
+		return errObj
+	}
+	i := strings.Index(values[0], values[1])
+	if i < 0 {
+		return intObj(-1)
+	}
+	return intObj(int64(utf8.RuneCountInString(values[0][:i])))
+}
+
+// Repeat returns count copies of s
+func Repeat(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	s, errObj := stringArg("repeat", args, 0)
+	if errObj != nil {
+		return errObj
+	}
+	count, errObj := intArg("repeat", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	if count < 0 {
+		return newError("repeat count must not be negative, got %d", count)
+	}
+	if count > 0 && int64(len(s)) > maxStringBytes/count {
+		return newError("repeat result would exceed %d bytes", maxStringBytes)
+	}
+	return stringObj(strings.Repeat(s, int(count)))
+}
+
+// Pad widens s to width characters with spaces, or with the single
+// character fill. Like printf field widths, a positive width aligns s to the
+// right and a negative width aligns it to the left. Longer strings are
+// returned unchanged.
+func Pad(args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+	s, errObj := stringArg("pad", args, 0)
+	if errObj != nil {
+		return errObj
+	}
+	width, errObj := intArg("pad", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+	fill := " "
+	if len(args) == 3 {
+		if fill, errObj = stringArg("pad", args, 2); errObj != nil {
+			return errObj
+		}
+		if utf8.RuneCountInString(fill) != 1 {
+			return newError("pad fill must be a single character, got %q", fill)
+		}
+	}
+
+	missing := int(max(width, -width)) - utf8.RuneCountInString(s)
+	if missing <= 0 {
+		return stringObj(s)
+	}
+	if missing > maxStringBytes/len(fill) {
+		return newError("pad result would exceed %d bytes", maxStringBytes)
+	}
+	padding := strings.Repeat(fill, missing)
+	if width < 0 {
+		return stringObj(s + padding)
+	}
+	return stringObj(padding + s)
+}
+
+// Substr returns up to length characters of s starting at character start,
+// or the rest of s when length is omitted. A negative start counts back from
+// the end of s; positions past either end are clamped.
+func Substr(args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+	s, errObj := stringArg("substr", args, 0)
+	if errObj != nil {
+		return errObj
+	}
+	start, errObj := intArg("substr", args, 1)
+	if errObj != nil {
+		return errObj
+	}
+
+	runes := []rune(s)
+	size := int64(len(runes))
+	if start < 0 {
+		start += size
+	}
+	start = max(0, min(start, size))
+	end := size
+	if len(args) == 3 {
+		length, errObj := intArg("substr", args, 2)
+		if errObj != nil {
+			return errObj
+		}
+		if length < 0 {
+			return newError("substr length must not be negative, got %d", length)
+		}
+		end = start + min(length, size-start)
+	}
+	return stringObj(string(runes[start:end]))
+}
+
+// maxStringBytes bounds the strings repeat and pad build, so a script cannot
+// exhaust memory with one call
+const maxStringBytes = 64 << 20
+
+func stringArg(name string, args []object.Object, i int) (string, *object.Error) {
+	str, ok := args[i].(*object.String)
+	if !ok {
+		return "", newError("argument %d to `%s` must be STRING, got %s", i+1, name, keyType(args[i]))
+	}
+	return str.Value, nil
+}
+
+func stringArgs(name string, args []object.Object) ([]string, *object.Error) {
+	values := make([]string, len(args))
+	for i := range args {
+		value, errObj := stringArg(name, args, i)
+		if errObj != nil {
+			return nil, errObj
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func intArg(name string, args []object.Object, i int) (int64, *object.Error) {
+	integer, ok := args[i].(*object.Integer)
+	if !ok {
+		return 0, newError("argument %d to `%s` must be INTEGER, got %s", i+1, name, keyType(args[i]))
+	}
+	return integer.Value, nil
+}
+
+func stringArray(values []string) *object.Array {
+	elements := make([]object.Object, len(values))
+	for i, value := range values {
+		elements[i] = stringObj(value)
+	}
+	return &object.Array{Elements: elements}
+}
@@ -0,0 +1,88 @@
+package builtin
+
+import (
+	"testing"
+
+	"mutant/object"
+)
+
+func str(value string) *object.String { return &object.String{Value: value} }
+
+func integer(value int64) *object.Integer { return &object.Integer{Value: value} }
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       BuiltinFunction
+		args     []object.Object
+		expected string
+	}{
+		{"split", Split, []object.Object{str("a,b,,c"), str(",")}, "[a, b, , c]"},
+		{"split runes", Split, []object.Object{str("héé"), str("")}, "[h, é, é]"},
+		{"join", Join, []object.Object{&object.Array{Elements: []object.Object{str("a"), str("b")}}, str("-")}, "a-b"},
+		{"join empty", Join, []object.Object{&object.Array{}, str("-")}, ""},
+		{"replace", Replace, []object.Object{str("a.b.c"), str("."), str("::")}, "a::b::c"},
+		{"trim", Trim, []object.Object{str(" \tport 22\r\n")}, "port 22"},
+		{"trim cutset", Trim, []object.Object{str("--x--"), str("-")}, "x"},
+		{"upper", Upper, []object.Object{str("héllo")}, "HÉLLO"},
+		{"lower", Lower, []object.Object{str("ÉCOLE")}, "école"},
+		{"contains", Contains, []object.Object{str("HTTP/1.1 200 OK"), str("200")}, "true"},
+		{"starts_with", StartsWith, []object.Object{str("ssh-rsa AAA"), str("ssh-")}, "true"},
+		{"ends_with", EndsWith, []object.Object{str("notes.txt"), str(".md")}, "false"},
+		{"index_of runes", IndexOf, []object.Object{str("héllo"), str("l")}, "2"},
+		{"index_of missing", IndexOf, []object.Object{str("abc"), str("z")}, "-1"},
+		{"repeat", Repeat, []object.Object{str("ab"), integer(3)}, "ababab"},
+		{"repeat zero", Repeat, []object.Object{str("ab"), integer(0)}, ""},
+		{"pad right aligned", Pad, []object.Object{str("é"), integer(3)}, "  é"},
+		{"pad left aligned", Pad, []object.Object{str("22"), integer(-5), str(".")}, "22..."},
+		{"pad longer", Pad, []object.Object{str("abcdef"), integer(3)}, "abcdef"},
+		{"substr", Substr, []object.Object{str("héllo"), integer(1), integer(3)}, "éll"},
+		{"substr rest", Substr, []object.Object{str("héllo"), integer(2)}, "llo"},
+		{"substr from end", Substr, []object.Object{str("héllo"), integer(-2)}, "lo"},
+		{"substr clamped", Substr, []object.Object{str("abc"), integer(2), integer(10)}, "c"},
+		{"substr past end", Substr, []object.Object{str("abc"), integer(5)}, ""},
+	}
+
+	for _, tt := range tests {
+		result := tt.fn(tt.args...)
+		if errObj, ok := result.(*object.Error); ok {
+			t.Fatalf("%s: unexpected error: %s", tt.name, errObj.Message)
+		}
+		got := result.Inspect()
+		if s, ok := result.(*object.String); ok {
+			got = s.Value
+		}
+		if got != tt.expected {
+			t.Errorf("%s: wrong result. got=%q, want=%q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestStringBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		fn       BuiltinFunction
+		args     []object.Object
+		expected string
+	}{
+		{Split, []object.Object{str("a")}, "wrong number of arguments. got=1, want=2"},
+		{Split, []object.Object{integer(1), str(",")}, "argument 1 to `split` must be STRING, got INTEGER"},
+		{Join, []object.Object{&object.Array{Elements: []object.Object{str("a"), integer(1)}}, str(",")}, "element 1 of array passed to `join` must be STRING, got INTEGER"},
+		{Repeat, []object.Object{str("a"), integer(-1)}, "repeat count must not be negative, got -1"},
+		{Repeat, []object.Object{str("ab"), integer(1 << 62)}, "repeat result would exceed 67108864 bytes"},
+		{Pad, []object.Object{str("a"), integer(3), str("ab")}, `pad fill must be a single character, got "ab"`},
+		{Pad, []object.Object{str("a"), integer(1 << 62)}, "pad result would exceed 67108864 bytes"},
+		{Substr, []object.Object{str("a"), str("0")}, "argument 2 to `substr` must be INTEGER, got STRING"},
+		{Substr, []object.Object{str("a"), integer(0), integer(-1)}, "substr length must not be negative, got -1"},
+		{Trim, []object.Object{}, "wrong number of arguments. got=0, want=1 or 2"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := tt.fn(tt.args...).(*object.Error)
+		if !ok {
+			t.Fatalf("expected error %q", tt.expected)
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error. got=%q, want=%q", errObj.Message, tt.expected)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"mutant/global"
 	"mutant/repl"
 	"mutant/runner"
+	"mutant/security"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -24,7 +25,7 @@ func RunRepl(version string, enableMacros bool) {
 	repl.Start(os.Stdin, os.Stdout, version, enableMacros)
 }
 
-func CompileCode(src, goos, goarch string, release bool, password string, mutationLevel int, mutationSeed int64) {
+func CompileCode(src, goos, goarch string, release bool, password string, mutationLevel int, mutationSeed int64, optimizationLevel int) {
 	start := time.Now()
 	srcpath, err := filepath.Abs(src)
 	if err != nil {
@@ -35,7 +36,7 @@ func CompileCode(src, goos, goarch string, release bool, password string, mutati
 
 	// Pass nil for privateKey - Generate() will create a new one
 	// In production, you'd load a persistent key from a secure location
-	if err, errtype, errors := generator.Generate(srcpath, dstpath, goos, goarch, release, password, mutationLevel, mutationSeed, nil); err != nil {
+	if err, errtype, errors := generator.Generate(srcpath, dstpath, goos, goarch, release, password, mutationLevel, mutationSeed, optimizationLevel, nil); err != nil {
 		switch errtype {
 		case errrs.ERROR:
 			fmt.Println(err)
@@ -77,3 +78,44 @@ func RunCode(src string, password string, secureMode bool, enforceSignerAuth boo
 		}
 	}
 }
+
+func ShowInfo(src string) {
+	srcpath, err := filepath.Abs(src)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	manifest, signer, found, err := runner.ReadCapabilityManifest(srcpath)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println("File:", srcpath)
+	fmt.Println("Signer public key:", signer)
+	if !found {
+		fmt.Println("Capability manifest: none (artifact predates capability manifests)")
+		return
+	}
+
+	if len(manifest.Capabilities) == 0 {
+		fmt.Println("Required capabilities: none")
+	} else {
+		fmt.Println("Required capabilities:", strings.Join(manifest.Capabilities, ", "))
+	}
+	for _, entry := range manifest.Builtins {
+		fmt.Printf("\t%s -> %s\n", entry.Builtin, entry.Capability)
+	}
+
+	policy, err := security.ResolveBuiltinCapabilityPolicy()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if missing := manifest.MissingCapabilities(policy); len(missing) > 0 {
+		fmt.Println("Missing under current policy:", strings.Join(missing, ", "))
+		return
+	}
+	fmt.Println("Current policy grants all required capabilities.")
+}
@@ -50,6 +50,32 @@ const (
 	OpGetField
 	OpSetField
 	OpEnumValue
+	OpJumpFalseOrPop
+	OpJumpTrueOrPop
+	OpGreaterEqual
+	OpPow
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpShl
+	OpShr
+	OpIterInit
+	OpIterNext
+	OpTry
+	OpEndTry
+	OpThrow
+	OpMakeEnum
+	OpMatchTag
+	OpEnumPayload
+	OpUnpackArray
+	OpUnpackKey
+	OpUnpackField
+	OpJumpIfArg
+	OpSetFree
+	OpAssignLocal
+	OpCaptureLocal
+	OpCaptureFree
+	OpTailCall
 )
 
 type Definition struct {
@@ -64,6 +90,7 @@ var definitions = map[Opcode]*Definition{
 	OpSub:            {"OpSub", []int{}},
 	OpMul:            {"OpMul", []int{}},
 	OpDiv:            {"OpDiv", []int{}},
+	OpMod:            {"OpMod", []int{}},
 	OpTrue:           {"OpTrue", []int{}},
 	OpFalse:          {"OpFalse", []int{}},
 	OpEqual:          {"OpEqual", []int{}},
@@ -96,6 +123,32 @@ var definitions = map[Opcode]*Definition{
 	OpGetField:       {"OpGetField", []int{2}},
 	OpSetField:       {"OpSetField", []int{2}},
 	OpEnumValue:      {"OpEnumValue", []int{2, 2}},
+	OpJumpFalseOrPop: {"OpJumpFalseOrPop", []int{2}},
+	OpJumpTrueOrPop:  {"OpJumpTrueOrPop", []int{2}},
+	OpGreaterEqual:   {"OpGreaterEqual", []int{}},
+	OpPow:            {"OpPow", []int{}},
+	OpBitAnd:         {"OpBitAnd", []int{}},
+	OpBitOr:          {"OpBitOr", []int{}},
+	OpBitXor:         {"OpBitXor", []int{}},
+	OpShl:            {"OpShl", []int{}},
+	OpShr:            {"OpShr", []int{}},
+	OpIterInit:       {"OpIterInit", []int{}},
+	OpIterNext:       {"OpIterNext", []int{2, 1}},
+	OpTry:            {"OpTry", []int{2}},
+	OpEndTry:         {"OpEndTry", []int{}},
+	OpThrow:          {"OpThrow", []int{}},
+	OpMakeEnum:       {"OpMakeEnum", []int{2, 2}},
+	OpMatchTag:       {"OpMatchTag", []int{2, 2}},
+	OpEnumPayload:    {"OpEnumPayload", []int{}},
+	OpUnpackArray:    {"OpUnpackArray", []int{2, 1}},
+	OpUnpackKey:      {"OpUnpackKey", []int{2}},
+	OpUnpackField:    {"OpUnpackField", []int{2, 2}},
+	OpJumpIfArg:      {"OpJumpIfArg", []int{2, 1}},
+	OpSetFree:        {"OpSetFree", []int{1}},
+	OpAssignLocal:    {"OpAssignLocal", []int{1}},
+	OpCaptureLocal:   {"OpCaptureLocal", []int{1}},
+	OpCaptureFree:    {"OpCaptureFree", []int{1}},
+	OpTailCall:       {"OpTailCall", []int{1}},
 }
 
 func Lookup(op byte) (*Definition, error) {
@@ -181,9 +234,9 @@ func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
 	for i, width := range def.OperandWidths {
 		switch width {
 		case 1:
-			operands[i] = int(uint8(ins[0]))
+			operands[i] = int(uint8(ins[offset]))
 		case 2:
-			operands[i] = int(binary.BigEndian.Uint16(ins))
+			operands[i] = int(binary.BigEndian.Uint16(ins[offset:]))
 		}
 		offset += width
 	}
@@ -72,6 +72,7 @@ func TestReadOperands(t *testing.T) {
 		{OpConstant, []int{65535}, 2},
 		{OpGetLocal, []int{255}, 1},
 		{OpClosure, []int{65535, 255}, 3},
+		{OpIterNext, []int{300, 2}, 3},
 	}
 
 	for _, tt := range tests {
@@ -3,12 +3,12 @@ package compiler
 import (
 	"crypto/rand"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"mutant/ast"
 	"mutant/builtin"
 	"mutant/code"
 	"mutant/object"
-	"sort"
 )
 
 type Compiler struct {
@@ -17,22 +17,37 @@ type Compiler struct {
 	scopes            []CompilationScope
 	scopeIndex        int
 	structDefinitions map[string][]*ast.Identifier // Maps struct name to field names
+	structMethods     map[string]map[string]int    // Maps struct name to method constant indices
 	enumDefinitions   map[string][]string          // Maps enum name to tag names
+	enumPayloads      map[string]map[string]bool   // Maps enum name to the tags that carry a payload
 	loopContexts      []LoopContext
+	tryContexts       []tryContext
+	matchDepth        int
+	unboundParams     map[string]bool // parameters a default value may not refer to yet
+	warnings          []string        // see Warnings
+
+	sourceFile  string                       // absolute path of the file being compiled, if known
+	importStack []string                     // files currently being compiled, entry first
+	modules     map[string]map[string]Symbol // compiled modules' exports by absolute path
+	typeOrigins map[string]string            // file that declared each struct and enum name
 
 	injectSecurityChecks bool
 	hasChkDbg            bool
 	hasChkSnd            bool
 
 	polymorphicEngine *PolymorphicEngine // Optional bytecode mutation engine
+	optimizationLevel int                // see EnableOptimization
 }
 
 type ByteCode struct {
-	Instructions code.Instructions
-	Constants    []object.Object
-	StructDefs   map[string][]*ast.Identifier
-	EnumDefs     map[string][]string
-	LuaPatches   map[string]*object.LuaPatch
+	Instructions  code.Instructions
+	Constants     []object.Object
+	StructDefs    map[string][]*ast.Identifier
+	StructMethods map[string]map[string]int // struct name → method name → constant index
+	EnumDefs      map[string][]string
+	EnumPayloads  map[string]map[string]bool // enum name → tags that carry a payload
+	LuaPatches    map[string]*object.LuaPatch
+	Handlers      []object.ExceptionHandler // handler table of the main program
 }
 
 type EmittedInstruction struct {
@@ -44,6 +59,7 @@ type CompilationScope struct {
 	instructions    code.Instructions
 	lastInstruction EmittedInstruction
 	prevInstruction EmittedInstruction
+	handlers        []object.ExceptionHandler
 }
 
 type LoopContext struct {
@@ -69,7 +85,9 @@ func New() *Compiler {
 		scopes:            []CompilationScope{mainScope},
 		scopeIndex:        0,
 		structDefinitions: make(map[string][]*ast.Identifier),
+		structMethods:     make(map[string]map[string]int),
 		enumDefinitions:   make(map[string][]string),
+		enumPayloads:      make(map[string]map[string]bool),
 		loopContexts:      []LoopContext{},
 	}
 }
@@ -79,7 +97,9 @@ func NewWithState(st *SymbolTable, constants []object.Object) *Compiler {
 	compiler.symbolTable = st
 	compiler.constants = constants
 	compiler.structDefinitions = make(map[string][]*ast.Identifier)
+	compiler.structMethods = make(map[string]map[string]int)
 	compiler.enumDefinitions = make(map[string][]string)
+	compiler.enumPayloads = make(map[string]map[string]bool)
 	return compiler
 }
 
@@ -111,10 +131,17 @@ func (c *Compiler) EnablePolymorphismWithSeed(level int, seed int64) {
 func (c *Compiler) Compile(node ast.Node) error {
 	switch node := node.(type) {
 	case *ast.Program:
+		if c.optimizationLevel >= OptimizeBasic {
+			optimizeProgram(node)
+		}
 		for _, s := range node.Statements {
+			start := len(c.currentInstructions())
 			if err := c.Compile(s); err != nil {
 				return err
 			}
+			if c.optimizationLevel >= OptimizeFull {
+				c.threadJumps(start)
+			}
 			c.maybeEmitRandomSecurityCheckOpcodes()
 		}
 	case *ast.BlockStatement:
@@ -138,17 +165,24 @@ func (c *Compiler) Compile(node ast.Node) error {
 		case "!":
 			c.emit(code.OpBang)
 		default:
-			return fmt.Errorf("unknown operator %s", node.Operator)
+			return errorAt(node, "unknown operator %s", node.Operator)
 		}
 	case *ast.InfixExpression:
-		if node.Operator == "<" {
+		if node.Operator == "&&" || node.Operator == "||" {
+			return c.compileLogicalExpression(node)
+		}
+		if node.Operator == "<" || node.Operator == "<=" {
 			if err := c.Compile(node.Right); err != nil {
 				return err
 			}
 			if err := c.Compile(node.Left); err != nil {
 				return err
 			}
-			c.emit(code.OpGreater)
+			if node.Operator == "<" {
+				c.emit(code.OpGreater)
+			} else {
+				c.emit(code.OpGreaterEqual)
+			}
 			return nil
 		}
 		if err := c.Compile(node.Left); err != nil {
@@ -166,16 +200,43 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(code.OpMul)
 		case "/":
 			c.emit(code.OpDiv)
+		case "%":
+			c.emit(code.OpMod)
+		case "**":
+			c.emit(code.OpPow)
+		case "&":
+			c.emit(code.OpBitAnd)
+		case "|":
+			c.emit(code.OpBitOr)
+		case "^":
+			c.emit(code.OpBitXor)
+		case "<<":
+			c.emit(code.OpShl)
+		case ">>":
+			c.emit(code.OpShr)
 		case ">":
 			c.emit(code.OpGreater)
+		case ">=":
+			c.emit(code.OpGreaterEqual)
 		case "==":
 			c.emit(code.OpEqual)
 		case "!=":
 			c.emit(code.OpUnEqual)
 		default:
-			return fmt.Errorf("unknown operator %s", node.Operator)
+			return errorAt(node, "unknown operator %s", node.Operator)
 		}
 	case *ast.IfExpression:
+		if condition, ok := node.Condition.(*ast.Boolean); ok && c.optimizationLevel >= OptimizeBasic {
+			skipped := node.Alternative
+			if !condition.Value {
+				skipped = node.Consequence
+			}
+			// A branch that declares names is still compiled, so they stay
+			// defined for the code after the if.
+			if !blockDeclaresName(skipped) {
+				return c.compileConstantIf(node, condition.Value)
+			}
+		}
 		if err := c.Compile(node.Condition); err != nil {
 			return err
 		}
@@ -186,10 +247,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if err := c.Compile(node.Consequence); err != nil {
 			return err
 		}
-
-		if c.lastInstructionIs(code.OpPop) {
-			c.removeLastPop()
-		}
+		c.keepBlockValue()
 
 		// emit bogus jump location
 		jumpPos := c.emit(code.OpJump, 9999)
@@ -203,10 +261,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 			if err := c.Compile(node.Alternative); err != nil {
 				return err
 			}
-
-			if c.lastInstructionIs(code.OpPop) {
-				c.removeLastPop()
-			}
+			c.keepBlockValue()
 		}
 
 		afterAlternativePosition := len(c.currentInstructions())
@@ -228,6 +283,13 @@ func (c *Compiler) Compile(node ast.Node) error {
 	case *ast.StringLiteral:
 		str := &object.String{Value: node.Value}
 		c.emit(code.OpConstant, c.addConstant(str))
+	case *ast.StringifyExpression:
+		// Call the str builtin directly so user bindings named str cannot shadow it.
+		c.emit(code.OpGetBuiltin, builtin.GetBuiltinIndex("str"))
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		c.emit(code.OpCall, 1)
 	case *ast.Boolean:
 		if node.Value {
 			c.emit(code.OpTrue)
@@ -242,13 +304,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 		c.emit(code.OpArray, len(node.Elements))
 	case *ast.HashLiteral:
-		keys := []ast.Expression{}
-		for k := range node.Pairs {
-			keys = append(keys, k)
-		}
-		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
-
-		for _, k := range keys {
+		for _, k := range node.OrderedKeys() {
 			if err := c.Compile(k); err != nil {
 				return err
 			}
@@ -270,43 +326,27 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(code.OpSetLocal, symbol.Index)
 		}
 
+	case *ast.DestructureStatement:
+		return c.compileDestructureStatement(node)
+
 	case *ast.Identifier:
 		symbol, ok := c.symbolTable.Resolve(node.Value)
 		if !ok {
-			return fmt.Errorf("undefined variable: %s", node.Value)
+			return errorAt(node, "undefined variable: %s", node.Value)
+		}
+		if c.unboundParams[node.Value] && symbol.Scope == LocalScope {
+			return errorAt(node, "default value cannot refer to parameter %s, which is bound after it", node.Value)
 		}
 		c.loadSymbol(symbol)
 
 	case *ast.FunctionLiteral:
-		c.enterScope()
-		if node.Name != "" {
-			c.symbolTable.DefineFunctionName(node.Name)
-		}
-		for _, param := range node.Parameters {
-			c.symbolTable.Define(param.Value)
-		}
-		if err := c.Compile(node.Body); err != nil {
+		compiledFun, freeSymbols, err := c.compileFunctionBody(node, nil)
+		if err != nil {
 			return err
 		}
-		if c.lastInstructionIs(code.OpPop) {
-			c.replaceLastPopWithReturn()
-		}
-		if !c.lastInstructionIs(code.OpReturnValue) {
-			c.emit(code.OpReturn)
-		}
-
-		freeSymbols := c.symbolTable.FreeSymbols
-		numLocals := c.symbolTable.numDefinitions
-		insts := c.leaveScope()
 
 		for _, sym := range freeSymbols {
-			c.loadSymbol(sym)
-		}
-
-		compiledFun := &object.CompiledFunction{
-			Instructions: insts,
-			NumLocals:    numLocals,
-			NumParams:    len(node.Parameters),
+			c.captureSymbol(sym)
 		}
 
 		fnIndex := c.addConstant(compiledFun)
@@ -315,14 +355,38 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if err := c.Compile(node.ReturnValue); err != nil {
 			return err
 		}
+		if err := c.unwindTryContexts(func(tryContext) bool { return true }); err != nil {
+			return err
+		}
 		c.emit(code.OpReturnValue)
 
 	case *ast.ForStatement:
 		return c.compileForStatement(node)
 
+	case *ast.ForInStatement:
+		return c.compileForInStatement(node)
+
+	case *ast.ImportStatement:
+		return c.compileImportStatement(node)
+
+	case *ast.TryStatement:
+		return c.compileTryStatement(node)
+
+	case *ast.ThrowStatement:
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		c.emit(code.OpThrow)
+
+	case *ast.RangeExpression:
+		return errorAt(node, "range expressions are only supported as a for-in iterable")
+
 	case *ast.BreakStatement:
 		if len(c.loopContexts) == 0 {
-			return fmt.Errorf("break used outside of for loop")
+			return errorAt(node, "break used outside of for loop")
+		}
+		if err := c.unwindTryContexts(c.insideInnermostLoop); err != nil {
+			return err
 		}
 		jumpPos := c.emit(code.OpJump, 9999)
 		ctx := &c.loopContexts[len(c.loopContexts)-1]
@@ -330,24 +394,33 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 	case *ast.ContinueStatement:
 		if len(c.loopContexts) == 0 {
-			return fmt.Errorf("continue used outside of for loop")
+			return errorAt(node, "continue used outside of for loop")
+		}
+		if err := c.unwindTryContexts(c.insideInnermostLoop); err != nil {
+			return err
 		}
 		jumpPos := c.emit(code.OpJump, 9999)
 		ctx := &c.loopContexts[len(c.loopContexts)-1]
 		ctx.continuePositions = append(ctx.continuePositions, jumpPos)
 
 	case *ast.StructStatement:
-		// Store struct definition
-		c.structDefinitions[node.Name.Value] = node.Fields
-		return nil
+		return c.compileStructStatement(node)
 
 	case *ast.EnumStatement:
+		if err := c.declareType(node.Name, node.Name.Value); err != nil {
+			return err
+		}
 		// Store enum definition
 		tags := []string{}
+		payloads := map[string]bool{}
 		for _, variant := range node.Variants {
 			tags = append(tags, variant.Value)
+			if _, ok := node.Payloads[variant.Value]; ok {
+				payloads[variant.Value] = true
+			}
 		}
 		c.enumDefinitions[node.Name.Value] = tags
+		c.enumPayloads[node.Name.Value] = payloads
 		return nil
 
 	case *ast.AssignExpression:
@@ -359,11 +432,22 @@ func (c *Compiler) Compile(node ast.Node) error {
 	case *ast.StructLiteral:
 		return c.compileStructLiteral(node)
 
+	case *ast.MatchExpression:
+		return c.compileMatchExpression(node)
+
 	case *ast.CallExpression:
+		if enum, tag, ok := c.enumVariantOf(node.Function); ok {
+			return c.compileEnumConstructor(node, enum, tag)
+		}
+		c.checkPatternArgument(node)
 		if err := c.Compile(node.Function); err != nil {
 			return err
 		}
 		for _, arg := range node.Arguments {
+			if enum, ok := c.enumTypeArgument(node, arg); ok {
+				c.emit(code.OpConstant, c.addConstant(&object.String{Value: enum}))
+				continue
+			}
 			if err := c.Compile(arg); err != nil {
 				return err
 			}
@@ -374,17 +458,43 @@ func (c *Compiler) Compile(node ast.Node) error {
 	return nil
 }
 
+// enumTypeArgument reports whether arg is the bare name of an enum type
+// passed to a builtin that takes one, such as enum_variants
+func (c *Compiler) enumTypeArgument(call *ast.CallExpression, arg ast.Expression) (string, bool) {
+	fn, ok := call.Function.(*ast.Identifier)
+	if !ok || !builtin.TakesEnumType(fn.Value) {
+		return "", false
+	}
+	if symbol, ok := c.symbolTable.Resolve(fn.Value); !ok || symbol.Scope != BuiltinScope {
+		return "", false
+	}
+	ident, ok := arg.(*ast.Identifier)
+	if !ok {
+		return "", false
+	}
+	if _, exists := c.enumDefinitions[ident.Value]; !exists {
+		return "", false
+	}
+	if _, shadowed := c.symbolTable.Resolve(ident.Value); shadowed {
+		return "", false
+	}
+	return ident.Value, true
+}
+
 func (c *Compiler) ByteCode() *ByteCode {
 	if c.injectSecurityChecks {
 		c.ensureRequiredSecurityCheckOpcodes()
 	}
 
 	bytecode := &ByteCode{
-		Instructions: c.currentInstructions(),
-		Constants:    c.constants,
-		StructDefs:   c.structDefinitions,
-		EnumDefs:     c.enumDefinitions,
-		LuaPatches:   make(map[string]*object.LuaPatch),
+		Instructions:  c.currentInstructions(),
+		Constants:     c.constants,
+		StructDefs:    c.structDefinitions,
+		StructMethods: c.structMethods,
+		EnumDefs:      c.enumDefinitions,
+		EnumPayloads:  c.enumPayloads,
+		LuaPatches:    make(map[string]*object.LuaPatch),
+		Handlers:      c.scopes[0].handlers,
 	}
 
 	// Apply polymorphic mutations if engine is enabled
@@ -483,9 +593,17 @@ func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
 	}
 }
 
+// changeOperand patches the first operand of the instruction at pos,
+// keeping any further operands (such as OpIterNext's binding count)
 func (c *Compiler) changeOperand(pos int, operand int) {
-	op := code.Opcode(c.currentInstructions()[pos])
-	newInstruction := code.Make(op, operand)
+	ins := c.currentInstructions()
+	op := code.Opcode(ins[pos])
+	operands := []int{operand}
+	if def, err := code.Lookup(byte(op)); err == nil && len(def.OperandWidths) > 1 {
+		operands, _ = code.ReadOperands(def, ins[pos+1:])
+		operands[0] = operand
+	}
+	newInstruction := code.Make(op, operands...)
 	c.replaceInstruction(pos, newInstruction)
 }
 
@@ -493,6 +611,16 @@ func (c *Compiler) currentInstructions() code.Instructions {
 	return c.scopes[c.scopeIndex].instructions
 }
 
+// keepBlockValue leaves the value of a just-compiled if branch on the stack:
+// the last expression's value, or null when the block ends in a statement.
+func (c *Compiler) keepBlockValue() {
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+}
+
 func (c *Compiler) enterScope() {
 	scope := CompilationScope{
 		instructions:    code.Instructions{},
@@ -529,6 +657,39 @@ func (c *Compiler) loadSymbol(s Symbol) {
 	}
 }
 
+// captureSymbol pushes what a new closure stores for the free variable s:
+// the shared cell of a local or free variable, or the value itself for a
+// function's own name.
+func (c *Compiler) captureSymbol(s Symbol) {
+	switch s.Scope {
+	case LocalScope:
+		c.emit(code.OpCaptureLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpCaptureFree, s.Index)
+	default:
+		c.loadSymbol(s)
+	}
+}
+
+// assignSymbol emits the store for an assignment to an existing variable.
+// Unlike the store of a `let`, it writes through the variable's cell, so
+// closures that captured the variable see the new value.
+func (c *Compiler) assignSymbol(node ast.Node, s Symbol) error {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpSetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpAssignLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpSetFree, s.Index)
+	case FunctionScope:
+		return errorAt(node, "cannot assign to %s inside the function of that name", s.Name)
+	default:
+		return errorAt(node, "cannot assign to builtin %s", s.Name)
+	}
+	return nil
+}
+
 func (c *Compiler) compileForStatement(node *ast.ForStatement) error {
 	initStart := len(c.currentInstructions())
 	if node.Init != nil {
@@ -590,6 +751,99 @@ func (c *Compiler) compileForStatement(node *ast.ForStatement) error {
 	return nil
 }
 
+// compileForInStatement lowers for-in to a counted loop over hidden slots.
+// Ranges count directly from Start to End; every other iterable is
+// snapshotted by OpIterInit and stepped with OpIterNext.
+func (c *Compiler) compileForInStatement(node *ast.ForInStatement) error {
+	depth := len(c.loopContexts)
+	cursor := c.symbolTable.defineHidden(fmt.Sprintf("$cursor%d", depth))
+	source := c.symbolTable.defineHidden(fmt.Sprintf("$iter%d", depth))
+
+	rng, isRange := node.Iterable.(*ast.RangeExpression)
+	if isRange {
+		if node.Key != nil {
+			return errorAt(node.Key, "range loops bind a single variable")
+		}
+		if err := c.Compile(rng.Start); err != nil {
+			return err
+		}
+		c.storeSymbol(cursor)
+		if err := c.Compile(rng.End); err != nil {
+			return err
+		}
+		c.storeSymbol(source)
+	} else {
+		if err := c.Compile(node.Iterable); err != nil {
+			return err
+		}
+		c.emit(code.OpIterInit)
+		c.storeSymbol(source)
+		c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: 0}))
+		c.storeSymbol(cursor)
+	}
+
+	conditionStartPosition := len(c.currentInstructions())
+	var exitPosition int
+	if isRange {
+		c.loadSymbol(source)
+		c.loadSymbol(cursor)
+		c.emit(code.OpGreater)
+		exitPosition = c.emit(code.OpJumpFalse, 9999)
+		c.loadSymbol(cursor)
+		c.storeSymbol(c.symbolTable.Define(node.Value.Value))
+	} else {
+		c.loadSymbol(source)
+		c.loadSymbol(cursor)
+		bindings := 1
+		if node.Key != nil {
+			bindings = 2
+		}
+		exitPosition = c.emit(code.OpIterNext, 9999, bindings)
+		// OpIterNext pushes the key below the value, so bind in reverse
+		c.storeSymbol(c.symbolTable.Define(node.Value.Value))
+		if node.Key != nil {
+			c.storeSymbol(c.symbolTable.Define(node.Key.Value))
+		}
+	}
+
+	c.loopContexts = append(c.loopContexts, LoopContext{})
+	if err := c.Compile(node.Body); err != nil {
+		c.loopContexts = c.loopContexts[:len(c.loopContexts)-1]
+		return err
+	}
+
+	stepPosition := len(c.currentInstructions())
+	ctx := &c.loopContexts[len(c.loopContexts)-1]
+	for _, pos := range ctx.continuePositions {
+		c.changeOperand(pos, stepPosition)
+	}
+
+	c.loadSymbol(cursor)
+	c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: 1}))
+	c.emit(code.OpAdd)
+	c.storeSymbol(cursor)
+	c.emit(code.OpJump, conditionStartPosition)
+
+	loopEndPosition := len(c.currentInstructions())
+	c.changeOperand(exitPosition, loopEndPosition)
+	for _, pos := range ctx.breakPositions {
+		c.changeOperand(pos, loopEndPosition)
+	}
+
+	c.loopContexts = c.loopContexts[:len(c.loopContexts)-1]
+
+	return nil
+}
+
+// storeSymbol pops the top of the stack into a global or local slot
+func (c *Compiler) storeSymbol(s Symbol) {
+	if s.Scope == GlobalScope {
+		c.emit(code.OpSetGlobal, s.Index)
+	} else {
+		c.emit(code.OpSetLocal, s.Index)
+	}
+}
+
 func (c *Compiler) compileAssignExpression(node *ast.AssignExpression) error {
 	// Handle identifier assignment: x = value
 	if ident, ok := node.Left.(*ast.Identifier); ok {
@@ -603,18 +857,27 @@ func (c *Compiler) compileAssignExpression(node *ast.AssignExpression) error {
 			symbol = c.symbolTable.Define(ident.Value)
 		}
 
-		if symbol.Scope == GlobalScope {
-			c.emit(code.OpSetGlobal, symbol.Index)
-			c.emit(code.OpGetGlobal, symbol.Index)
-		} else {
-			c.emit(code.OpSetLocal, symbol.Index)
-			c.emit(code.OpGetLocal, symbol.Index)
+		if err := c.assignSymbol(ident, symbol); err != nil {
+			return err
 		}
+		c.loadSymbol(symbol)
 		return nil
 	}
 
 	// Handle field assignment: struct.field = value
 	if fieldExpr, ok := node.Left.(*ast.FieldExpression); ok {
+		if symbol, isMember, err := c.resolveModuleMember(fieldExpr); isMember || err != nil {
+			if err != nil {
+				return err
+			}
+			if err := c.Compile(node.Value); err != nil {
+				return err
+			}
+			c.emit(code.OpSetGlobal, symbol.Index)
+			c.emit(code.OpGetGlobal, symbol.Index)
+			return nil
+		}
+
 		if err := c.Compile(fieldExpr.Left); err != nil {
 			return err
 		}
@@ -631,27 +894,27 @@ func (c *Compiler) compileAssignExpression(node *ast.AssignExpression) error {
 		if ident, ok := fieldExpr.Left.(*ast.Identifier); ok {
 			symbol, resolved := c.symbolTable.Resolve(ident.Value)
 			if !resolved {
-				return fmt.Errorf("undefined variable: %s", ident.Value)
+				return errorAt(ident, "undefined variable: %s", ident.Value)
 			}
 
-			if symbol.Scope == GlobalScope {
-				c.emit(code.OpSetGlobal, symbol.Index)
-				c.emit(code.OpGetGlobal, symbol.Index)
-			} else {
-				c.emit(code.OpSetLocal, symbol.Index)
-				c.emit(code.OpGetLocal, symbol.Index)
+			if err := c.assignSymbol(ident, symbol); err != nil {
+				return err
 			}
+			c.loadSymbol(symbol)
 			c.emit(code.OpGetField, fieldNameIndex)
 		}
 		return nil
 	}
 
-	return fmt.Errorf("invalid assignment target")
+	return errorAt(node, "invalid assignment target")
 }
 
 func (c *Compiler) compileFieldExpression(node *ast.FieldExpression) error {
 	if ident, ok := node.Left.(*ast.Identifier); ok {
 		if _, exists := c.enumDefinitions[ident.Value]; exists {
+			if c.enumPayloads[ident.Value][node.Field.Value] {
+				return errorAt(node, "enum variant %s.%s requires a payload", ident.Value, node.Field.Value)
+			}
 			typeNameIndex := c.addConstant(&object.String{Value: ident.Value})
 			tagNameIndex := c.addConstant(&object.String{Value: node.Field.Value})
 			c.emit(code.OpEnumValue, typeNameIndex, tagNameIndex)
@@ -659,6 +922,13 @@ func (c *Compiler) compileFieldExpression(node *ast.FieldExpression) error {
 		}
 	}
 
+	if symbol, ok, err := c.resolveModuleMember(node); ok || err != nil {
+		if err == nil {
+			c.loadSymbol(symbol)
+		}
+		return err
+	}
+
 	if err := c.Compile(node.Left); err != nil {
 		return err
 	}
@@ -668,20 +938,173 @@ func (c *Compiler) compileFieldExpression(node *ast.FieldExpression) error {
 	return nil
 }
 
+// resolveModuleMember resolves `ns.name` when ns is an imported module rather
+// than a variable. ok is false when node is an ordinary field access.
+func (c *Compiler) resolveModuleMember(node *ast.FieldExpression) (Symbol, bool, error) {
+	ident, isIdent := node.Left.(*ast.Identifier)
+	if !isIdent {
+		return Symbol{}, false, nil
+	}
+	if _, isVariable := c.symbolTable.Resolve(ident.Value); isVariable {
+		return Symbol{}, false, nil
+	}
+	members, isNamespace := c.symbolTable.ResolveNamespace(ident.Value)
+	if !isNamespace {
+		return Symbol{}, false, nil
+	}
+	symbol, ok := members[node.Field.Value]
+	if !ok {
+		return Symbol{}, true, errorAt(node.Field, "module %s has no member %s", ident.Value, node.Field.Value)
+	}
+	return symbol, true, nil
+}
+
+// compileFunctionBody compiles fn in a fresh scope and returns it with the
+// free symbols its closure must capture. A non-nil receiver is bound as an
+// implicit first parameter, as for struct methods.
+//
+// Parameters with defaults get a prologue that runs before the body:
+//
+//	OpJumpIfArg skip, i; <default>; OpSetLocal i
+//	skip: ...
+func (c *Compiler) compileFunctionBody(fn *ast.FunctionLiteral, receiver *ast.Identifier) (*object.CompiledFunction, []Symbol, error) {
+	c.enterScope()
+	outerUnbound := c.unboundParams
+	c.unboundParams = nil
+	defer func() { c.unboundParams = outerUnbound }()
+
+	if fn.Name != "" {
+		c.symbolTable.DefineFunctionName(fn.Name)
+	}
+	params := fn.Parameters
+	if receiver != nil {
+		params = append([]*ast.Identifier{receiver}, params...)
+	}
+	symbols := make([]Symbol, len(params))
+	for i, param := range params {
+		symbols[i] = c.symbolTable.Define(param.Value)
+	}
+	if fn.Rest != nil {
+		c.symbolTable.Define(fn.Rest.Value)
+	}
+
+	offset := len(params) - len(fn.Parameters)
+	for i := range fn.Parameters {
+		def := fn.DefaultFor(i)
+		if def == nil {
+			continue
+		}
+		if err := c.compileDefaultParameter(symbols[offset+i], def, fn, i); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := c.Compile(fn.Body); err != nil {
+		return nil, nil, err
+	}
+	if c.lastInstructionIs(code.OpPop) {
+		c.replaceLastPopWithReturn()
+	}
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpReturn)
+	}
+	if c.optimizationLevel >= OptimizeFull {
+		c.threadJumps(0)
+	}
+	c.markTailCalls()
+
+	freeSymbols := c.symbolTable.FreeSymbols
+	numLocals := c.symbolTable.numDefinitions
+	handlers := c.scopes[c.scopeIndex].handlers
+	insts := c.leaveScope()
+
+	compiledFun := &object.CompiledFunction{
+		Instructions: insts,
+		NumLocals:    numLocals,
+		NumParams:    len(params),
+		NumDefaults:  fn.NumDefaults(),
+		Variadic:     fn.Rest != nil,
+		Handlers:     handlers,
+	}
+	return compiledFun, freeSymbols, nil
+}
+
+// compileDefaultParameter emits the prologue that stores def into param when
+// the caller omitted it. The default may only refer to the parameters before
+// it, since the ones after it are not bound yet.
+func (c *Compiler) compileDefaultParameter(param Symbol, def ast.Expression, fn *ast.FunctionLiteral, index int) error {
+	c.unboundParams = make(map[string]bool)
+	for _, later := range fn.Parameters[index:] {
+		c.unboundParams[later.Value] = true
+	}
+	if fn.Rest != nil {
+		c.unboundParams[fn.Rest.Value] = true
+	}
+	defer func() { c.unboundParams = nil }()
+
+	skip := c.emit(code.OpJumpIfArg, 9999, param.Index)
+	if err := c.Compile(def); err != nil {
+		return err
+	}
+	c.storeSymbol(param)
+	c.changeOperand(skip, len(c.currentInstructions()))
+	return nil
+}
+
+// compileStructStatement records the struct's fields and compiles each method
+// as a function constant whose first parameter is the receiver `self`. The
+// constant indices are kept in structMethods for OpGetField to dispatch on.
+func (c *Compiler) compileStructStatement(node *ast.StructStatement) error {
+	structName := node.Name.Value
+	if err := c.declareType(node.Name, structName); err != nil {
+		return err
+	}
+	c.structDefinitions[structName] = node.Fields
+
+	fields := make(map[string]bool, len(node.Fields))
+	for _, field := range node.Fields {
+		fields[field.Value] = true
+	}
+
+	methods := make(map[string]int, len(node.Methods))
+	for _, method := range node.Methods {
+		methodName := method.Name.Value
+		if fields[methodName] {
+			return errorAt(method.Name, "struct %s has both a field and a method named %s", structName, methodName)
+		}
+		if _, exists := methods[methodName]; exists {
+			return errorAt(method.Name, "duplicate method %s.%s", structName, methodName)
+		}
+
+		self := &ast.Identifier{Token: method.Token, Value: "self"}
+		fn, freeSymbols, err := c.compileFunctionBody(method.Function, self)
+		if err != nil {
+			return err
+		}
+		if len(freeSymbols) > 0 {
+			return errorAt(method.Name, "method %s.%s cannot capture local variable %s", structName, methodName, freeSymbols[0].Name)
+		}
+		methods[methodName] = c.addConstant(fn)
+	}
+	c.structMethods[structName] = methods
+
+	return nil
+}
+
 func (c *Compiler) compileStructLiteral(node *ast.StructLiteral) error {
 	structName := node.Name.Value
 	typeDef, ok := c.structDefinitions[structName]
 	if !ok {
-		return fmt.Errorf("undefined struct type: %s", structName)
+		return errorAt(node.Name, "undefined struct type: %s", structName)
 	}
 	if len(typeDef) != len(node.Fields) {
-		return fmt.Errorf("struct %s expects %d fields, got %d", structName, len(typeDef), len(node.Fields))
+		return errorAt(node, "struct %s expects %d fields, got %d", structName, len(typeDef), len(node.Fields))
 	}
 
 	fieldExprByName := make(map[string]ast.Expression, len(node.Fields))
 	for _, field := range node.Fields {
 		if field == nil || field.Name == nil {
-			return fmt.Errorf("invalid field initializer in struct %s", structName)
+			return errorAt(node, "invalid field initializer in struct %s", structName)
 		}
 		fieldExprByName[field.Name.Value] = field.Value
 	}
@@ -690,7 +1113,7 @@ func (c *Compiler) compileStructLiteral(node *ast.StructLiteral) error {
 	for _, field := range typeDef {
 		expr, exists := fieldExprByName[field.Value]
 		if !exists {
-			return fmt.Errorf("missing field %s for struct %s", field.Value, structName)
+			return errorAt(node, "missing field %s for struct %s", field.Value, structName)
 		}
 		if err := c.Compile(expr); err != nil {
 			return err
@@ -700,3 +1123,34 @@ func (c *Compiler) compileStructLiteral(node *ast.StructLiteral) error {
 	c.emit(code.OpMakeStruct, typeNameIndex, len(typeDef))
 	return nil
 }
+
+// errorAt builds a compiler error prefixed with the source position of node
+func errorAt(node ast.Node, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	if pos := node.Pos(); pos.IsValid() {
+		return fmt.Errorf("%s: %s", pos, msg)
+	}
+	return errors.New(msg)
+}
+
+// compileLogicalExpression emits short-circuit code for && and ||. The left value
+// stays on the stack when it decides the result; otherwise it is popped and the
+// right operand is evaluated in its place.
+func (c *Compiler) compileLogicalExpression(node *ast.InfixExpression) error {
+	if err := c.Compile(node.Left); err != nil {
+		return err
+	}
+
+	jumpOp := code.OpJumpFalseOrPop
+	if node.Operator == "||" {
+		jumpOp = code.OpJumpTrueOrPop
+	}
+	jumpPos := c.emit(jumpOp, 9999)
+
+	if err := c.Compile(node.Right); err != nil {
+		return err
+	}
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+	return nil
+}
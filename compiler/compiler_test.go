@@ -3,10 +3,12 @@ package compiler
 import (
 	"fmt"
 	"mutant/ast"
+	"mutant/builtin"
 	"mutant/code"
 	"mutant/lexer"
 	"mutant/object"
 	"mutant/parser"
+	"strings"
 	"testing"
 )
 
@@ -337,6 +339,100 @@ func TestConditionals(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestExtendedBinaryOperators(t *testing.T) {
+	operators := map[string]code.Opcode{
+		"%":  code.OpMod,
+		"**": code.OpPow,
+		"&":  code.OpBitAnd,
+		"|":  code.OpBitOr,
+		"^":  code.OpBitXor,
+		"<<": code.OpShl,
+		">>": code.OpShr,
+		">=": code.OpGreaterEqual,
+	}
+
+	tests := []compilerTestCase{}
+	for operator, op := range operators {
+		tests = append(tests, compilerTestCase{
+			input:             "1 " + operator + " 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(op),
+				code.Make(code.OpPop),
+			},
+		})
+	}
+
+	tests = append(tests,
+		compilerTestCase{
+			input:             "1 <= 2",
+			expectedConstants: []interface{}{2, 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpGreaterEqual),
+				code.Make(code.OpPop),
+			},
+		},
+		compilerTestCase{
+			input:             "let x = 1; x += 2;",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	)
+
+	runCompilerTests(t, tests)
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "true && false; 1;",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpFalseOrPop, 5),
+				// 0004
+				code.Make(code.OpFalse),
+				// 0005
+				code.Make(code.OpPop),
+				// 0006
+				code.Make(code.OpConstant, 0),
+				// 0009
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "false || true;",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpFalse),
+				// 0001
+				code.Make(code.OpJumpTrueOrPop, 5),
+				// 0004
+				code.Make(code.OpTrue),
+				// 0005
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
 func TestGlobalLetStatements(t *testing.T) {
 	tests := []compilerTestCase{
 		{
@@ -819,7 +915,7 @@ func TestBuiltins(t *testing.T) {
 				[]code.Instructions{
 					code.Make(code.OpGetBuiltin, 0),
 					code.Make(code.OpArray, 0),
-					code.Make(code.OpCall, 1),
+					code.Make(code.OpTailCall, 1),
 					code.Make(code.OpReturnValue),
 				},
 			},
@@ -856,7 +952,7 @@ func TestClosures(t *testing.T) {
 					code.Make(code.OpAdd),
 					code.Make(code.OpReturnValue),
 				},
-				[]code.Instructions{code.Make(code.OpGetLocal, 0),
+				[]code.Instructions{code.Make(code.OpCaptureLocal, 0),
 					code.Make(code.OpClosure, 0, 1),
 					code.Make(code.OpReturnValue),
 				},
@@ -878,13 +974,13 @@ func TestClosures(t *testing.T) {
 					code.Make(code.OpReturnValue),
 				},
 				[]code.Instructions{
-					code.Make(code.OpGetFree, 0),
-					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpCaptureFree, 0),
+					code.Make(code.OpCaptureLocal, 0),
 					code.Make(code.OpClosure, 0, 2),
 					code.Make(code.OpReturnValue),
 				},
 				[]code.Instructions{
-					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpCaptureLocal, 0),
 					code.Make(code.OpClosure, 1, 1),
 					code.Make(code.OpReturnValue),
 				},
@@ -916,15 +1012,15 @@ func TestClosures(t *testing.T) {
 				[]code.Instructions{
 					code.Make(code.OpConstant, 2),
 					code.Make(code.OpSetLocal, 0),
-					code.Make(code.OpGetFree, 0),
-					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpCaptureFree, 0),
+					code.Make(code.OpCaptureLocal, 0),
 					code.Make(code.OpClosure, 4, 2),
 					code.Make(code.OpReturnValue),
 				},
 				[]code.Instructions{
 					code.Make(code.OpConstant, 1),
 					code.Make(code.OpSetLocal, 0),
-					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpCaptureLocal, 0),
 					code.Make(code.OpClosure, 5, 1),
 					code.Make(code.OpReturnValue),
 				},
@@ -955,7 +1051,7 @@ func TestRecursiveFunctions(t *testing.T) {
 					code.Make(code.OpGetLocal, 0),
 					code.Make(code.OpConstant, 0),
 					code.Make(code.OpSub),
-					code.Make(code.OpCall, 1),
+					code.Make(code.OpTailCall, 1),
 					code.Make(code.OpReturnValue),
 				},
 				1,
@@ -984,7 +1080,7 @@ func TestRecursiveFunctions(t *testing.T) {
 					code.Make(code.OpGetLocal, 0),
 					code.Make(code.OpConstant, 0),
 					code.Make(code.OpSub),
-					code.Make(code.OpCall, 1),
+					code.Make(code.OpTailCall, 1),
 					code.Make(code.OpReturnValue),
 				},
 				1,
@@ -993,7 +1089,7 @@ func TestRecursiveFunctions(t *testing.T) {
 					code.Make(code.OpSetLocal, 0),
 					code.Make(code.OpGetLocal, 0),
 					code.Make(code.OpConstant, 2),
-					code.Make(code.OpCall, 1),
+					code.Make(code.OpTailCall, 1),
 					code.Make(code.OpReturnValue),
 				},
 			},
@@ -1009,3 +1105,783 @@ func TestRecursiveFunctions(t *testing.T) {
 
 	runCompilerTests(t, tests)
 }
+
+func TestForInStatement(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "for (k, v in []) { v }",
+			expectedConstants: []interface{}{0, 1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpArray, 0),
+				// 0003
+				code.Make(code.OpIterInit),
+				// 0004
+				code.Make(code.OpSetGlobal, 1),
+				// 0007
+				code.Make(code.OpConstant, 0),
+				// 0010
+				code.Make(code.OpSetGlobal, 0),
+				// 0013
+				code.Make(code.OpGetGlobal, 1),
+				// 0016
+				code.Make(code.OpGetGlobal, 0),
+				// 0019
+				code.Make(code.OpIterNext, 46, 2),
+				// 0023
+				code.Make(code.OpSetGlobal, 2),
+				// 0026
+				code.Make(code.OpSetGlobal, 3),
+				// 0029
+				code.Make(code.OpGetGlobal, 2),
+				// 0032
+				code.Make(code.OpPop),
+				// 0033
+				code.Make(code.OpGetGlobal, 0),
+				// 0036
+				code.Make(code.OpConstant, 1),
+				// 0039
+				code.Make(code.OpAdd),
+				// 0040
+				code.Make(code.OpSetGlobal, 0),
+				// 0043
+				code.Make(code.OpJump, 13),
+			},
+		},
+		{
+			input:             "for (i in 0..3) { break; }",
+			expectedConstants: []interface{}{0, 3, 1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpSetGlobal, 0),
+				// 0006
+				code.Make(code.OpConstant, 1),
+				// 0009
+				code.Make(code.OpSetGlobal, 1),
+				// 0012
+				code.Make(code.OpGetGlobal, 1),
+				// 0015
+				code.Make(code.OpGetGlobal, 0),
+				// 0018
+				code.Make(code.OpGreater),
+				// 0019
+				code.Make(code.OpJumpFalse, 44),
+				// 0022
+				code.Make(code.OpGetGlobal, 0),
+				// 0025
+				code.Make(code.OpSetGlobal, 2),
+				// 0028
+				code.Make(code.OpJump, 44),
+				// 0031
+				code.Make(code.OpGetGlobal, 0),
+				// 0034
+				code.Make(code.OpConstant, 2),
+				// 0037
+				code.Make(code.OpAdd),
+				// 0038
+				code.Make(code.OpSetGlobal, 0),
+				// 0041
+				code.Make(code.OpJump, 12),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestCompilerErrorsIncludePositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = 1;\nlet b = a + c;", "2:13: undefined variable: c"},
+		{"let f = fn() {\n  break;\n};", "2:3: break used outside of for loop"},
+		{"struct P { x; }\nlet p = Q { x: 1 };", "2:9: undefined struct type: Q"},
+		{"let r = 0..3;", "1:10: range expressions are only supported as a for-in iterable"},
+		{"for (i, x in 0..3) { x }", "1:6: range loops bind a single variable"},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+		err := compiler.Compile(parse(tt.input))
+		if err == nil {
+			t.Fatalf("expected compiler error for %q", tt.input)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestTryStatement(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "try { 1 } catch (e) { e } finally { 2 }",
+			expectedConstants: []interface{}{1, 2, 2, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTry, 0),
+				// 0003
+				code.Make(code.OpConstant, 0),
+				// 0006
+				code.Make(code.OpPop),
+				// 0007
+				code.Make(code.OpEndTry),
+				// 0008
+				code.Make(code.OpConstant, 1),
+				// 0011
+				code.Make(code.OpPop),
+				// 0012
+				code.Make(code.OpJump, 44),
+				// 0015
+				code.Make(code.OpSetGlobal, 0),
+				// 0018
+				code.Make(code.OpTry, 1),
+				// 0021
+				code.Make(code.OpGetGlobal, 0),
+				// 0024
+				code.Make(code.OpPop),
+				// 0025
+				code.Make(code.OpEndTry),
+				// 0026
+				code.Make(code.OpConstant, 2),
+				// 0029
+				code.Make(code.OpPop),
+				// 0030
+				code.Make(code.OpJump, 44),
+				// 0033
+				code.Make(code.OpSetGlobal, 1),
+				// 0036
+				code.Make(code.OpConstant, 3),
+				// 0039
+				code.Make(code.OpPop),
+				// 0040
+				code.Make(code.OpGetGlobal, 1),
+				// 0043
+				code.Make(code.OpThrow),
+			},
+		},
+		{
+			input: "fn() { try { return 1; } finally { 2 } }",
+			expectedConstants: []interface{}{
+				1, 2, 2, 2,
+				[]code.Instructions{
+					// 0000
+					code.Make(code.OpTry, 0),
+					// 0003
+					code.Make(code.OpConstant, 0),
+					// 0006
+					code.Make(code.OpEndTry),
+					// 0007
+					code.Make(code.OpConstant, 1),
+					// 0010
+					code.Make(code.OpPop),
+					// 0011
+					code.Make(code.OpReturnValue),
+					// 0012
+					code.Make(code.OpEndTry),
+					// 0013
+					code.Make(code.OpConstant, 2),
+					// 0016
+					code.Make(code.OpPop),
+					// 0017
+					code.Make(code.OpJump, 29),
+					// 0020
+					code.Make(code.OpSetLocal, 0),
+					// 0022
+					code.Make(code.OpConstant, 3),
+					// 0025
+					code.Make(code.OpPop),
+					// 0026
+					code.Make(code.OpGetLocal, 0),
+					// 0028
+					code.Make(code.OpThrow),
+					// 0029
+					code.Make(code.OpReturn),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 4, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "for (x in []) { try { break; } catch { 1 } }",
+			expectedConstants: []interface{}{0, 1, 1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpArray, 0),
+				// 0003
+				code.Make(code.OpIterInit),
+				// 0004
+				code.Make(code.OpSetGlobal, 1),
+				// 0007
+				code.Make(code.OpConstant, 0),
+				// 0010
+				code.Make(code.OpSetGlobal, 0),
+				// 0013
+				code.Make(code.OpGetGlobal, 1),
+				// 0016
+				code.Make(code.OpGetGlobal, 0),
+				// 0019
+				code.Make(code.OpIterNext, 58, 1),
+				// 0023
+				code.Make(code.OpSetGlobal, 2),
+				// 0026
+				code.Make(code.OpTry, 0),
+				// 0029
+				code.Make(code.OpEndTry),
+				// 0030
+				code.Make(code.OpJump, 58),
+				// 0033
+				code.Make(code.OpEndTry),
+				// 0034
+				code.Make(code.OpJump, 45),
+				// 0037
+				code.Make(code.OpPop),
+				// 0038
+				code.Make(code.OpConstant, 1),
+				// 0041
+				code.Make(code.OpPop),
+				// 0042
+				code.Make(code.OpJump, 45),
+				// 0045
+				code.Make(code.OpGetGlobal, 0),
+				// 0048
+				code.Make(code.OpConstant, 2),
+				// 0051
+				code.Make(code.OpAdd),
+				// 0052
+				code.Make(code.OpSetGlobal, 0),
+				// 0055
+				code.Make(code.OpJump, 13),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestTryStatementHandlerTable(t *testing.T) {
+	compiler := New()
+	if err := compiler.Compile(parse("try { 1 } catch (e) { e } finally { 2 }")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	expected := []object.ExceptionHandler{
+		{Start: 0, End: 7, Target: 15},
+		{Start: 18, End: 25, Target: 33},
+	}
+	handlers := compiler.ByteCode().Handlers
+	if len(handlers) != len(expected) {
+		t.Fatalf("wrong number of handlers. want=%d, got=%d", len(expected), len(handlers))
+	}
+	for i, want := range expected {
+		if handlers[i] != want {
+			t.Errorf("wrong handler %d. want=%+v, got=%+v", i, want, handlers[i])
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "enum O { S(v), N } match (O.S(1)) { O.S(v) => v, _ => 0 }",
+			expectedConstants: []interface{}{1, "O", "S", "O", "S", 0},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpMakeEnum, 1, 2),
+				// 0008
+				code.Make(code.OpSetGlobal, 0),
+				// 0011
+				code.Make(code.OpGetGlobal, 0),
+				// 0014
+				code.Make(code.OpMatchTag, 3, 4),
+				// 0019
+				code.Make(code.OpJumpFalse, 35),
+				// 0022
+				code.Make(code.OpGetGlobal, 0),
+				// 0025
+				code.Make(code.OpEnumPayload),
+				// 0026
+				code.Make(code.OpSetGlobal, 1),
+				// 0029
+				code.Make(code.OpGetGlobal, 1),
+				// 0032
+				code.Make(code.OpJump, 41),
+				// 0035
+				code.Make(code.OpConstant, 5),
+				// 0038
+				code.Make(code.OpJump, 41),
+				// 0041
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `match (1) { 2 => "a", _ => "b" }`,
+			expectedConstants: []interface{}{1, 2, "a", "b"},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpSetGlobal, 0),
+				// 0006
+				code.Make(code.OpGetGlobal, 0),
+				// 0009
+				code.Make(code.OpConstant, 1),
+				// 0012
+				code.Make(code.OpEqual),
+				// 0013
+				code.Make(code.OpJumpFalse, 22),
+				// 0016
+				code.Make(code.OpConstant, 2),
+				// 0019
+				code.Make(code.OpJump, 28),
+				// 0022
+				code.Make(code.OpConstant, 3),
+				// 0025
+				code.Make(code.OpJump, 28),
+				// 0028
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestMatchExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"enum O { S(v), N }\nmatch (1) { O.S(v) => v }", "2:1: non-exhaustive match on O: missing N"},
+		{"match (1) { 1 => 1 }", "1:1: non-exhaustive match: add a `_` arm"},
+		{"match (1) { _ => 1, 2 => 2 }", "1:21: unreachable match arm after `_`"},
+		{"enum O { S(v), N }\nmatch (1) { O.S(v) => v, 1 => 2, _ => 3 }", "2:26: match arms mix enum and literal patterns"},
+		{"enum A { X }\nenum B { Y }\nmatch (1) { A.X => 1, B.Y => 2 }", "3:23: match arms mix enums A and B"},
+		{"match (1) { Nope.X => 1, _ => 2 }", "1:13: unknown enum Nope"},
+		{"enum O { S(v), N }\nmatch (1) { O.T => 1, _ => 2 }", "2:13: unknown enum tag O.T"},
+		{"enum O { S(v), N }\nmatch (1) { O.N => 1, O.N => 2, _ => 3 }", "2:23: duplicate match arm for O.N"},
+		{"enum O { S(v), N }\nmatch (1) { O.N(x) => 1, _ => 2 }", "2:13: enum variant O.N has no payload to bind"},
+		{"enum O { S(v), N }\nO.S", "2:2: enum variant O.S requires a payload"},
+		{"enum O { S(v), N }\nO.N(1)", "2:4: enum variant O.N takes no payload"},
+		{"enum O { S(v), N }\nO.S()", "2:4: enum variant O.S takes 1 payload, got 0"},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+		err := compiler.Compile(parse(tt.input))
+		if err == nil {
+			t.Fatalf("expected compiler error for %q", tt.input)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestStructMethods(t *testing.T) {
+	comp := New()
+	if err := comp.Compile(parse("struct P { x; fn get(k) { self.x + k } }")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bytecode := comp.ByteCode()
+
+	if len(bytecode.Instructions) != 0 {
+		t.Fatalf("struct declaration should not emit instructions. got=%q", bytecode.Instructions)
+	}
+	index, ok := bytecode.StructMethods["P"]["get"]
+	if !ok {
+		t.Fatalf("method P.get not recorded. got=%v", bytecode.StructMethods)
+	}
+	fn, ok := bytecode.Constants[index].(*object.CompiledFunction)
+	if !ok {
+		t.Fatalf("method constant is not a function. got=%T", bytecode.Constants[index])
+	}
+	if fn.NumParams != 2 || fn.NumLocals != 2 {
+		t.Errorf("method should take self plus its parameters. got params=%d locals=%d", fn.NumParams, fn.NumLocals)
+	}
+
+	err := testInstructions([]code.Instructions{
+		code.Make(code.OpGetLocal, 0),
+		code.Make(code.OpGetField, 0),
+		code.Make(code.OpGetLocal, 1),
+		code.Make(code.OpAdd),
+		code.Make(code.OpReturnValue),
+	}, fn.Instructions)
+	if err != nil {
+		t.Errorf("wrong method instructions: %s", err)
+	}
+}
+
+func TestStructMethodErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"struct P {\n  x;\n  fn x() { 1 }\n}", "3:6: struct P has both a field and a method named x"},
+		{"struct P { fn m() { 1 } fn m() { 2 } }", "1:28: duplicate method P.m"},
+		{"let f = fn(n) {\n  struct P { fn m() { n } }\n};", "2:17: method P.m cannot capture local variable n"},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+		err := compiler.Compile(parse(tt.input))
+		if err == nil {
+			t.Fatalf("expected compiler error for %q", tt.input)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestDestructureStatement(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "let [a, _, ...r] = [1, 2, 3];",
+			expectedConstants: []interface{}{1, 2, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpArray, 3),
+				code.Make(code.OpUnpackArray, 2, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpSetGlobal, 1),
+			},
+		},
+		{
+			input: "fn(h) { let {a, b} = h; a }",
+			expectedConstants: []interface{}{
+				"a",
+				"b",
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpSetLocal, 1),
+					code.Make(code.OpGetLocal, 1),
+					code.Make(code.OpUnpackKey, 0),
+					code.Make(code.OpSetLocal, 2),
+					code.Make(code.OpGetLocal, 1),
+					code.Make(code.OpUnpackKey, 1),
+					code.Make(code.OpSetLocal, 3),
+					code.Make(code.OpGetLocal, 2),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+
+	comp := New()
+	if err := comp.Compile(parse("struct P { x; y; }\nlet p = P { x: 1, y: 2 };\nlet P {y, x} = p;")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	if got := comp.ByteCode().Instructions.String(); !strings.Contains(got, "OpUnpackField") {
+		t.Errorf("struct pattern should use OpUnpackField. got=\n%s", got)
+	}
+}
+
+func TestDestructureStatementErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let Q {x} = 1;", "1:5: undefined struct type: Q"},
+		{"struct P { x; }\nlet P {x, z} = 1;", "2:11: struct P has no field z"},
+		{"let [a, b] = c;", "1:14: undefined variable: c"},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+		err := compiler.Compile(parse(tt.input))
+		if err == nil {
+			t.Fatalf("expected compiler error for %q", tt.input)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestDefaultAndRestParameters(t *testing.T) {
+	comp := New()
+	if err := comp.Compile(parse("fn(a, b = a + 1) { b }; fn(a, ...r) { r }")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	constants := comp.ByteCode().Constants
+
+	withDefault, ok := constants[1].(*object.CompiledFunction)
+	if !ok {
+		t.Fatalf("constant 1 is not a function. got=%T", constants[1])
+	}
+	if withDefault.NumParams != 2 || withDefault.NumDefaults != 1 || withDefault.Variadic {
+		t.Errorf("wrong metadata. got params=%d defaults=%d variadic=%t",
+			withDefault.NumParams, withDefault.NumDefaults, withDefault.Variadic)
+	}
+	err := testInstructions([]code.Instructions{
+		code.Make(code.OpJumpIfArg, 12, 1),
+		code.Make(code.OpGetLocal, 0),
+		code.Make(code.OpConstant, 0),
+		code.Make(code.OpAdd),
+		code.Make(code.OpSetLocal, 1),
+		code.Make(code.OpGetLocal, 1),
+		code.Make(code.OpReturnValue),
+	}, withDefault.Instructions)
+	if err != nil {
+		t.Errorf("wrong default prologue: %s", err)
+	}
+
+	variadic, ok := constants[2].(*object.CompiledFunction)
+	if !ok {
+		t.Fatalf("constant 2 is not a function. got=%T", constants[2])
+	}
+	if variadic.NumParams != 1 || variadic.NumLocals != 2 || !variadic.Variadic {
+		t.Errorf("wrong metadata. got params=%d locals=%d variadic=%t",
+			variadic.NumParams, variadic.NumLocals, variadic.Variadic)
+	}
+	err = testInstructions([]code.Instructions{
+		code.Make(code.OpGetLocal, 1),
+		code.Make(code.OpReturnValue),
+	}, variadic.Instructions)
+	if err != nil {
+		t.Errorf("wrong variadic instructions: %s", err)
+	}
+}
+
+func TestDefaultParameterErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(a = b, b = 1) { a }", "1:8: default value cannot refer to parameter b, which is bound after it"},
+		{"fn(a = a) { a }", "1:8: default value cannot refer to parameter a, which is bound after it"},
+		{"fn(a = len(r), ...r) { a }", "1:12: default value cannot refer to parameter r, which is bound after it"},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+		err := compiler.Compile(parse(tt.input))
+		if err == nil {
+			t.Fatalf("expected compiler error for %q", tt.input)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestClosureAssignment(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: "fn() { let n = 0; fn() { n = n + 1 } }",
+			expectedConstants: []interface{}{
+				0,
+				1,
+				[]code.Instructions{
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpSetFree, 0),
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpCaptureLocal, 0),
+					code.Make(code.OpClosure, 2, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 3, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "fn() { let a = 1; a = 2 }",
+			expectedConstants: []interface{}{
+				1,
+				2,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpAssignLocal, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn() { len = 1 }", "1:8: cannot assign to builtin len"},
+		{"let f = fn() { f = 1 };", "1:16: cannot assign to f inside the function of that name"},
+	}
+	for _, tt := range errorTests {
+		err := New().Compile(parse(tt.input))
+		if err == nil {
+			t.Fatalf("expected compiler error for %q", tt.input)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: "fn(f) { return f(1); }",
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpTailCall, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "fn(f) { let x = f(1); x }",
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpSetLocal, 1),
+					code.Make(code.OpGetLocal, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+
+	tailCallTests := []struct {
+		input     string
+		tailCalls int
+	}{
+		{"fn(f) { f(1) }", 1},
+		{"fn(f) { 1 + f(1) }", 0},
+		{"fn(f, x) { if (x) { f(1) } else { f(2) } }", 2},
+		{"fn(f, x) { if (x) { return f(1); } f(2) }", 2},
+		{"fn(f) { f(f(1)) }", 1},
+		{"fn(f) { try { return f(1); } catch (e) { 0 } }", 0},
+		{"fn(f) { try { 0 } catch (e) { return f(e); } }", 1},
+		{"fn(f) { try { 0 } finally { f(1) } f(2) }", 1},
+	}
+	for _, tt := range tailCallTests {
+		compiler := New()
+		if err := compiler.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error for %q: %s", tt.input, err)
+		}
+		var fn *object.CompiledFunction
+		for _, constant := range compiler.ByteCode().Constants {
+			if compiled, ok := constant.(*object.CompiledFunction); ok {
+				fn = compiled
+			}
+		}
+		if fn == nil {
+			t.Fatalf("no function constant for %q", tt.input)
+		}
+		got := strings.Count(fn.Instructions.String(), "OpTailCall")
+		if got != tt.tailCalls {
+			t.Errorf("wrong number of tail calls for %q. want=%d, got=%d\n%s", tt.input, tt.tailCalls, got, fn.Instructions)
+		}
+	}
+}
+
+func TestInvalidPatternWarnings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`re_match("a(", "a")`, []string{"1:10: invalid regular expression passed to re_match: error parsing regexp: missing closing ): `a(`"}},
+		{`fn() { re_split("[", "a") }`, []string{"1:17: invalid regular expression passed to re_split: error parsing regexp: missing closing ]: `[`"}},
+		{`re_match("a+", "a"); re_find_all("\\d", "1")`, nil},
+		// Only literal patterns passed to the builtins themselves are checked.
+		{`let p = "a("; re_match(p, "a")`, nil},
+		{`let re_match = fn(p, s) { p }; re_match("a(", "a")`, nil},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+		if err := compiler.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error for %q: %s", tt.input, err)
+		}
+		warnings := compiler.Warnings()
+		if len(warnings) != len(tt.expected) {
+			t.Fatalf("wrong warnings for %q. want=%q, got=%q", tt.input, tt.expected, warnings)
+		}
+		for i, warning := range warnings {
+			if warning != tt.expected[i] {
+				t.Errorf("wrong warning for %q. want=%q, got=%q", tt.input, tt.expected[i], warning)
+			}
+		}
+	}
+}
+
+func TestEnumTypeArguments(t *testing.T) {
+	index := builtin.GetBuiltinIndex("enum_variants")
+	runCompilerTests(t, []compilerTestCase{
+		{
+			input:             "enum Shape { A, B } enum_variants(Shape)",
+			expectedConstants: []interface{}{"Shape"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpGetBuiltin, index),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpCall, 1),
+				code.Make(code.OpPop),
+			},
+		},
+	})
+
+	// Once enum_variants is shadowed, Shape is an ordinary identifier again.
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"enum Shape { A } let enum_variants = fn(x) { x }; enum_variants(Shape)", "1:65: undefined variable: Shape"},
+		{"enum Shape { A } fn(enum_variants) { enum_variants(Shape) }", "1:52: undefined variable: Shape"},
+	}
+	for _, tt := range tests {
+		err := New().Compile(parse(tt.input))
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong error for %q. want=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package compiler
+
+import (
+	"mutant/ast"
+	"mutant/code"
+	"mutant/object"
+)
+
+// compileDestructureStatement binds each name of the pattern in the current
+// scope. Array patterns unpack every element in one OpUnpackArray; hash and
+// struct patterns keep the value in a hidden slot and read one key at a time,
+// so a missing key or field fails at the binding that needs it.
+func (c *Compiler) compileDestructureStatement(node *ast.DestructureStatement) error {
+	if node.Kind == ast.StructPattern {
+		fields, ok := c.structDefinitions[node.Struct.Value]
+		if !ok {
+			return errorAt(node.Struct, "undefined struct type: %s", node.Struct.Value)
+		}
+		for _, name := range node.Names {
+			if !hasField(fields, name.Value) {
+				return errorAt(name, "struct %s has no field %s", node.Struct.Value, name.Value)
+			}
+		}
+	}
+
+	if err := c.Compile(node.Value); err != nil {
+		return err
+	}
+
+	if node.Kind == ast.ArrayPattern {
+		hasRest := 0
+		if node.Rest != nil {
+			hasRest = 1
+		}
+		c.emit(code.OpUnpackArray, len(node.Names), hasRest)
+
+		// The rest array is on top, preceded by the elements in order
+		if node.Rest != nil {
+			c.storeSymbol(c.symbolTable.Define(node.Rest.Value))
+		}
+		for i := len(node.Names) - 1; i >= 0; i-- {
+			if node.Names[i].Value == "_" {
+				c.emit(code.OpPop)
+				continue
+			}
+			c.storeSymbol(c.symbolTable.Define(node.Names[i].Value))
+		}
+		return nil
+	}
+
+	subject := c.symbolTable.defineHidden("$destructure")
+	c.storeSymbol(subject)
+
+	typeIndex := -1
+	if node.Kind == ast.StructPattern {
+		typeIndex = c.addConstant(&object.String{Value: node.Struct.Value})
+	}
+	for _, name := range node.Names {
+		c.loadSymbol(subject)
+		nameIndex := c.addConstant(&object.String{Value: name.Value})
+		if typeIndex >= 0 {
+			c.emit(code.OpUnpackField, nameIndex, typeIndex)
+		} else {
+			c.emit(code.OpUnpackKey, nameIndex)
+		}
+		c.storeSymbol(c.symbolTable.Define(name.Value))
+	}
+
+	return nil
+}
+
+func hasField(fields []*ast.Identifier, name string) bool {
+	for _, field := range fields {
+		if field.Value == name {
+			return true
+		}
+	}
+	return false
+}
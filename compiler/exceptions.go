@@ -0,0 +1,152 @@
+package compiler
+
+import (
+	"fmt"
+	"mutant/ast"
+	"mutant/code"
+	"mutant/object"
+)
+
+// tryContext is a try statement being compiled. return, break and continue
+// leaving it must deactivate its handler and run its finally block first.
+type tryContext struct {
+	scopeIndex    int
+	loopDepth     int  // len(loopContexts) when the try started
+	handlerActive bool // false while compiling the catch block
+	finally       *ast.BlockStatement
+}
+
+// compileTryStatement lays out
+//
+//	OpTry h0; <try>; OpEndTry; <finally>; OpJump end
+//	h0: <bind e>; OpTry h1; <catch>; OpEndTry; <finally>; OpJump end
+//	h1: OpSet $exc; <finally>; OpGet $exc; OpThrow
+//	end:
+//
+// where h1 exists only with a finally block and h0 targets it directly when
+// there is no catch block.
+func (c *Compiler) compileTryStatement(node *ast.TryStatement) error {
+	depth := len(c.tryContexts)
+	ctx := tryContext{
+		scopeIndex:    c.scopeIndex,
+		loopDepth:     len(c.loopContexts),
+		handlerActive: true,
+		finally:       node.Finally,
+	}
+
+	handler := c.emitTry()
+	if err := c.compileTryBlock(node.Block, ctx); err != nil {
+		return err
+	}
+	c.emitEndTry(handler)
+	if err := c.compileFinally(node.Finally); err != nil {
+		return err
+	}
+	exitJumps := []int{c.emit(code.OpJump, 9999)}
+
+	if node.Catch != nil {
+		c.setHandlerTarget(handler)
+		if node.CatchParam != nil {
+			c.storeSymbol(c.symbolTable.Define(node.CatchParam.Value))
+		} else {
+			c.emit(code.OpPop)
+		}
+
+		handler = -1
+		if node.Finally != nil {
+			handler = c.emitTry()
+		}
+		ctx.handlerActive = handler >= 0
+		if err := c.compileTryBlock(node.Catch, ctx); err != nil {
+			return err
+		}
+		if handler >= 0 {
+			c.emitEndTry(handler)
+			if err := c.compileFinally(node.Finally); err != nil {
+				return err
+			}
+		}
+		exitJumps = append(exitJumps, c.emit(code.OpJump, 9999))
+	}
+
+	if node.Finally != nil {
+		c.setHandlerTarget(handler)
+		exception := c.symbolTable.defineHidden(fmt.Sprintf("$exc%d", depth))
+		c.storeSymbol(exception)
+		if err := c.compileFinally(node.Finally); err != nil {
+			return err
+		}
+		c.loadSymbol(exception)
+		c.emit(code.OpThrow)
+	}
+
+	end := len(c.currentInstructions())
+	for _, pos := range exitJumps {
+		c.changeOperand(pos, end)
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileTryBlock(block *ast.BlockStatement, ctx tryContext) error {
+	c.tryContexts = append(c.tryContexts, ctx)
+	defer func() { c.tryContexts = c.tryContexts[:len(c.tryContexts)-1] }()
+	return c.Compile(block)
+}
+
+func (c *Compiler) compileFinally(block *ast.BlockStatement) error {
+	if block == nil {
+		return nil
+	}
+	return c.Compile(block)
+}
+
+// unwindTryContexts emits the exits of the enclosing try statements in the
+// current function that leaving selects, innermost first: OpEndTry for an
+// active handler followed by an inline copy of the finally block.
+func (c *Compiler) unwindTryContexts(leaving func(tryContext) bool) error {
+	saved := c.tryContexts
+	defer func() { c.tryContexts = saved }()
+
+	for i := len(saved) - 1; i >= 0; i-- {
+		ctx := saved[i]
+		if ctx.scopeIndex != c.scopeIndex || !leaving(ctx) {
+			break
+		}
+		// A finally block runs outside its own try statement.
+		c.tryContexts = saved[:i]
+		if ctx.handlerActive {
+			c.emit(code.OpEndTry)
+		}
+		if err := c.compileFinally(ctx.finally); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insideInnermostLoop reports whether ctx started inside the loop that a
+// break or continue would leave
+func (c *Compiler) insideInnermostLoop(ctx tryContext) bool {
+	return ctx.loopDepth >= len(c.loopContexts)
+}
+
+// emitTry adds a handler table entry for a new OpTry and returns its index
+func (c *Compiler) emitTry() int {
+	scope := &c.scopes[c.scopeIndex]
+	index := len(scope.handlers)
+	scope.handlers = append(scope.handlers, object.ExceptionHandler{Start: len(scope.instructions)})
+	c.emit(code.OpTry, index)
+	return index
+}
+
+func (c *Compiler) emitEndTry(handler int) {
+	c.scopes[c.scopeIndex].handlers[handler].End = len(c.currentInstructions())
+	c.emit(code.OpEndTry)
+}
+
+// setHandlerTarget points handler at the next instruction to be emitted
+func (c *Compiler) setHandlerTarget(handler int) {
+	c.scopes[c.scopeIndex].handlers[handler].Target = len(c.currentInstructions())
+}
@@ -0,0 +1,104 @@
+package compiler
+
+import (
+	"fmt"
+	"mutant/ast"
+	"mutant/code"
+	"mutant/object"
+)
+
+// enumVariantOf reports whether exp names an enum variant, as in `Result.Ok`
+func (c *Compiler) enumVariantOf(exp ast.Expression) (string, string, bool) {
+	field, ok := exp.(*ast.FieldExpression)
+	if !ok {
+		return "", "", false
+	}
+	ident, ok := field.Left.(*ast.Identifier)
+	if !ok {
+		return "", "", false
+	}
+	if _, exists := c.enumDefinitions[ident.Value]; !exists {
+		return "", "", false
+	}
+	return ident.Value, field.Field.Value, true
+}
+
+// compileEnumConstructor compiles `Enum.Tag(payload)`
+func (c *Compiler) compileEnumConstructor(node *ast.CallExpression, enum, tag string) error {
+	if !c.enumPayloads[enum][tag] {
+		return errorAt(node, "enum variant %s.%s takes no payload", enum, tag)
+	}
+	if len(node.Arguments) != 1 {
+		return errorAt(node, "enum variant %s.%s takes 1 payload, got %d", enum, tag, len(node.Arguments))
+	}
+
+	if err := c.Compile(node.Arguments[0]); err != nil {
+		return err
+	}
+	c.emit(code.OpMakeEnum, c.addConstant(&object.String{Value: enum}), c.addConstant(&object.String{Value: tag}))
+	return nil
+}
+
+func (c *Compiler) enumVariants(enum string) (ast.EnumVariants, bool) {
+	tags, ok := c.enumDefinitions[enum]
+	return ast.EnumVariants{Tags: tags, Payloads: c.enumPayloads[enum]}, ok
+}
+
+// compileMatchExpression stores the subject in a hidden slot and tests the
+// arms in order. Tag patterns use OpMatchTag, literal patterns compare with
+// OpEqual, and each body leaves its value on the stack like an if branch.
+func (c *Compiler) compileMatchExpression(node *ast.MatchExpression) error {
+	if at, msg := node.Check(c.enumVariants); at != nil {
+		return errorAt(at, "%s", msg)
+	}
+
+	if err := c.Compile(node.Subject); err != nil {
+		return err
+	}
+	subject := c.symbolTable.defineHidden(fmt.Sprintf("$match%d", c.matchDepth))
+	c.storeSymbol(subject)
+
+	c.matchDepth++
+	defer func() { c.matchDepth-- }()
+
+	endJumps := []int{}
+	for _, arm := range node.Arms {
+		nextArm := -1
+
+		switch {
+		case arm.Tag != nil:
+			c.loadSymbol(subject)
+			c.emit(code.OpMatchTag, c.addConstant(&object.String{Value: arm.Enum.Value}), c.addConstant(&object.String{Value: arm.Tag.Value}))
+			nextArm = c.emit(code.OpJumpFalse, 9999)
+			if arm.Binding != nil {
+				c.loadSymbol(subject)
+				c.emit(code.OpEnumPayload)
+				c.storeSymbol(c.symbolTable.Define(arm.Binding.Value))
+			}
+		case arm.Literal != nil:
+			c.loadSymbol(subject)
+			if err := c.Compile(arm.Literal); err != nil {
+				return err
+			}
+			c.emit(code.OpEqual)
+			nextArm = c.emit(code.OpJumpFalse, 9999)
+		}
+
+		if err := c.Compile(arm.Body); err != nil {
+			return err
+		}
+		c.keepBlockValue()
+		endJumps = append(endJumps, c.emit(code.OpJump, 9999))
+
+		if nextArm >= 0 {
+			c.changeOperand(nextArm, len(c.currentInstructions()))
+		}
+	}
+
+	end := len(c.currentInstructions())
+	for _, pos := range endJumps {
+		c.changeOperand(pos, end)
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package compiler
+
+import (
+	"mutant/ast"
+	"mutant/lexer"
+	"mutant/parser"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SetSourceFile records the path of the entry file. Imports are resolved
+// relative to the importing file, so without it they resolve against the
+// working directory.
+func (c *Compiler) SetSourceFile(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	c.sourceFile = abs
+	c.importStack = []string{abs}
+	return nil
+}
+
+// compileImportStatement compiles an imported module inline, once per file,
+// into the same instruction stream and binds its globals to a namespace
+func (c *Compiler) compileImportStatement(node *ast.ImportStatement) error {
+	if c.scopeIndex != 0 || c.symbolTable.Outer != nil {
+		return errorAt(node, "import is only allowed at the top level")
+	}
+
+	path, err := c.resolveImportPath(node.Path)
+	if err != nil {
+		return errorAt(node, "cannot import %q: %v", node.Path, err)
+	}
+
+	for i, importing := range c.importStack {
+		if importing == path {
+			cycle := append(append([]string{}, c.importStack[i:]...), path)
+			for j := range cycle {
+				cycle[j] = c.displayPath(cycle[j])
+			}
+			return errorAt(node, "import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	exports, ok := c.modules[path]
+	if !ok {
+		if exports, err = c.compileModule(node, path); err != nil {
+			return err
+		}
+		if c.modules == nil {
+			c.modules = make(map[string]map[string]Symbol)
+		}
+		c.modules[path] = exports
+	}
+
+	c.symbolTable.DefineNamespace(node.Name.Value, exports)
+	return nil
+}
+
+func (c *Compiler) compileModule(node *ast.ImportStatement, path string) (map[string]Symbol, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, errorAt(node, "cannot import %q: file not found", node.Path)
+	}
+	if err != nil {
+		return nil, errorAt(node, "cannot import %q: %v", node.Path, err)
+	}
+
+	display := c.displayPath(path)
+	p := parser.New(lexer.NewWithFile(string(data), display))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		return nil, errorAt(node, "cannot import %q:\n\t%s", node.Path, strings.Join(errs, "\n\t"))
+	}
+
+	importerTable, importerFile := c.symbolTable, c.sourceFile
+	moduleTable := NewModuleSymbolTable(importerTable, display+"::")
+	c.symbolTable, c.sourceFile = moduleTable, path
+	c.importStack = append(c.importStack, path)
+	defer func() {
+		c.symbolTable, c.sourceFile = importerTable, importerFile
+		c.importStack = c.importStack[:len(c.importStack)-1]
+	}()
+
+	if err := c.Compile(program); err != nil {
+		return nil, err
+	}
+	return moduleTable.Exports(), nil
+}
+
+// declareType records that the file being compiled declares the struct or
+// enum name. Types are not namespaced by module, so a name another file
+// already declared is an error rather than a silent replacement.
+func (c *Compiler) declareType(node ast.Node, name string) error {
+	if origin, ok := c.typeOrigins[name]; ok && origin != c.sourceFile {
+		where := "the main program"
+		if origin != "" {
+			where = c.displayPath(origin)
+		}
+		return errorAt(node, "type %s is already declared in %s; struct and enum names must be unique across modules", name, where)
+	}
+	if c.typeOrigins == nil {
+		c.typeOrigins = make(map[string]string)
+	}
+	c.typeOrigins[name] = c.sourceFile
+	return nil
+}
+
+// resolveImportPath makes an import path absolute relative to the importing file
+func (c *Compiler) resolveImportPath(importPath string) (string, error) {
+	path := filepath.FromSlash(importPath)
+	if !filepath.IsAbs(path) && c.sourceFile != "" {
+		path = filepath.Join(filepath.Dir(c.sourceFile), path)
+	}
+	return filepath.Abs(path)
+}
+
+// displayPath shortens a module path relative to the entry file for
+// diagnostics and global name mangling
+func (c *Compiler) displayPath(path string) string {
+	if len(c.importStack) > 0 {
+		if rel, err := filepath.Rel(filepath.Dir(c.importStack[0]), path); err == nil {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(path)
+}
@@ -0,0 +1,138 @@
+package compiler
+
+import (
+	"mutant/lexer"
+	"mutant/parser"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeModuleFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func compileEntry(t *testing.T, dir, entry string) (*Compiler, error) {
+	t.Helper()
+	path := filepath.Join(dir, entry)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := parser.New(lexer.NewWithFile(string(data), entry))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	c := New()
+	if err := c.SetSourceFile(path); err != nil {
+		t.Fatal(err)
+	}
+	return c, c.Compile(program)
+}
+
+func TestImportBindsNamespacesAndCompilesModulesOnce(t *testing.T) {
+	dir := writeModuleFiles(t, map[string]string{
+		"main.mut":        "import \"lib/util.mut\"\nimport \"lib/util.mut\" as again\nlet add = 1;\nutil.add(add, again.twice(2));",
+		"lib/util.mut":    "import helpers\nlet add = fn(a, b) { helpers.sum(a, b) };\nlet twice = fn(x) { add(x, x) };",
+		"lib/helpers.mut": "let sum = fn(a, b) { putln(a); a + b };",
+	})
+
+	c, err := compileEntry(t, dir, "main.mut")
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	if len(c.modules) != 2 {
+		t.Fatalf("expected 2 compiled modules, got=%d", len(c.modules))
+	}
+
+	util, ok := c.symbolTable.ResolveNamespace("util")
+	if !ok {
+		t.Fatalf("namespace util not defined")
+	}
+	again, _ := c.symbolTable.ResolveNamespace("again")
+	if util["add"] != again["add"] || util["add"].Scope != GlobalScope {
+		t.Fatalf("both imports should share the module globals. got=%+v and %+v", util["add"], again["add"])
+	}
+
+	entryAdd, _ := c.symbolTable.Resolve("add")
+	if entryAdd.Index == util["add"].Index {
+		t.Fatalf("module global add must not alias the entry's add")
+	}
+	if util["add"].Name != "lib/util.mut::add" {
+		t.Fatalf("module globals should be mangled with the module path. got=%q", util["add"].Name)
+	}
+
+	builtins := c.symbolTable.ReferencedBuiltins()
+	if len(builtins) != 1 || builtins[0].Name != "putln" {
+		t.Fatalf("builtins referenced by modules should be recorded on the root table. got=%+v", builtins)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		files    map[string]string
+		expected string
+	}{
+		{
+			map[string]string{"main.mut": "import a", "a.mut": "import b", "b.mut": "import a"},
+			"b.mut:1:1: import cycle: a.mut -> b.mut -> a.mut",
+		},
+		{
+			map[string]string{"main.mut": "import main"},
+			"main.mut:1:1: import cycle: main.mut -> main.mut",
+		},
+		{
+			map[string]string{"main.mut": "\nimport missing"},
+			"main.mut:2:1: cannot import \"missing.mut\": file not found",
+		},
+		{
+			map[string]string{"main.mut": "import a\na.nope;", "a.mut": "let x = 1;"},
+			"main.mut:2:3: module a has no member nope",
+		},
+		{
+			map[string]string{"main.mut": "let secret = 1;\nimport a", "a.mut": "secret;"},
+			"a.mut:1:1: undefined variable: secret",
+		},
+		{
+			map[string]string{"main.mut": "let f = fn() { import a };", "a.mut": ""},
+			"main.mut:1:16: import is only allowed at the top level",
+		},
+		{
+			map[string]string{"main.mut": "import m\nstruct P { y }\nm.mk().x;", "m.mut": "struct P { x }\nlet mk = fn() { P { x: 1 } };"},
+			"main.mut:2:8: type P is already declared in m.mut; struct and enum names must be unique across modules",
+		},
+		{
+			map[string]string{"main.mut": "enum E { A }\nimport m", "m.mut": "struct E { x }"},
+			"m.mut:1:8: type E is already declared in main.mut; struct and enum names must be unique across modules",
+		},
+		{
+			map[string]string{"main.mut": "import a", "a.mut": "let = 1;"},
+			"main.mut:1:1: cannot import \"a.mut\":\n\ta.mut:1:5: expected next token to be IDENT, but got = instead\n\ta.mut:1:5: no prefix parse function for = found",
+		},
+	}
+
+	for _, tt := range tests {
+		dir := writeModuleFiles(t, tt.files)
+		_, err := compileEntry(t, dir, "main.mut")
+		if err == nil {
+			t.Fatalf("expected compiler error, want=%q", tt.expected)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
@@ -20,7 +20,13 @@ func TestGenerateOpcodeMapping(t *testing.T) {
 		code.OpReturn, code.OpGetBuiltin, code.OpClosure, code.OpGetFree,
 		code.OpCurrentClosure, code.OpChkDbg, code.OpChkSnd, code.OpBreak,
 		code.OpContinue, code.OpMakeStruct, code.OpGetField, code.OpSetField,
-		code.OpEnumValue,
+		code.OpEnumValue, code.OpJumpFalseOrPop, code.OpJumpTrueOrPop,
+		code.OpGreaterEqual, code.OpPow, code.OpBitAnd, code.OpBitOr,
+		code.OpBitXor, code.OpShl, code.OpShr, code.OpIterInit, code.OpIterNext,
+		code.OpTry, code.OpEndTry, code.OpThrow, code.OpMakeEnum, code.OpMatchTag,
+		code.OpEnumPayload, code.OpUnpackArray, code.OpUnpackKey, code.OpUnpackField,
+		code.OpJumpIfArg, code.OpSetFree, code.OpAssignLocal, code.OpCaptureLocal,
+		code.OpCaptureFree, code.OpTailCall,
 	}
 
 	// Check all opcodes are mapped
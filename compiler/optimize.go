@@ -0,0 +1,423 @@
+package compiler
+
+import (
+	"encoding/binary"
+	"math"
+	"mutant/ast"
+	"mutant/code"
+	"mutant/object"
+)
+
+// Optimization levels accepted by EnableOptimization
+const (
+	OptimizeNone = 0
+	// OptimizeBasic folds constant expressions, removes statements that can
+	// never run and compiles only the taken branch of an if with a literal
+	// condition.
+	OptimizeBasic = 1
+	// OptimizeFull additionally threads jumps that land on another jump.
+	OptimizeFull = 2
+)
+
+// maxThreadedJumps bounds how far threadJumps follows a chain, so a cycle of
+// jumps cannot hang the compiler
+const maxThreadedJumps = 16
+
+// EnableOptimization turns on the optimization passes up to level. The passes
+// rewrite the program before any bytecode is emitted and thread jumps as each
+// statement or function body is finished, so security opcode injection and
+// polymorphic mutation always see the optimized code.
+func (c *Compiler) EnableOptimization(level int) {
+	c.optimizationLevel = max(OptimizeNone, min(level, OptimizeFull))
+}
+
+// optimizeProgram runs the AST passes over a whole program in place
+func optimizeProgram(program *ast.Program) {
+	for i, stmt := range program.Statements {
+		program.Statements[i] = optimizeStatement(stmt)
+	}
+}
+
+func optimizeBlock(block *ast.BlockStatement) {
+	if block == nil {
+		return
+	}
+	for i, stmt := range block.Statements {
+		block.Statements[i] = optimizeStatement(stmt)
+		if endsControlFlow(stmt) {
+			// Statements after stmt never run, but the names they declare
+			// are still in scope for the code that follows the block.
+			kept := block.Statements[:i+1]
+			for _, dead := range block.Statements[i+1:] {
+				if declaresName(dead) {
+					kept = append(kept, dead)
+				}
+			}
+			block.Statements = kept
+			return
+		}
+	}
+}
+
+// declaresName reports whether stmt binds a name in the enclosing function or
+// program. Blocks do not open a scope, so a let nested in an if, loop or try
+// counts; function literals have their own scope and are not searched.
+func declaresName(stmt ast.Statement) bool {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement, *ast.DestructureStatement, *ast.ForInStatement,
+		*ast.StructStatement, *ast.EnumStatement, *ast.ImportStatement:
+		return true
+	case *ast.BlockStatement:
+		return blockDeclaresName(stmt)
+	case *ast.ForStatement:
+		return (stmt.Init != nil && declaresName(stmt.Init)) || blockDeclaresName(stmt.Body)
+	case *ast.TryStatement:
+		return stmt.CatchParam != nil || blockDeclaresName(stmt.Block) ||
+			blockDeclaresName(stmt.Catch) || blockDeclaresName(stmt.Finally)
+	case *ast.ExpressionStatement:
+		switch expr := stmt.Expression.(type) {
+		case *ast.IfExpression:
+			return blockDeclaresName(expr.Consequence) || blockDeclaresName(expr.Alternative)
+		case *ast.MatchExpression:
+			return true
+		}
+	}
+	return false
+}
+
+func blockDeclaresName(block *ast.BlockStatement) bool {
+	if block == nil {
+		return false
+	}
+	for _, stmt := range block.Statements {
+		if declaresName(stmt) {
+			return true
+		}
+	}
+	return false
+}
+
+// endsControlFlow reports whether no statement after stmt in the same block
+// can run
+func endsControlFlow(stmt ast.Statement) bool {
+	switch stmt.(type) {
+	case *ast.ReturnStatement, *ast.BreakStatement, *ast.ContinueStatement, *ast.ThrowStatement:
+		return true
+	}
+	return false
+}
+
+func optimizeStatement(stmt ast.Statement) ast.Statement {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		stmt.Expression = foldConstants(stmt.Expression)
+	case *ast.LetStatement:
+		stmt.Value = foldConstants(stmt.Value)
+	case *ast.DestructureStatement:
+		stmt.Value = foldConstants(stmt.Value)
+	case *ast.ReturnStatement:
+		stmt.ReturnValue = foldConstants(stmt.ReturnValue)
+	case *ast.ThrowStatement:
+		stmt.Value = foldConstants(stmt.Value)
+	case *ast.BlockStatement:
+		optimizeBlock(stmt)
+	case *ast.ForStatement:
+		if stmt.Init != nil {
+			stmt.Init = optimizeStatement(stmt.Init)
+		}
+		stmt.Condition = foldConstants(stmt.Condition)
+		stmt.Post = foldConstants(stmt.Post)
+		optimizeBlock(stmt.Body)
+	case *ast.ForInStatement:
+		stmt.Iterable = foldConstants(stmt.Iterable)
+		optimizeBlock(stmt.Body)
+	case *ast.TryStatement:
+		optimizeBlock(stmt.Block)
+		optimizeBlock(stmt.Catch)
+		optimizeBlock(stmt.Finally)
+	case *ast.StructStatement:
+		for _, method := range stmt.Methods {
+			foldConstants(method.Function)
+		}
+	}
+	return stmt
+}
+
+// foldConstants replaces operators applied to literals with the literal the
+// VM would compute, working bottom-up through expr. Operations that fail at
+// run time, such as division by zero, are left for the VM to report.
+func foldConstants(expr ast.Expression) ast.Expression {
+	switch expr := expr.(type) {
+	case *ast.PrefixExpression:
+		expr.Right = foldConstants(expr.Right)
+		if folded := foldPrefix(expr); folded != nil {
+			return folded
+		}
+	case *ast.InfixExpression:
+		expr.Left = foldConstants(expr.Left)
+		expr.Right = foldConstants(expr.Right)
+		if folded := foldInfix(expr); folded != nil {
+			return folded
+		}
+	case *ast.IfExpression:
+		expr.Condition = foldConstants(expr.Condition)
+		optimizeBlock(expr.Consequence)
+		optimizeBlock(expr.Alternative)
+	case *ast.MatchExpression:
+		expr.Subject = foldConstants(expr.Subject)
+		for _, arm := range expr.Arms {
+			optimizeBlock(arm.Body)
+		}
+	case *ast.FunctionLiteral:
+		for i, def := range expr.Defaults {
+			expr.Defaults[i] = foldConstants(def)
+		}
+		optimizeBlock(expr.Body)
+	case *ast.CallExpression:
+		expr.Function = foldConstants(expr.Function)
+		for i, arg := range expr.Arguments {
+			expr.Arguments[i] = foldConstants(arg)
+		}
+	case *ast.IndexExpression:
+		expr.Left = foldConstants(expr.Left)
+		expr.Index = foldConstants(expr.Index)
+	case *ast.ArrayLiteral:
+		for i, element := range expr.Elements {
+			expr.Elements[i] = foldConstants(element)
+		}
+	case *ast.HashLiteral:
+		pairs := make(map[ast.Expression]ast.Expression, len(expr.Pairs))
+		keys := make([]ast.Expression, 0, len(expr.Pairs))
+		for _, key := range expr.OrderedKeys() {
+			folded := foldConstants(key)
+			pairs[folded] = foldConstants(expr.Pairs[key])
+			keys = append(keys, folded)
+		}
+		expr.Pairs, expr.Keys = pairs, keys
+	case *ast.StructLiteral:
+		for _, field := range expr.Fields {
+			field.Value = foldConstants(field.Value)
+		}
+	case *ast.AssignExpression:
+		expr.Value = foldConstants(expr.Value)
+	case *ast.FieldExpression:
+		expr.Left = foldConstants(expr.Left)
+	case *ast.StringifyExpression:
+		expr.Value = foldConstants(expr.Value)
+	case *ast.RangeExpression:
+		expr.Start = foldConstants(expr.Start)
+		expr.End = foldConstants(expr.End)
+	}
+	return expr
+}
+
+func foldPrefix(expr *ast.PrefixExpression) ast.Expression {
+	switch right := expr.Right.(type) {
+	case *ast.IntegerLiteral:
+		if expr.Operator == "-" {
+			return &ast.IntegerLiteral{Token: expr.Token, Value: -right.Value}
+		}
+	case *ast.FloatLiteral:
+		if expr.Operator == "-" {
+			return &ast.FloatLiteral{Token: expr.Token, Value: -right.Value}
+		}
+	case *ast.Boolean:
+		if expr.Operator == "!" {
+			return &ast.Boolean{Token: expr.Token, Value: !right.Value}
+		}
+	}
+	return nil
+}
+
+func foldInfix(expr *ast.InfixExpression) ast.Expression {
+	switch left := expr.Left.(type) {
+	case *ast.IntegerLiteral:
+		switch right := expr.Right.(type) {
+		case *ast.IntegerLiteral:
+			return foldIntegerInfix(expr, left.Value, right.Value)
+		case *ast.FloatLiteral:
+			return foldFloatInfix(expr, float64(left.Value), right.Value)
+		}
+	case *ast.FloatLiteral:
+		switch right := expr.Right.(type) {
+		case *ast.IntegerLiteral:
+			return foldFloatInfix(expr, left.Value, float64(right.Value))
+		case *ast.FloatLiteral:
+			return foldFloatInfix(expr, left.Value, right.Value)
+		}
+	case *ast.StringLiteral:
+		if right, ok := expr.Right.(*ast.StringLiteral); ok && expr.Operator == "+" {
+			return &ast.StringLiteral{Token: expr.Token, Value: left.Value + right.Value}
+		}
+	case *ast.Boolean:
+		if right, ok := expr.Right.(*ast.Boolean); ok {
+			switch expr.Operator {
+			case "==":
+				return &ast.Boolean{Token: expr.Token, Value: left.Value == right.Value}
+			case "!=":
+				return &ast.Boolean{Token: expr.Token, Value: left.Value != right.Value}
+			}
+		}
+	}
+	return nil
+}
+
+func foldIntegerInfix(expr *ast.InfixExpression, left, right int64) ast.Expression {
+	integer := func(value int64) ast.Expression {
+		return &ast.IntegerLiteral{Token: expr.Token, Value: value}
+	}
+	boolean := func(value bool) ast.Expression {
+		return &ast.Boolean{Token: expr.Token, Value: value}
+	}
+
+	switch expr.Operator {
+	case "+":
+		return integer(left + right)
+	case "-":
+		return integer(left - right)
+	case "*":
+		return integer(left * right)
+	case "/":
+		if right != 0 {
+			return integer(left / right)
+		}
+	case "%":
+		if right != 0 {
+			return integer(left % right)
+		}
+	case "**":
+		if result, ok := object.IntPow(left, right); ok && right >= 0 {
+			return integer(result)
+		}
+	case "&":
+		return integer(left & right)
+	case "|":
+		return integer(left | right)
+	case "^":
+		return integer(left ^ right)
+	case "<<":
+		if right >= 0 {
+			return integer(left << uint64(right))
+		}
+	case ">>":
+		if right >= 0 {
+			return integer(left >> uint64(right))
+		}
+	case "==":
+		return boolean(left == right)
+	case "!=":
+		return boolean(left != right)
+	case "<":
+		return boolean(left < right)
+	case "<=":
+		return boolean(left <= right)
+	case ">":
+		return boolean(left > right)
+	case ">=":
+		return boolean(left >= right)
+	}
+	return nil
+}
+
+func foldFloatInfix(expr *ast.InfixExpression, left, right float64) ast.Expression {
+	float := func(value float64) ast.Expression {
+		return &ast.FloatLiteral{Token: expr.Token, Value: value}
+	}
+	boolean := func(value bool) ast.Expression {
+		return &ast.Boolean{Token: expr.Token, Value: value}
+	}
+
+	switch expr.Operator {
+	case "+":
+		return float(left + right)
+	case "-":
+		return float(left - right)
+	case "*":
+		return float(left * right)
+	case "/":
+		return float(left / right)
+	case "%":
+		return float(math.Mod(left, right))
+	case "**":
+		return float(math.Pow(left, right))
+	case "==":
+		return boolean(left == right)
+	case "!=":
+		return boolean(left != right)
+	case "<":
+		return boolean(left < right)
+	case "<=":
+		return boolean(left <= right)
+	case ">":
+		return boolean(left > right)
+	case ">=":
+		return boolean(left >= right)
+	}
+	return nil
+}
+
+// compileConstantIf compiles only the branch an if with a literal condition
+// takes. The other branch is not compiled at all.
+func (c *Compiler) compileConstantIf(node *ast.IfExpression, condition bool) error {
+	branch := node.Consequence
+	if !condition {
+		branch = node.Alternative
+	}
+	if branch == nil {
+		c.emit(code.OpNull)
+		return nil
+	}
+
+	start := len(c.currentInstructions())
+	if err := c.Compile(branch); err != nil {
+		return err
+	}
+	if c.lastInstructionIs(code.OpPop) && c.scopes[c.scopeIndex].lastInstruction.Position >= start {
+		c.removeLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+	return nil
+}
+
+// threadJumps retargets every jump emitted since start whose target is an
+// unconditional OpJump to the end of that chain of jumps. Instruction
+// positions do not change, so handler tables and other jumps stay valid.
+func (c *Compiler) threadJumps(start int) {
+	ins := c.currentInstructions()
+	for pos := start; pos < len(ins); {
+		op := code.Opcode(ins[pos])
+		def, err := code.Lookup(ins[pos])
+		if err != nil {
+			return
+		}
+		width := 1
+		for _, w := range def.OperandWidths {
+			width += w
+		}
+
+		switch op {
+		case code.OpJump, code.OpJumpFalse, code.OpJumpFalseOrPop, code.OpJumpTrueOrPop,
+			code.OpJumpIfArg, code.OpIterNext:
+			target := int(binary.BigEndian.Uint16(ins[pos+1:]))
+			if final := finalJumpTarget(ins, target); final != target {
+				binary.BigEndian.PutUint16(ins[pos+1:], uint16(final))
+			}
+		}
+		pos += width
+	}
+}
+
+func finalJumpTarget(ins code.Instructions, target int) int {
+	for hops := 0; hops < maxThreadedJumps; hops++ {
+		if target >= len(ins) || code.Opcode(ins[target]) != code.OpJump {
+			return target
+		}
+		next := int(binary.BigEndian.Uint16(ins[target+1:]))
+		if next == target {
+			return target
+		}
+		target = next
+	}
+	return target
+}
@@ -0,0 +1,265 @@
+package compiler
+
+import (
+	"mutant/code"
+	"testing"
+)
+
+func runOptimizedCompilerTests(t *testing.T, level int, tests []compilerTestCase) {
+	t.Helper()
+	for _, tt := range tests {
+		compiler := New()
+		compiler.EnableOptimization(level)
+		if err := compiler.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error for %q: %s", tt.input, err)
+		}
+
+		bytecode := compiler.ByteCode()
+		if err := testInstructions(tt.expectedInstructions, bytecode.Instructions); err != nil {
+			t.Fatalf("testInstructions failed for %q: %s", tt.input, err)
+		}
+		if err := testConstants(tt.expectedConstants, bytecode.Constants); err != nil {
+			t.Fatalf("testConstants failed for %q: %s", tt.input, err)
+		}
+	}
+}
+
+func TestConstantFolding(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "1 + 2 * 3",
+			expectedConstants: []interface{}{7},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "-(2 ** 10) % 7 + (1 << 4)",
+			expectedConstants: []interface{}{14},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `"mu" + "ta" + "nt"`,
+			expectedConstants: []interface{}{"mutant"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// Folded keys keep their place in the literal.
+			input:             `{"z": 1, 2 + 3: "a", "b": 2, 1 + 0: 9}`,
+			expectedConstants: []interface{}{"z", 1, 5, "a", "b", 2, 1, 9},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpConstant, 4),
+				code.Make(code.OpConstant, 5),
+				code.Make(code.OpConstant, 6),
+				code.Make(code.OpConstant, 7),
+				code.Make(code.OpHash, 8),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "!(1 < 2)",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpFalse),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "let x = 2; x * (3 + 4)",
+			expectedConstants: []interface{}{2, 7},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpMul),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// Errors are left for the VM to raise at run time.
+			input:             "1 / 0",
+			expectedConstants: []interface{}{1, 0},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpDiv),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "2 ** 64",
+			expectedConstants: []interface{}{2, 64},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPow),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "fn() { [1 + 1, len(\"a\" + \"b\")] }",
+			expectedConstants: []interface{}{
+				2,
+				"ab",
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpGetBuiltin, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpArray, 2),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runOptimizedCompilerTests(t, OptimizeBasic, tests)
+}
+
+func TestDeadCodeElimination(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: "fn() { return 1; 2; 3 }",
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "if (true) { 10 } else { 20 }; 30",
+			expectedConstants: []interface{}{10, 30},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "if (1 > 2) { 10 }",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpNull),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// An empty taken branch yields null rather than the previous value.
+			input:             "1; if (true) { }",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpNull),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runOptimizedCompilerTests(t, OptimizeBasic, tests)
+}
+
+func TestDeadCodeKeepsDeclarations(t *testing.T) {
+	inputs := []string{
+		"if (false) { let x = 1; } putln(x);",
+		"if (true) { 1 } else { let [a, b] = [1, 2]; }; a + b",
+		"fn() { for (let i = 0; i < 3; i = i + 1) { break; let z = 1; } return z; }",
+		"fn() { if (true) { return 1; for (k, v in {}) { } } k }",
+		"fn() { if (true) { return 1; try { } catch (e) { } } e }",
+		"if (false) { let y = 1; }; q",
+	}
+
+	for _, input := range inputs {
+		var errs []string
+		for level := OptimizeNone; level <= OptimizeFull; level++ {
+			compiler := New()
+			compiler.EnableOptimization(level)
+			msg := "<nil>"
+			if err := compiler.Compile(parse(input)); err != nil {
+				msg = err.Error()
+			}
+			errs = append(errs, msg)
+		}
+		if errs[1] != errs[0] || errs[2] != errs[0] {
+			t.Errorf("%q compiles differently across -O0, -O1, -O2: %q", input, errs)
+		}
+	}
+}
+
+func TestJumpThreading(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: "fn(a, b) { if (a) { if (b) { 1 } else { 2 } } else { 3 } }",
+			expectedConstants: []interface{}{
+				1,
+				2,
+				3,
+				[]code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpJumpFalse, 22),
+					code.Make(code.OpGetLocal, 1),
+					code.Make(code.OpJumpFalse, 16),
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpJump, 25), // was 19, the outer branch's jump to 25
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpJump, 25),
+					code.Make(code.OpConstant, 2),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 3, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runOptimizedCompilerTests(t, OptimizeFull, tests)
+}
+
+func TestOptimizationKeepsSecurityOpcodes(t *testing.T) {
+	compiler := New()
+	compiler.EnableOptimization(OptimizeFull)
+	compiler.EnableSecurityOpcodeInjection()
+	if err := compiler.Compile(parse("if (false) { 1 }; 2 + 3")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	ins := compiler.ByteCode().Instructions
+	found := map[code.Opcode]bool{}
+	for pos := 0; pos < len(ins); {
+		def, err := code.Lookup(ins[pos])
+		if err != nil {
+			t.Fatalf("bad opcode at %d: %s", pos, err)
+		}
+		found[code.Opcode(ins[pos])] = true
+		_, read := code.ReadOperands(def, ins[pos+1:])
+		pos += 1 + read
+	}
+	if !found[code.OpChkDbg] || !found[code.OpChkSnd] {
+		t.Fatalf("security opcodes missing from optimized bytecode:\n%s", ins)
+	}
+}
@@ -216,6 +216,32 @@ func (pe *PolymorphicEngine) generateOpcodeMapping() map[code.Opcode]code.Opcode
 		code.OpGetField,
 		code.OpSetField,
 		code.OpEnumValue,
+		code.OpJumpFalseOrPop,
+		code.OpJumpTrueOrPop,
+		code.OpGreaterEqual,
+		code.OpPow,
+		code.OpBitAnd,
+		code.OpBitOr,
+		code.OpBitXor,
+		code.OpShl,
+		code.OpShr,
+		code.OpIterInit,
+		code.OpIterNext,
+		code.OpTry,
+		code.OpEndTry,
+		code.OpThrow,
+		code.OpMakeEnum,
+		code.OpMatchTag,
+		code.OpEnumPayload,
+		code.OpUnpackArray,
+		code.OpUnpackKey,
+		code.OpUnpackField,
+		code.OpJumpIfArg,
+		code.OpSetFree,
+		code.OpAssignLocal,
+		code.OpCaptureLocal,
+		code.OpCaptureFree,
+		code.OpTailCall,
 	}
 
 	// Create a copy for shuffling
@@ -263,6 +289,13 @@ func (pe *PolymorphicEngine) randomizeConstantPool(bytecode *ByteCode) *ByteCode
 		}
 	}
 
+	// Struct methods refer to their functions by constant index
+	for _, methods := range bytecode.StructMethods {
+		for name, oldIdx := range methods {
+			methods[name] = mapping[oldIdx]
+		}
+	}
+
 	bytecode.Constants = newConstants
 	return bytecode
 }
@@ -1,5 +1,10 @@
 package compiler
 
+import (
+	"sort"
+	"strings"
+)
+
 type SymbolScope string
 
 const (
@@ -21,12 +26,31 @@ type SymbolTable struct {
 	store          map[string]Symbol
 	numDefinitions int
 	FreeSymbols    []Symbol
+
+	referencedBuiltins map[string]Symbol // builtins resolved through this table's builtin scope
+
+	root       *SymbolTable                 // for module tables, the global table slots are allocated in
+	prefix     string                       // for module tables, mangles names defined in root
+	namespaces map[string]map[string]Symbol // imported modules by namespace name
 }
 
 func NewSymbolTable() *SymbolTable {
 	s := make(map[string]Symbol)
 	free := []Symbol{}
-	return &SymbolTable{store: s, FreeSymbols: free}
+	return &SymbolTable{store: s, FreeSymbols: free, referencedBuiltins: make(map[string]Symbol)}
+}
+
+// NewModuleSymbolTable returns the top-level table of an imported module.
+// Its definitions are globals allocated in root under prefix-mangled names,
+// and it sees root's builtins but none of root's own globals.
+func NewModuleSymbolTable(root *SymbolTable, prefix string) *SymbolTable {
+	if root.root != nil {
+		root = root.root
+	}
+	s := NewSymbolTable()
+	s.root = root
+	s.prefix = prefix
+	return s
 }
 
 func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
@@ -36,6 +60,12 @@ func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
 }
 
 func (st *SymbolTable) Define(name string) Symbol {
+	if st.root != nil {
+		symbol := st.root.Define(st.prefix + name)
+		st.store[name] = symbol
+		return symbol
+	}
+
 	symbol := Symbol{Name: name, Index: st.numDefinitions}
 	if st.Outer == nil {
 		symbol.Scope = GlobalScope
@@ -46,9 +76,28 @@ func (st *SymbolTable) Define(name string) Symbol {
 	st.numDefinitions++
 	return symbol
 }
+
+// defineHidden returns a compiler-internal slot in this table, defining it on
+// first use. Hidden names start with '$' so they never collide with identifiers.
+func (st *SymbolTable) defineHidden(name string) Symbol {
+	if symbol, ok := st.store[name]; ok {
+		return symbol
+	}
+	return st.Define(name)
+}
+
 func (st *SymbolTable) Resolve(name string) (Symbol, bool) {
 	obj, ok := st.store[name]
 
+	if !ok && st.root != nil {
+		builtin, found := st.root.store[name]
+		if !found || builtin.Scope != BuiltinScope {
+			return obj, false
+		}
+		st.root.referencedBuiltins[name] = builtin
+		return builtin, true
+	}
+
 	if !ok && st.Outer != nil {
 		obj, ok = st.Outer.Resolve(name)
 		if !ok {
@@ -63,9 +112,54 @@ func (st *SymbolTable) Resolve(name string) (Symbol, bool) {
 		return free, true
 	}
 
+	if ok && obj.Scope == BuiltinScope {
+		st.referencedBuiltins[name] = obj
+	}
+
 	return obj, ok
 }
 
+// ReferencedBuiltins returns the builtin symbols resolved so far, ordered by builtin index.
+func (st *SymbolTable) ReferencedBuiltins() []Symbol {
+	symbols := make([]Symbol, 0, len(st.referencedBuiltins))
+	for _, symbol := range st.referencedBuiltins {
+		symbols = append(symbols, symbol)
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].Index < symbols[j].Index })
+	return symbols
+}
+
+// Exports returns the globals a module table defined, keyed by their
+// unmangled names, excluding compiler-internal slots
+func (st *SymbolTable) Exports() map[string]Symbol {
+	exports := make(map[string]Symbol)
+	for name, symbol := range st.store {
+		if symbol.Scope == GlobalScope && !strings.HasPrefix(name, "$") {
+			exports[name] = symbol
+		}
+	}
+	return exports
+}
+
+// DefineNamespace binds an imported module's exports to name
+func (st *SymbolTable) DefineNamespace(name string, members map[string]Symbol) {
+	if st.namespaces == nil {
+		st.namespaces = make(map[string]map[string]Symbol)
+	}
+	st.namespaces[name] = members
+}
+
+// ResolveNamespace finds the module bound to name in this or an enclosing table
+func (st *SymbolTable) ResolveNamespace(name string) (map[string]Symbol, bool) {
+	if members, ok := st.namespaces[name]; ok {
+		return members, true
+	}
+	if st.Outer != nil {
+		return st.Outer.ResolveNamespace(name)
+	}
+	return nil, false
+}
+
 func (st *SymbolTable) DefineBuiltin(index int, name string) Symbol {
 	symbol := Symbol{Name: name, Index: index, Scope: BuiltinScope}
 	st.store[name] = symbol
@@ -318,3 +318,22 @@ func TestShadowingFunctionName(t *testing.T) {
 			expected.Name, expected, result)
 	}
 }
+
+func TestReferencedBuiltinsTracksNestedResolution(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineBuiltin(0, "len")
+	global.DefineBuiltin(1, "cmd")
+	global.DefineBuiltin(2, "puts")
+	global.Define("a")
+
+	local := NewEnclosedSymbolTable(global)
+	local.Resolve("cmd")
+	local.Resolve("a")
+	global.Resolve("len")
+	global.Resolve("missing")
+
+	got := global.ReferencedBuiltins()
+	if len(got) != 2 || got[0].Name != "len" || got[1].Name != "cmd" {
+		t.Fatalf("unexpected referenced builtins: %+v", got)
+	}
+}
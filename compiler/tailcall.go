@@ -0,0 +1,64 @@
+package compiler
+
+import (
+	"encoding/binary"
+
+	"mutant/code"
+	"mutant/object"
+)
+
+// maxTailJumps bounds how many unconditional jumps markTailCalls follows from
+// a call to the return that consumes its result
+const maxTailJumps = 8
+
+// markTailCalls rewrites every OpCall of the current function whose result is
+// returned unchanged into OpTailCall. That is the case when the next
+// instruction to run, following unconditional jumps, is OpReturnValue, which
+// covers `return f(x)`, a call as the last expression and calls ending the
+// branches of a final if or match. Calls inside the range of a try handler
+// keep OpCall, so the handler and any finally block still run.
+func (c *Compiler) markTailCalls() {
+	scope := &c.scopes[c.scopeIndex]
+	ins := scope.instructions
+
+	for pos := 0; pos < len(ins); {
+		def, err := code.Lookup(ins[pos])
+		if err != nil {
+			return
+		}
+		next := pos + 1
+		for _, width := range def.OperandWidths {
+			next += width
+		}
+
+		if code.Opcode(ins[pos]) == code.OpCall && returnsAt(ins, next) && !insideHandler(scope.handlers, pos) {
+			ins[pos] = byte(code.OpTailCall)
+		}
+		pos = next
+	}
+}
+
+// returnsAt reports whether execution from pos reaches OpReturnValue through
+// nothing but unconditional jumps
+func returnsAt(ins code.Instructions, pos int) bool {
+	for jumps := 0; jumps <= maxTailJumps && pos < len(ins); jumps++ {
+		switch code.Opcode(ins[pos]) {
+		case code.OpReturnValue:
+			return true
+		case code.OpJump:
+			pos = int(binary.BigEndian.Uint16(ins[pos+1:]))
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+func insideHandler(handlers []object.ExceptionHandler, pos int) bool {
+	for _, handler := range handlers {
+		if pos >= handler.Start && pos < handler.End {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package compiler
+
+import (
+	"fmt"
+	"regexp"
+
+	"mutant/ast"
+	"mutant/builtin"
+)
+
+// Warnings returns the problems found while compiling that do not stop
+// compilation, each prefixed with its source position when known
+func (c *Compiler) Warnings() []string {
+	return c.warnings
+}
+
+// warnAt records a warning prefixed with the source position of node
+func (c *Compiler) warnAt(node ast.Node, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if pos := node.Pos(); pos.IsValid() {
+		msg = fmt.Sprintf("%s: %s", pos, msg)
+	}
+	c.warnings = append(c.warnings, msg)
+}
+
+// checkPatternArgument warns when a regex builtin is called with a string
+// literal pattern that does not compile. The call still compiles and fails
+// with an error value at run time.
+func (c *Compiler) checkPatternArgument(node *ast.CallExpression) {
+	ident, ok := node.Function.(*ast.Identifier)
+	if !ok || len(node.Arguments) == 0 || !builtin.IsPatternBuiltin(ident.Value) {
+		return
+	}
+	pattern, ok := node.Arguments[0].(*ast.StringLiteral)
+	if !ok {
+		return
+	}
+	if symbol, ok := c.symbolTable.Resolve(ident.Value); !ok || symbol.Scope != BuiltinScope {
+		return
+	}
+	if _, err := regexp.Compile(pattern.Value); err != nil {
+		c.warnAt(pattern, "invalid regular expression passed to %s: %s", ident.Value, err)
+	}
+}
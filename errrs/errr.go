@@ -21,3 +21,10 @@ func PrintMachineError(out io.Writer, msg string) {
 	io.WriteString(out, "vm error:")
 	io.WriteString(out, "\n\t"+msg+"\t\n")
 }
+
+// PrintCompilerWarnings writes warnings that did not stop compilation
+func PrintCompilerWarnings(out io.Writer, msgs []string) {
+	for _, msg := range msgs {
+		io.WriteString(out, "compiler warning: "+msg+"\n")
+	}
+}
@@ -46,4 +46,63 @@ var builtins = map[string]*builtin.BuiltIn{
 	"db_query_nodes":   builtin.GetBuiltinByName("db_query_nodes"),
 	"db_bfs":           builtin.GetBuiltinByName("db_bfs"),
 	"db_shortest_path": builtin.GetBuiltinByName("db_shortest_path"),
-	"db_stats":         builtin.GetBuiltinByName("db_stats")}
+	"db_stats":         builtin.GetBuiltinByName("db_stats"),
+	// conversions
+	"str":       builtin.GetBuiltinByName("str"),
+	"int":       builtin.GetBuiltinByName("int"),
+	"float":     builtin.GetBuiltinByName("float"),
+	"parse_int": builtin.GetBuiltinByName("parse_int"),
+	// math
+	"abs":   builtin.GetBuiltinByName("abs"),
+	"min":   builtin.GetBuiltinByName("min"),
+	"max":   builtin.GetBuiltinByName("max"),
+	"floor": builtin.GetBuiltinByName("floor"),
+	"ceil":  builtin.GetBuiltinByName("ceil"),
+	"round": builtin.GetBuiltinByName("round"),
+	"sqrt":  builtin.GetBuiltinByName("sqrt"),
+	"pow":   builtin.GetBuiltinByName("pow"),
+	"log":   builtin.GetBuiltinByName("log"),
+	"exp":   builtin.GetBuiltinByName("exp"),
+	// strings
+	"split":       builtin.GetBuiltinByName("split"),
+	"join":        builtin.GetBuiltinByName("join"),
+	"replace":     builtin.GetBuiltinByName("replace"),
+	"trim":        builtin.GetBuiltinByName("trim"),
+	"upper":       builtin.GetBuiltinByName("upper"),
+	"lower":       builtin.GetBuiltinByName("lower"),
+	"contains":    builtin.GetBuiltinByName("contains"),
+	"starts_with": builtin.GetBuiltinByName("starts_with"),
+	"ends_with":   builtin.GetBuiltinByName("ends_with"),
+	"index_of":    builtin.GetBuiltinByName("index_of"),
+	"repeat":      builtin.GetBuiltinByName("repeat"),
+	"pad":         builtin.GetBuiltinByName("pad"),
+	"substr":      builtin.GetBuiltinByName("substr"),
+	// hashes
+	"keys":     builtin.GetBuiltinByName("keys"),
+	"values":   builtin.GetBuiltinByName("values"),
+	"items":    builtin.GetBuiltinByName("items"),
+	"has_key":  builtin.GetBuiltinByName("has_key"),
+	"delete":   builtin.GetBuiltinByName("delete"),
+	"merge":    builtin.GetBuiltinByName("merge"),
+	"hash_len": builtin.GetBuiltinByName("hash_len"),
+	// json
+	"json_decode": builtin.GetBuiltinByName("json_decode"),
+	// introspection
+	"type_of":     builtin.GetBuiltinByName("type_of"),
+	"is_int":      builtin.GetBuiltinByName("is_int"),
+	"is_float":    builtin.GetBuiltinByName("is_float"),
+	"is_number":   builtin.GetBuiltinByName("is_number"),
+	"is_string":   builtin.GetBuiltinByName("is_string"),
+	"is_bool":     builtin.GetBuiltinByName("is_bool"),
+	"is_array":    builtin.GetBuiltinByName("is_array"),
+	"is_hash":     builtin.GetBuiltinByName("is_hash"),
+	"is_null":     builtin.GetBuiltinByName("is_null"),
+	"is_function": builtin.GetBuiltinByName("is_function"),
+	"is_struct":   builtin.GetBuiltinByName("is_struct"),
+	"is_enum":     builtin.GetBuiltinByName("is_enum"),
+	"is_error":    builtin.GetBuiltinByName("is_error"),
+	"has_field":   builtin.GetBuiltinByName("has_field"),
+	"get_field":   builtin.GetBuiltinByName("get_field"),
+	"set_field":   builtin.GetBuiltinByName("set_field"),
+	"enum_tag":    builtin.GetBuiltinByName("enum_tag"),
+}
@@ -1,6 +1,7 @@
 package evaluator
 
 import (
+	"math"
 	"mutant/ast"
 	"mutant/object"
 )
@@ -44,17 +45,22 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: -right.Value}
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
 func evalInfixExpression(operator string, left, right object.Object) object.Object {
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isNumeric(left) && isNumeric(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case operator == "==":
 		return nativeBoolToBoolObject(left.Inspect() == right.Inspect())
 	case operator == "!=":
@@ -80,11 +86,47 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}
+	case "%":
+		if rightVal == 0 {
+			return newError("modulo by zero")
+		}
+		return &object.Integer{Value: leftVal % rightVal}
+	case "**":
+		if rightVal < 0 {
+			// negative exponents cannot stay integral
+			return &object.Float{Value: math.Pow(float64(leftVal), float64(rightVal))}
+		}
+		result, ok := object.IntPow(leftVal, rightVal)
+		if !ok {
+			return newError("%d ** %d is out of range for INTEGER", leftVal, rightVal)
+		}
+		return &object.Integer{Value: result}
+	case "&":
+		return &object.Integer{Value: leftVal & rightVal}
+	case "|":
+		return &object.Integer{Value: leftVal | rightVal}
+	case "^":
+		return &object.Integer{Value: leftVal ^ rightVal}
+	case "<<", ">>":
+		if rightVal < 0 {
+			return newError("negative shift count: %d", rightVal)
+		}
+		if operator == "<<" {
+			return &object.Integer{Value: leftVal << uint64(rightVal)}
+		}
+		return &object.Integer{Value: leftVal >> uint64(rightVal)}
 	case "<":
 		return nativeBoolToBoolObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBoolObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBoolObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBoolObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBoolObject(leftVal == rightVal)
 	case "!=":
@@ -94,13 +136,83 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	}
 }
 
-func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
-	if operator != "+" {
+// evalFloatInfixExpression handles float operands, promoting an integer side to float
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
+	case "**":
+		return &object.Float{Value: math.Pow(leftVal, rightVal)}
+	case "&", "|", "^", "<<", ">>":
+		return newError("bitwise operators require integer operands: %s%s%s", left.Type(), operator, right.Type())
+	case "<":
+		return nativeBoolToBoolObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBoolObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBoolObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBoolObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBoolObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBoolObject(leftVal != rightVal)
+	default:
 		return newError("unknown operator: %s%s%s", left.Type(), operator, right.Type())
 	}
+}
+
+func isNumeric(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+func toFloat(obj object.Object) float64 {
+	if i, ok := obj.(*object.Integer); ok {
+		return float64(i.Value)
+	}
+	return obj.(*object.Float).Value
+}
+
+// evalLogicalExpression short-circuits && and ||, yielding the operand that decided the result
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+	if isTruthy(left) == (node.Operator == "||") {
+		return left
+	}
+	return Eval(node.Right, env)
+}
+
+func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
 	lval := left.(*object.String).Value
 	rval := right.(*object.String).Value
-	return &object.String{Value: lval + rval}
+	switch operator {
+	case "+":
+		return &object.String{Value: lval + rval}
+	case "<":
+		return nativeBoolToBoolObject(lval < rval)
+	case ">":
+		return nativeBoolToBoolObject(lval > rval)
+	case "<=":
+		return nativeBoolToBoolObject(lval <= rval)
+	case ">=":
+		return nativeBoolToBoolObject(lval >= rval)
+	default:
+		return newError("unknown operator: %s%s%s", left.Type(), operator, right.Type())
+	}
 }
 
 func evalIfExpression(node *ast.IfExpression, env *object.Environment) object.Object {
@@ -67,6 +67,83 @@ func TestNestedForLoops(t *testing.T) {
 	testIntegerObject(t, evaluated, 6)
 }
 
+func TestForInLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let sum = 0; for (x in [1, 2, 3]) { sum += x; } sum", 6},
+		{"let sum = 0; for (i, x in [5, 6, 7]) { sum += i * x; } sum", 20},
+		{`let out = ""; for (k, v in {"b": 2, "a": 1}) { out = out + k + str(v); } out`, "b2a1"},
+		{`let out = ""; for (k in {"b": 2, "a": 1}) { out = out + k; } out`, "ba"},
+		{`let out = ""; for (ch in "héllo") { if (ch == "l") { break; } out = out + ch; } out`, "hé"},
+		{"let sum = 0; for (i in 0..10) { if (i % 2 == 0) { continue; } sum += i; } sum", 25},
+		{"let n = 0; for (i in 0..4) { for (j in 0..i) { n += 1; } } n", 6},
+		{"let f = fn() { for (i in 1..100) { if (i * i > 50) { return i; } } }; f()", 8},
+		{"for (i in 5..0) { 1 }", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok || str.Value != expected {
+				t.Errorf("%q: expected %q, got=%s", tt.input, expected, evaluated.Inspect())
+			}
+		case nil:
+			if evaluated != NULL {
+				t.Errorf("%q: expected NULL, got=%s", tt.input, evaluated.Inspect())
+			}
+		}
+	}
+}
+
+func TestForInErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for (x in 5) { x }", "cannot iterate over INTEGER"},
+		{"for (i, x in 0..3) { x }", "range loops bind a single variable"},
+		{`for (i in 0.."a") { i }`, "range bounds must be integers, got INTEGER..STRING"},
+		{"let r = 0..3;", "range expressions are only supported as a for-in iterable"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Fatalf("%q: expected error", tt.input)
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestCompiledOnlyStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import util`, "import is only supported by compiled programs"},
+		{`try { 1 } catch (e) { 2 }`, "try is only supported by compiled programs"},
+		{`throw "boom";`, "throw is only supported by compiled programs"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Fatalf("%q: expected error", tt.input)
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+}
+
 func TestStructDefinition(t *testing.T) {
 	input := "struct Point { x; y; } 42"
 	evaluated := testEval(input)
@@ -174,3 +251,205 @@ func TestStructInArray(t *testing.T) {
 	evaluated := testEval(input)
 	testIntegerObject(t, evaluated, 1)
 }
+
+func TestEnumPayloadsAndMatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{
+			`
+			enum Shape { Circle(r), Square(side), Empty }
+			let area = fn(s) {
+				match (s) {
+					Shape.Circle(r) => 3 * r * r,
+					Shape.Square(n) => n * n,
+					Shape.Empty => 0,
+				}
+			};
+			area(Shape.Circle(2)) + area(Shape.Square(3)) + area(Shape.Empty)
+			`,
+			21,
+		},
+		{`enum Result { Ok(v), Err(m) } match (Result.Err("bad")) { Result.Ok(v) => v, Result.Err(m) => "error: " + m }`, "error: bad"},
+		{`enum Opt { Some(v), None } match (Opt.None) { Opt.Some(v) => v, _ => "none" }`, "none"},
+		{`match (2) { 1 => "one", 2 => "two", _ => "many" }`, "two"},
+		{`match (-1) { -1 => "neg", _ => "pos" }`, "neg"},
+		{`enum Opt { Some(v), None } Opt.Some([1, 2])`, "Opt.Some([1, 2])"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if str, ok := evaluated.(*object.String); ok {
+				if str.Value != expected {
+					t.Errorf("%q: wrong value. want=%q, got=%q", tt.input, expected, str.Value)
+				}
+			} else if evaluated.Inspect() != expected {
+				t.Errorf("%q: wrong value. want=%q, got=%q", tt.input, expected, evaluated.Inspect())
+			}
+		}
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`enum Opt { Some(v), None } match (Opt.None) { Opt.Some(v) => v }`, "non-exhaustive match on Opt: missing None"},
+		{`match (1) { 1 => 1 }`, "non-exhaustive match: add a `_` arm"},
+		{`enum Opt { Some(v), None } match (Opt.None) { Opt.Nope => 1, _ => 2 }`, "unknown enum tag Opt.Nope"},
+		{`enum Opt { Some(v), None } match (Opt.None) { Opt.None(x) => 1, _ => 2 }`, "enum variant Opt.None has no payload to bind"},
+		{`enum Opt { Some(v), None } match (1) { Opt.None => 1, _ => 2 }`, "cannot match 1 against Opt"},
+		{`enum Opt { Some(v), None } Opt.Some`, "enum variant Opt.Some requires a payload"},
+		{`enum Opt { Some(v), None } Opt.None(1)`, "enum variant Opt.None takes no payload"},
+		{`enum Opt { Some(v), None } Opt.Some(1, 2)`, "enum variant Opt.Some takes 1 payload, got 2"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Fatalf("%q: expected error", tt.input)
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestStructMethods(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{
+			`
+			struct Point {
+				x; y;
+				fn norm2() { self.x * self.x + self.y * self.y }
+				fn scaled(k) { Point { x: self.x * k, y: self.y * k } }
+			}
+			let p = Point { x: 3, y: 4 };
+			p.norm2() + p.scaled(2).norm2()
+			`,
+			125,
+		},
+		{
+			`
+			struct Greeter { name; fn greet(greeting) { greeting + ", " + self.name } }
+			let g = Greeter { name: "ann" };
+			let f = g.greet;
+			f("hi")
+			`,
+			"hi, ann",
+		},
+		{`struct Box { v; fn bump() { self.v = self.v + 1; self } } let b = Box { v: 1 }; b.bump().v * 10 + b.v`, 21},
+		{`struct P { x; fn m(a) { a } } P { x: 1 }.m()`, "wrong number of arguments. want=1, got=0"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch result := evaluated.(type) {
+			case *object.String:
+				if result.Value != expected {
+					t.Errorf("wrong value. want=%q, got=%q", expected, result.Value)
+				}
+			case *object.Error:
+				if result.Message != expected {
+					t.Errorf("wrong error. want=%q, got=%q", expected, result.Message)
+				}
+			default:
+				t.Errorf("unexpected result %T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestDestructuringLet(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let [a, b, ...rest] = [1, 2, 3, 4]; a + b + len(rest) * 10 + rest[1]`, 27},
+		{`let [_, second, _] = [1, 2, 3]; second`, 2},
+		{`let {status, body} = {"status": 200, "body": 5}; status + body`, 205},
+		{`struct Point { x; y; } let Point {y, x} = Point { x: 3, y: 4 }; x * 10 + y`, 34},
+		{`let [a, b] = [1];`, "array pattern expects 2 elements, got 1"},
+		{`let [a, b, ...c] = [1];`, "array pattern expects at least 2 elements, got 1"},
+		{`let {a} = [1];`, "cannot destructure ARRAY as a hash"},
+		{`let {a} = {"b": 1};`, `missing key "a" in destructured hash`},
+		{`struct P { x; } let P {x} = Q { x: 1 };`, "cannot destructure Q as P"},
+		{`struct P { x; } let P {z} = P { x: 1 };`, "struct P has no field z"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("%q: expected error, got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestDefaultAndRestParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let f = fn(x, timeout = 3000) { x + timeout }; f(1) * 10 + f(1, 2)`, 30013},
+		{`let f = fn(a, b = a * 10) { b }; f(4)`, 40},
+		{`let f = fn(first, ...rest) { len(rest) * 10 + first }; f(1) + f(1, 2, 3) * 100`, 2101},
+		{`let f = fn(...xs) { push(xs, len(xs)) }; let r = f(7, 8); r[2] * 10 + len(r)`, 23},
+		{`struct R { lo; fn span(hi = self.lo + 10, ...extra) { hi - self.lo + len(extra) } } R { lo: 5 }.span(8, 0, 0)`, 5},
+		{`fn(a, b = 1) { a }()`, "wrong number of arguments. want=1 to 2, got=0"},
+		{`fn(a, ...r) { a }()`, "wrong number of arguments. want at least 1, got=0"},
+		{`fn(a) { a }(1, 2)`, "wrong number of arguments. want=1, got=2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Fatalf("%q: expected error, got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestMutableCapturedVariables(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`let counter = fn() { let n = 0; [fn() { n = n + 1; n }, fn() { n }] }; let c = counter(); c[0](); c[0](); c[1]()`, 2},
+		{`let make = fn() { let n = 0; fn() { n = n + 1 } }; let a = make(); let b = make(); a(); a(); b(); a() * 10 + b()`, 32},
+		{`let f = fn() { let x = 1; let write = fn() { x = x + 1 }; write(); write(); x }; f()`, 3},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
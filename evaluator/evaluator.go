@@ -19,6 +19,9 @@ func Eval(n ast.Node, env *object.Environment) object.Object {
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
 
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+
 	case *ast.Boolean:
 		return nativeBoolToBoolObject(node.Value)
 
@@ -29,7 +32,17 @@ func Eval(n ast.Node, env *object.Environment) object.Object {
 		}
 		return evalPrefixExpression(node.Operator, right)
 
+	case *ast.StringifyExpression:
+		value := Eval(node.Value, env)
+		if isError(value) {
+			return value
+		}
+		return builtins["str"].Fn(value)
+
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evalLogicalExpression(node, env)
+		}
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
@@ -49,13 +62,16 @@ func Eval(n ast.Node, env *object.Environment) object.Object {
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return &object.Function{Parameters: params, Defaults: node.Defaults, Rest: node.Rest, Env: env, Body: body}
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 	case *ast.CallExpression:
 		if node.Function.TokenLiteral() == "quote" {
 			return quote(node.Arguments[0], env)
 		}
+		if template, ok := enumVariantTemplate(node.Function, env); ok {
+			return evalEnumConstructor(node, template, env)
+		}
 		function := Eval(node.Function, env)
 		if isError(function) {
 			return function
@@ -108,9 +124,24 @@ func Eval(n ast.Node, env *object.Environment) object.Object {
 		}
 		env.Set(node.Name.Value, val)
 
+	case *ast.DestructureStatement:
+		return evalDestructureStatement(node, env)
+
 	case *ast.ForStatement:
 		return evalForStatement(node, env)
 
+	case *ast.ForInStatement:
+		return evalForInStatement(node, env)
+
+	case *ast.ImportStatement:
+		return newError("import is only supported by compiled programs")
+
+	case *ast.TryStatement, *ast.ThrowStatement:
+		return newError("%s is only supported by compiled programs", node.TokenLiteral())
+
+	case *ast.RangeExpression:
+		return newError("range expressions are only supported as a for-in iterable")
+
 	case *ast.BreakStatement:
 		return &object.Break{}
 
@@ -131,6 +162,9 @@ func Eval(n ast.Node, env *object.Environment) object.Object {
 
 	case *ast.StructLiteral:
 		return evalStructLiteral(node, env)
+
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
 	}
 	return nil
 }
@@ -185,7 +219,10 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 func applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch fun := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fun, args)
+		extendedEnv, errObj := extendFunctionEnv(fun, args)
+		if errObj != nil {
+			return errObj
+		}
 		evaluated := Eval(fun.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
 	case *builtin.BuiltIn:
@@ -193,17 +230,63 @@ func applyFunction(fn object.Object, args []object.Object) object.Object {
 			return result
 		}
 		return NULL
+	case *object.BoundMethod:
+		return applyMethod(fun, args)
 	default:
 		return newError("not a function: %s", fn.Type())
 	}
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+// applyMethod calls a bound method with a copy of its receiver as `self`,
+// matching the compiled VM where structs are passed by value.
+func applyMethod(bound *object.BoundMethod, args []object.Object) object.Object {
+	method, ok := bound.Method.(*object.Function)
+	if !ok {
+		return newError("not a function: %s", bound.Method.Type())
+	}
+	if msg := object.ArityError(len(method.Parameters)-1, method.NumDefaults(), method.Rest != nil, len(args)); msg != "" {
+		return newError("%s", msg)
+	}
+
+	receiver := bound.Receiver
+	if structObj, ok := receiver.(*object.Struct); ok {
+		fields := make(map[string]object.Object, len(structObj.Fields))
+		for name, value := range structObj.Fields {
+			fields[name] = value
+		}
+		receiver = &object.Struct{TypeName: structObj.TypeName, Fields: fields}
+	}
+	return applyFunction(method, append([]object.Object{receiver}, args...))
+}
+
+// extendFunctionEnv binds args to the parameters of fn. Omitted parameters
+// get their defaults, evaluated left to right so that each may use the
+// parameters before it; a rest parameter collects the remaining arguments.
+func extendFunctionEnv(fn *object.Function, args []object.Object) (*object.Environment, object.Object) {
+	if msg := object.ArityError(len(fn.Parameters), fn.NumDefaults(), fn.Rest != nil, len(args)); msg != "" {
+		return nil, newError("%s", msg)
+	}
+
 	env := object.NewEnclosedEnvironement(fn.Env)
 	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+		if paramIdx < len(args) {
+			env.Set(param.Value, args[paramIdx])
+			continue
+		}
+		value := Eval(fn.Defaults[paramIdx], env)
+		if isError(value) {
+			return nil, value
+		}
+		env.Set(param.Value, value)
+	}
+	if fn.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(fn.Parameters) {
+			rest = append(rest, args[len(fn.Parameters):]...)
+		}
+		env.Set(fn.Rest.Value, &object.Array{Elements: rest})
 	}
-	return env
+	return env, nil
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -227,8 +310,8 @@ func isTruthy(obj object.Object) bool {
 }
 
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
-	for keyNode, valueNode := range node.Pairs {
+	hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+	for _, keyNode := range node.OrderedKeys() {
 		key := Eval(keyNode, env)
 		if isError(key) {
 			return key
@@ -237,14 +320,13 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 		if !ok {
 			return newError("unusable as hash key: %s", key.Type())
 		}
-		value := Eval(valueNode, env)
+		value := Eval(node.Pairs[keyNode], env)
 		if isError(value) {
 			return value
 		}
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashKey.HashKey(), object.HashPair{Key: key, Value: value})
 	}
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 func evalForStatement(node *ast.ForStatement, env *object.Environment) object.Object {
@@ -299,6 +381,138 @@ func evalForStatement(node *ast.ForStatement, env *object.Environment) object.Ob
 	return NULL
 }
 
+func evalForInStatement(node *ast.ForInStatement, env *object.Environment) object.Object {
+	loopEnv := object.NewEnclosedEnvironement(env)
+
+	if rng, ok := node.Iterable.(*ast.RangeExpression); ok {
+		if node.Key != nil {
+			return newError("range loops bind a single variable")
+		}
+		start := Eval(rng.Start, env)
+		if isError(start) {
+			return start
+		}
+		end := Eval(rng.End, env)
+		if isError(end) {
+			return end
+		}
+		from, ok1 := start.(*object.Integer)
+		to, ok2 := end.(*object.Integer)
+		if !ok1 || !ok2 {
+			return newError("range bounds must be integers, got %s..%s", start.Type(), end.Type())
+		}
+		for i := from.Value; i < to.Value; i++ {
+			loopEnv.Set(node.Value.Value, &object.Integer{Value: i})
+			if result, done := evalForInBody(node.Body, loopEnv); done {
+				return result
+			}
+		}
+		return NULL
+	}
+
+	iterable := Eval(node.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+	iter, ok := object.NewIterator(iterable)
+	if !ok {
+		return newError("cannot iterate over %s", iterable.Type())
+	}
+
+	for i := 0; i < iter.Len(); i++ {
+		if node.Key != nil {
+			loopEnv.Set(node.Key.Value, iter.Key(i))
+			loopEnv.Set(node.Value.Value, iter.Value(i))
+		} else {
+			loopEnv.Set(node.Value.Value, iter.Single(i))
+		}
+		if result, done := evalForInBody(node.Body, loopEnv); done {
+			return result
+		}
+	}
+
+	return NULL
+}
+
+// evalForInBody runs one iteration and reports whether the loop must stop,
+// along with the value the loop statement produces in that case
+func evalForInBody(body *ast.BlockStatement, env *object.Environment) (object.Object, bool) {
+	result := Eval(body, env)
+	if result == nil {
+		return nil, false
+	}
+	switch result.Type() {
+	case object.BREAK_OBJ:
+		return NULL, true
+	case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
+		return result, true
+	}
+	return nil, false
+}
+
+func evalDestructureStatement(node *ast.DestructureStatement, env *object.Environment) object.Object {
+	value := Eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	switch node.Kind {
+	case ast.ArrayPattern:
+		array, ok := value.(*object.Array)
+		if !ok {
+			return newError("cannot destructure %s as an array", value.Type())
+		}
+		count, length := len(node.Names), len(array.Elements)
+		if node.Rest != nil && length < count {
+			return newError("array pattern expects at least %d elements, got %d", count, length)
+		}
+		if node.Rest == nil && length != count {
+			return newError("array pattern expects %d elements, got %d", count, length)
+		}
+		for i, name := range node.Names {
+			if name.Value != "_" {
+				env.Set(name.Value, array.Elements[i])
+			}
+		}
+		if node.Rest != nil {
+			remaining := make([]object.Object, length-count)
+			copy(remaining, array.Elements[count:])
+			env.Set(node.Rest.Value, &object.Array{Elements: remaining})
+		}
+
+	case ast.HashPattern:
+		hash, ok := value.(*object.Hash)
+		if !ok {
+			return newError("cannot destructure %s as a hash", value.Type())
+		}
+		for _, name := range node.Names {
+			pair, ok := hash.Pairs[(&object.String{Value: name.Value}).HashKey()]
+			if !ok {
+				return newError("missing key %q in destructured hash", name.Value)
+			}
+			env.Set(name.Value, pair.Value)
+		}
+
+	case ast.StructPattern:
+		structObj, ok := value.(*object.Struct)
+		if !ok {
+			return newError("cannot destructure %s as %s", value.Type(), node.Struct.Value)
+		}
+		if structObj.TypeName != node.Struct.Value {
+			return newError("cannot destructure %s as %s", structObj.TypeName, node.Struct.Value)
+		}
+		for _, name := range node.Names {
+			field, ok := structObj.Fields[name.Value]
+			if !ok {
+				return newError("struct %s has no field %s", node.Struct.Value, name.Value)
+			}
+			env.Set(name.Value, field)
+		}
+	}
+
+	return nil
+}
+
 func evalStructStatement(node *ast.StructStatement, env *object.Environment) object.Object {
 	// Store struct definition as a special marker object in environment
 	// We'll use a simple approach: store field names in environment with prefix
@@ -318,6 +532,22 @@ func evalStructStatement(node *ast.StructStatement, env *object.Environment) obj
 		env.Set(fieldKey, &object.String{Value: fieldName})
 	}
 
+	// Methods take the receiver as an implicit first parameter `self`
+	for _, method := range node.Methods {
+		self := &ast.Identifier{Token: method.Token, Value: "self"}
+		var defaults []ast.Expression
+		if method.Function.Defaults != nil {
+			defaults = append([]ast.Expression{nil}, method.Function.Defaults...)
+		}
+		env.Set(structDefKey+"_method_"+method.Name.Value, &object.Function{
+			Parameters: append([]*ast.Identifier{self}, method.Function.Parameters...),
+			Defaults:   defaults,
+			Rest:       method.Function.Rest,
+			Body:       method.Function.Body,
+			Env:        env,
+		})
+	}
+
 	return NULL
 }
 
@@ -332,8 +562,13 @@ func evalEnumStatement(node *ast.EnumStatement, env *object.Environment) object.
 		variantKey := enumDefKey + "_variant_" + string(rune(i))
 		env.Set(variantKey, &object.String{Value: variant.Value})
 
-		// Also create enum value accessible as EnumName.VariantName
+		// Also create enum value accessible as EnumName.VariantName. Payload
+		// variants store a template with no value; calling it fills one in.
 		enumValKey := node.Name.Value + "." + variant.Value
+		if _, ok := node.Payloads[variant.Value]; ok {
+			env.Set(enumValKey, &object.EnumValue{TypeName: node.Name.Value, Tag: variant.Value})
+			continue
+		}
 		env.Set(enumValKey, &object.EnumValue{
 			TypeName: node.Name.Value,
 			Tag:      variant.Value,
@@ -344,6 +579,105 @@ func evalEnumStatement(node *ast.EnumStatement, env *object.Environment) object.
 	return NULL
 }
 
+// enumVariants rebuilds an enum's variant list from the environment keys
+// written by evalEnumStatement.
+func enumVariants(name string, env *object.Environment) (ast.EnumVariants, bool) {
+	enumDefKey := "__enum_" + name
+	if _, ok := env.Get(enumDefKey); !ok {
+		return ast.EnumVariants{}, false
+	}
+
+	variants := ast.EnumVariants{Payloads: map[string]bool{}}
+	for i := 0; ; i++ {
+		tag, ok := env.Get(enumDefKey + "_variant_" + string(rune(i)))
+		if !ok {
+			break
+		}
+		variants.Tags = append(variants.Tags, tag.Inspect())
+		if val, ok := env.Get(name + "." + tag.Inspect()); ok {
+			if enumVal, ok := val.(*object.EnumValue); ok && enumVal.Value == nil {
+				variants.Payloads[tag.Inspect()] = true
+			}
+		}
+	}
+	return variants, true
+}
+
+// enumVariantTemplate reports whether exp names an enum variant, returning
+// the value stored for it by evalEnumStatement.
+func enumVariantTemplate(exp ast.Expression, env *object.Environment) (*object.EnumValue, bool) {
+	field, ok := exp.(*ast.FieldExpression)
+	if !ok {
+		return nil, false
+	}
+	ident, ok := field.Left.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	val, ok := env.Get(ident.Value + "." + field.Field.Value)
+	if !ok {
+		return nil, false
+	}
+	template, ok := val.(*object.EnumValue)
+	return template, ok
+}
+
+func evalEnumConstructor(node *ast.CallExpression, template *object.EnumValue, env *object.Environment) object.Object {
+	if template.Value != nil {
+		return newError("enum variant %s.%s takes no payload", template.TypeName, template.Tag)
+	}
+	if len(node.Arguments) != 1 {
+		return newError("enum variant %s.%s takes 1 payload, got %d", template.TypeName, template.Tag, len(node.Arguments))
+	}
+
+	payload := Eval(node.Arguments[0], env)
+	if isError(payload) {
+		return payload
+	}
+	return &object.EnumValue{TypeName: template.TypeName, Tag: template.Tag, Value: payload}
+}
+
+func evalMatchExpression(node *ast.MatchExpression, env *object.Environment) object.Object {
+	lookup := func(enum string) (ast.EnumVariants, bool) { return enumVariants(enum, env) }
+	if _, msg := node.Check(lookup); msg != "" {
+		return newError("%s", msg)
+	}
+
+	subject := Eval(node.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, arm := range node.Arms {
+		armEnv := env
+		switch {
+		case arm.Tag != nil:
+			enumVal, ok := subject.(*object.EnumValue)
+			if !ok || enumVal.TypeName != arm.Enum.Value {
+				return newError("cannot match %s against %s", subject.Inspect(), arm.Enum.Value)
+			}
+			if enumVal.Tag != arm.Tag.Value {
+				continue
+			}
+			if arm.Binding != nil {
+				armEnv = object.NewEnclosedEnvironement(env)
+				armEnv.Set(arm.Binding.Value, enumVal.Value)
+			}
+		case arm.Literal != nil:
+			literal := Eval(arm.Literal, env)
+			if isError(literal) {
+				return literal
+			}
+			if !isTruthy(evalInfixExpression("==", subject, literal)) {
+				continue
+			}
+		}
+		return Eval(arm.Body, armEnv)
+	}
+
+	return NULL
+}
+
 func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
 	value := Eval(node.Value, env)
 	if isError(value) {
@@ -385,6 +719,9 @@ func evalFieldExpression(node *ast.FieldExpression, env *object.Environment) obj
 	if ident, ok := node.Left.(*ast.Identifier); ok {
 		enumValKey := ident.Value + "." + node.Field.Value
 		if val, ok := env.Get(enumValKey); ok {
+			if enumVal, ok := val.(*object.EnumValue); ok && enumVal.Value == nil {
+				return newError("enum variant %s requires a payload", enumValKey)
+			}
 			return val
 		}
 	}
@@ -400,6 +737,9 @@ func evalFieldExpression(node *ast.FieldExpression, env *object.Environment) obj
 		if val, ok := structObj.Fields[node.Field.Value]; ok {
 			return val
 		}
+		if method, ok := env.Get("__struct_" + structObj.TypeName + "_method_" + node.Field.Value); ok {
+			return &object.BoundMethod{Receiver: structObj, Name: node.Field.Value, Method: method}
+		}
 		return NULL
 	}
 
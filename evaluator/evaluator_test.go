@@ -140,6 +140,11 @@ func TestErrorHandling(t *testing.T) {
 		{"foobar", "identifier not found: foobar"},
 		{`"Hello" - "World"`, "unknown operator: STRING-STRING"},
 		{`{"name": "Monkey"}[fn(x) { x }];`, "unusable as hash key: FUNCTION"},
+		{"1 / 0", "division by zero"},
+		{"1 % 0", "modulo by zero"},
+		{"1 >> -2", "negative shift count: -2"},
+		{"2 ** 64", "2 ** 64 is out of range for INTEGER"},
+		{"1.5 | 1", "bitwise operators require integer operands: FLOAT|INTEGER"},
 	}
 
 	for _, tt := range tests {
@@ -244,6 +249,86 @@ func TestStringConcatenation(t *testing.T) {
 	}
 }
 
+func TestStringInterpolation(t *testing.T) {
+	input := `let n = 2; "n=${n}, sq=${n * n}\t${"x"}"`
+	evaluated := testEval(input)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+	if str.Value != "n=2, sq=4\tx" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"true && false", false},
+		{"false || true", true},
+		{"1 && 2", 2},
+		{"false || 3", 3},
+		{"false && foobar", false},
+		{"true || foobar", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		}
+	}
+}
+
+func TestExtendedOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"2 ** -2", 0.25},
+		{"7.5 % 2", 1.5},
+		{"1.5 * 2", 3.0},
+		{"-1.5 + 1", -0.5},
+		{"12 & 10", 8},
+		{"12 | 3", 15},
+		{"12 ^ 10", 6},
+		{"1 << 4", 16},
+		{"-16 >> 2", -4},
+		{"1 <= 1", true},
+		{"2 >= 3", false},
+		{"2.5 >= 2", true},
+		{`"a" <= "b"`, true},
+		{`"b" < "a"`, false},
+		{`"ab" > "a"`, true},
+		{`"a" >= "a"`, true},
+		{"let x = 5; x += 3; x", 8},
+		{"let x = 5; x -= 3; x", 2},
+		{"let x = 5; x *= 3; x", 15},
+		{"let x = 9; x /= 3; x", 3},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		}
+	}
+}
+
 func TestBuiltInFunctions(t *testing.T) {
 	tests := []struct {
 		input    string
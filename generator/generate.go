@@ -23,8 +23,9 @@ import (
 
 // Generate function takes a `string`, it's the path for the source code
 // password: optional password for encryption (empty string for deterministic encryption)
+// optimizationLevel: compiler optimization level (0 disables the optimizer)
 // privateKey: Ed25519 private key for signing (if nil, a temporary key is generated)
-func Generate(srcpath, dstpath, goos, goarch string, release bool, password string, mutationLevel int, mutationSeed int64, privateKey []byte) (error, errrs.ErrorType, []string) {
+func Generate(srcpath, dstpath, goos, goarch string, release bool, password string, mutationLevel int, mutationSeed int64, optimizationLevel int, privateKey []byte) (error, errrs.ErrorType, []string) {
 	data, err := os.ReadFile(srcpath)
 	if err != nil {
 		return err, errrs.ERROR, nil
@@ -49,7 +50,7 @@ func Generate(srcpath, dstpath, goos, goarch string, release bool, password stri
 		}
 	}
 
-	bytecode, err, errtype, errors := compile(data, password, mutationLevel, mutationSeed, privateKey)
+	bytecode, err, errtype, errors := compile(data, srcpath, password, mutationLevel, mutationSeed, optimizationLevel, privateKey)
 	if err != nil {
 		return err, errtype, errors
 	}
@@ -107,14 +108,14 @@ func loadSigningPrivateKeyFromEnv() ([]byte, error) {
 	return privateKey, nil
 }
 
-func compile(data []byte, password string, mutationLevel int, mutationSeed int64, privateKey []byte) ([]byte, error, errrs.ErrorType, []string) {
+func compile(data []byte, srcpath string, password string, mutationLevel int, mutationSeed int64, optimizationLevel int, privateKey []byte) ([]byte, error, errrs.ErrorType, []string) {
 	constants := []object.Object{}
 	symbolTable := compiler.NewSymbolTable()
 	for i, v := range builtin.Builtins {
 		symbolTable.DefineBuiltin(i, v.Name)
 	}
 
-	l := lexer.New(string(data))
+	l := lexer.NewWithFile(string(data), filepath.Base(srcpath))
 	p := parser.New(l)
 	program := p.ParseProgram()
 
@@ -123,13 +124,19 @@ func compile(data []byte, password string, mutationLevel int, mutationSeed int64
 	}
 
 	comp := compiler.NewWithState(symbolTable, constants)
+	if err := comp.SetSourceFile(srcpath); err != nil {
+		return nil, err, errrs.ERROR, nil
+	}
+	comp.EnableOptimization(optimizationLevel)
 	comp.EnableSecurityOpcodeInjection()
 	configureCompilerPolymorphism(comp, mutationLevel, mutationSeed)
 	if err := comp.Compile(program); err != nil {
 		return nil, err, errrs.COMPILER_ERROR, nil
 	}
+	errrs.PrintCompilerWarnings(os.Stderr, comp.Warnings())
 
-	encodedByteCode, err := encode(comp.ByteCode(), password, privateKey)
+	manifest := buildCapabilityManifest(symbolTable)
+	encodedByteCode, err := encode(comp.ByteCode(), password, privateKey, manifest)
 	if err != nil {
 		return nil, err, errrs.ERROR, nil
 	}
@@ -137,6 +144,20 @@ func compile(data []byte, password string, mutationLevel int, mutationSeed int64
 	return encodedByteCode, nil, "", nil
 }
 
+// buildCapabilityManifest records every capability-gated builtin the program references.
+func buildCapabilityManifest(symbolTable *compiler.SymbolTable) *security.CapabilityManifest {
+	entries := []security.CapabilityManifestEntry{}
+	for _, symbol := range symbolTable.ReferencedBuiltins() {
+		capability, ok := builtin.BuiltinCapability(symbol.Name)
+		if !ok {
+			continue
+		}
+		entries = append(entries, security.CapabilityManifestEntry{Builtin: symbol.Name, Capability: capability})
+	}
+
+	return security.NewCapabilityManifest(entries)
+}
+
 func configureCompilerPolymorphism(comp *compiler.Compiler, mutationLevel int, mutationSeed int64) {
 	if mutationLevel <= 0 {
 		return
@@ -152,7 +173,7 @@ func resolvePolymorphismSeed(seed int64) int64 {
 	return time.Now().UnixNano()
 }
 
-func encode(compByteCode *compiler.ByteCode, password string, privateKey []byte) ([]byte, error) {
+func encode(compByteCode *compiler.ByteCode, password string, privateKey []byte, manifest *security.CapabilityManifest) ([]byte, error) {
 	var content bytes.Buffer
 
 	// Polymorphic marker is compile-time metadata and must not be executed by VM.
@@ -169,10 +190,10 @@ func encode(compByteCode *compiler.ByteCode, password string, privateKey []byte)
 	}
 
 	byteCode := content.Bytes()
-	return encryptCode(byteCode, password, privateKey)
+	return encryptCode(byteCode, password, privateKey, manifest)
 }
 
-func encryptCode(b64ByteCode []byte, password string, privateKey []byte) ([]byte, error) {
+func encryptCode(b64ByteCode []byte, password string, privateKey []byte, manifest *security.CapabilityManifest) ([]byte, error) {
 	// Apply secure XOR (replaces insecure math/rand-based XOR)
 	xorByteCode, err := security.SecureXOREncrypt(b64ByteCode)
 	if err != nil {
@@ -186,8 +207,8 @@ func encryptCode(b64ByteCode []byte, password string, privateKey []byte) ([]byte
 		return nil, err
 	}
 
-	// Sign with Ed25519 (replaces insecure MD5)
-	signedCode, err := security.SignCode(encodedByteCode, privateKey)
+	// Sign with Ed25519 (replaces insecure MD5); the capability manifest rides alongside the payload
+	signedCode, err := security.SignCodeWithManifest(encodedByteCode, manifest, privateKey)
 	if err != nil {
 		return nil, err
 	}
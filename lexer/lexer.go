@@ -12,16 +12,45 @@ type Lexer struct {
 	position     int // current character index
 	readPosition int // next character index
 	ch           rune
+
+	file   string // source file name used in token positions
+	line   int    // line of the current character
+	column int    // column of the current character
+
+	comments []token.Token // comment trivia in source order
+	errors   []string
 }
 
 // New function initializes our lexer, takes input as a string
 // that input is the source code
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewWithFile(input, "")
+}
+
+// NewWithFile initializes a lexer whose token positions carry the given file name
+func NewWithFile(input, file string) *Lexer {
+	return NewAt(input, token.Position{File: file, Line: 1, Column: 1})
+}
+
+// NewAt initializes a lexer for a fragment of a larger source, such as a string
+// interpolation, so token positions point into the enclosing file
+func NewAt(input string, pos token.Position) *Lexer {
+	l := &Lexer{input: input, file: pos.File, line: pos.Line, column: pos.Column - 1}
 	l.readRune()
 	return l
 }
 
+// Comments returns the comments skipped so far, with their positions.
+// Literals keep the comment delimiters so tools can reproduce the source.
+func (l *Lexer) Comments() []token.Token { return l.comments }
+
+// Errors returns lexical errors such as unterminated block comments
+func (l *Lexer) Errors() []string { return l.errors }
+
+func (l *Lexer) currentPosition() token.Position {
+	return token.Position{File: l.file, Line: l.line, Column: l.column}
+}
+
 // NextToken method makes use of lexer data structure
 // Uses switch cases to identify whether a certain character
 // in source code is legal or not. Zetsu language only
@@ -30,32 +59,61 @@ func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 
 	l.skipWhiteSpace()
+	pos := l.currentPosition()
 
 	switch l.ch {
 	case '=':
-		if l.peekRune() == '=' {
-			ch := string(l.ch)
+		switch l.peekRune() {
+		case '=':
 			l.readRune()
-			tok = token.Token{Type: token.EQUALITY, Literal: ch + string(l.ch)}
-		} else {
+			tok = token.Token{Type: token.EQUALITY, Literal: "=="}
+		case '>':
+			l.readRune()
+			tok = token.Token{Type: token.ARROW, Literal: "=>"}
+		default:
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		tok = l.newCompoundToken(token.PLUS, token.PLUS_ASSIGN)
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		tok = l.newCompoundToken(token.MINUS, token.MINUS_ASSIGN)
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		if l.peekRune() == '*' {
+			l.readRune()
+			tok = token.Token{Type: token.POWER, Literal: "**"}
+		} else {
+			tok = l.newCompoundToken(token.ASTERISK, token.ASTERISK_ASSIGN)
+		}
 	case '/':
-		tok = newToken(token.FSLASH, l.ch)
+		tok = l.newCompoundToken(token.FSLASH, token.FSLASH_ASSIGN)
 	case '\\':
 		tok = newToken(token.FSLASH, l.ch)
 	case '%':
 		tok = newToken(token.MODULO, l.ch)
+	case '^':
+		tok = newToken(token.BITXOR, l.ch)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		switch l.peekRune() {
+		case '=':
+			l.readRune()
+			tok = token.Token{Type: token.LTE, Literal: "<="}
+		case '<':
+			l.readRune()
+			tok = token.Token{Type: token.SHL, Literal: "<<"}
+		default:
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		switch l.peekRune() {
+		case '=':
+			l.readRune()
+			tok = token.Token{Type: token.GTE, Literal: ">="}
+		case '>':
+			l.readRune()
+			tok = token.Token{Type: token.SHR, Literal: ">>"}
+		default:
+			tok = newToken(token.GT, l.ch)
+		}
 	case '!':
 		if l.peekRune() == '=' {
 			ch := string(l.ch)
@@ -64,6 +122,20 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = newToken(token.BANG, l.ch)
 		}
+	case '&':
+		if l.peekRune() == '&' {
+			l.readRune()
+			tok = token.Token{Type: token.AND, Literal: "&&"}
+		} else {
+			tok = newToken(token.BITAND, l.ch)
+		}
+	case '|':
+		if l.peekRune() == '|' {
+			l.readRune()
+			tok = token.Token{Type: token.OR, Literal: "||"}
+		} else {
+			tok = newToken(token.BITOR, l.ch)
+		}
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -81,18 +153,30 @@ func (l *Lexer) NextToken() token.Token {
 	case ':':
 		tok = newToken(token.COLON, l.ch)
 	case '.':
-		tok = newToken(token.DOT, l.ch)
+		if l.peekRune() == '.' {
+			l.readRune()
+			if l.peekRune() == '.' {
+				l.readRune()
+				tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+			} else {
+				tok = token.Token{Type: token.RANGE, Literal: ".."}
+			}
+		} else {
+			tok = newToken(token.DOT, l.ch)
+		}
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case 0:
 		tok = newToken(token.EOF, l.ch)
 	case '"':
-		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		tok = l.readString(pos)
+	case '`':
+		tok = l.readRawString(pos)
 	default:
-		if unicode.IsLetter(l.ch) {
+		if unicode.IsLetter(l.ch) || l.ch == '_' {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Pos = pos
 			return tok
 		} else if unicode.IsNumber(l.ch) {
 			val, isFloat := l.readNumber()
@@ -102,16 +186,29 @@ func (l *Lexer) NextToken() token.Token {
 			} else {
 				tok.Type = token.INT
 			}
+			tok.Pos = pos
 			return tok
 		}
 		tok = newToken(token.ILLEGAL, l.ch)
 	}
 
 	l.readRune()
+	tok.Pos = pos
 
 	return tok
 }
 
+// newCompoundToken returns the compound assignment form of an arithmetic
+// operator when it is immediately followed by '='
+func (l *Lexer) newCompoundToken(op, compound token.TokenType) token.Token {
+	if l.peekRune() == '=' {
+		ch := string(l.ch)
+		l.readRune()
+		return token.Token{Type: compound, Literal: ch + "="}
+	}
+	return newToken(op, l.ch)
+}
+
 func (l *Lexer) prevRune() rune {
 	var prev rune
 	if l.readPosition >= len(l.input) {
@@ -122,6 +219,12 @@ func (l *Lexer) prevRune() rune {
 	return prev
 }
 func (l *Lexer) readRune() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -141,17 +244,6 @@ func (l *Lexer) nextRune() rune {
 	return next
 }
 
-func (l *Lexer) readString() string {
-	position := l.position + 1
-	for {
-		l.readRune()
-		if l.ch == '"' || l.ch == 0 {
-			break
-		}
-	}
-	return l.input[position:l.position]
-}
-
 func newToken(tokenType token.TokenType, ch rune) token.Token {
 	var tok token.Token
 
@@ -174,12 +266,13 @@ func (l *Lexer) readNumber() (string, bool) {
 	flag := false
 	for unicode.IsDigit(l.ch) || l.ch == '.' {
 		if l.ch == '.' {
-			flag = true
 			prev := l.prevRune()
 			next := l.nextRune()
 			if !(unicode.IsDigit(prev) && unicode.IsDigit(next)) {
+				// not a fraction, e.g. the range operator in 0..n
 				break
 			}
+			flag = true
 		}
 
 		l.readRune()
@@ -187,8 +280,54 @@ func (l *Lexer) readNumber() (string, bool) {
 	return l.input[position:l.position], flag
 }
 
+// skipWhiteSpace skips whitespace and comments, recording comments as trivia
 func (l *Lexer) skipWhiteSpace() {
-	for unicode.IsSpace(l.ch) {
+	for {
+		switch {
+		case unicode.IsSpace(l.ch):
+			l.readRune()
+		case l.ch == '/' && l.peekRune() == '/':
+			l.skipLineComment()
+		case l.ch == '/' && l.peekRune() == '*':
+			l.skipBlockComment()
+		default:
+			return
+		}
+	}
+}
+
+func (l *Lexer) skipLineComment() {
+	pos := l.currentPosition()
+	start := l.position
+	for l.ch != '\n' && l.ch != 0 {
+		l.readRune()
+	}
+	l.comments = append(l.comments, token.Token{Type: token.COMMENT, Literal: l.input[start:l.position], Pos: pos})
+}
+
+// skipBlockComment consumes a /* */ comment; block comments nest
+func (l *Lexer) skipBlockComment() {
+	pos := l.currentPosition()
+	start := l.position
+	depth := 0
+	for {
+		switch {
+		case l.ch == 0:
+			l.errors = append(l.errors, pos.String()+": unterminated block comment")
+			l.comments = append(l.comments, token.Token{Type: token.COMMENT, Literal: l.input[start:l.position], Pos: pos})
+			return
+		case l.ch == '/' && l.peekRune() == '*':
+			depth++
+			l.readRune()
+		case l.ch == '*' && l.peekRune() == '/':
+			depth--
+			l.readRune()
+			if depth == 0 {
+				l.readRune()
+				l.comments = append(l.comments, token.Token{Type: token.COMMENT, Literal: l.input[start:l.position], Pos: pos})
+				return
+			}
+		}
 		l.readRune()
 	}
 }
@@ -16,7 +16,7 @@ func TestNextToken(t *testing.T) {
 	};
 
 	let result = add(five, ten);
-	!-/*5; 5 < 10 > 5;
+	!-/ *5; 5 < 10 > 5;
 
 	if (5 < 10) { return true; } else { return false; }
 
@@ -218,3 +218,243 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestNextTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  x + 10"
+
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"let", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"x", 2, 3},
+		{"+", 2, 5},
+		{"10", 2, 7},
+	}
+
+	l := NewWithFile(input, "main.mut")
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected = %q, got = %q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Pos.Line != tt.expectedLine || tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong. expected = %d:%d, got = %d:%d", i, tt.expectedLine, tt.expectedColumn, tok.Pos.Line, tok.Pos.Column)
+		}
+		if tok.Pos.File != "main.mut" {
+			t.Fatalf("tests[%d] - file wrong. got = %q", i, tok.Pos.File)
+		}
+	}
+}
+
+func TestNextTokenSkipsComments(t *testing.T) {
+	input := `// leading comment
+let x = 5; // trailing
+/* block /* nested */ still comment */ x / 2;`
+
+	expected := []string{"let", "x", "=", "5", ";", "x", "/", "2", ";", "\x00"}
+
+	l := New(input)
+	for i, literal := range expected {
+		tok := l.NextToken()
+		if tok.Literal != literal {
+			t.Fatalf("tests[%d] - literal wrong. expected = %q, got = %q", i, literal, tok.Literal)
+		}
+	}
+
+	comments := l.Comments()
+	if len(comments) != 3 {
+		t.Fatalf("expected 3 comments, got %d", len(comments))
+	}
+	if comments[0].Literal != "// leading comment" || comments[0].Pos.Line != 1 || comments[0].Pos.Column != 1 {
+		t.Fatalf("unexpected first comment: %+v", comments[0])
+	}
+	if comments[1].Literal != "// trailing" || comments[1].Pos.Line != 2 || comments[1].Pos.Column != 12 {
+		t.Fatalf("unexpected second comment: %+v", comments[1])
+	}
+	if comments[2].Literal != "/* block /* nested */ still comment */" || comments[2].Pos.Line != 3 {
+		t.Fatalf("unexpected block comment: %+v", comments[2])
+	}
+	if len(l.Errors()) != 0 {
+		t.Fatalf("unexpected lexer errors: %v", l.Errors())
+	}
+}
+
+func TestUnterminatedBlockCommentReportsError(t *testing.T) {
+	l := New("let x = 1;\n/* never /* closed */")
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+	}
+
+	errors := l.Errors()
+	if len(errors) != 1 || errors[0] != "2:1: unterminated block comment" {
+		t.Fatalf("unexpected lexer errors: %v", errors)
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{`"plain"`, token.STRING, "plain"},
+		{`"tab\there\n"`, token.STRING, "tab\there\n"},
+		{`"quote \" and \\ and \$"`, token.STRING, `quote " and \ and $`},
+		{`"\u{1F600}"`, token.STRING, "\U0001F600"},
+		{"`raw \\n ${x}\nsecond`", token.STRING, "raw \\n ${x}\nsecond"},
+		{`"a ${b + "}"} c"`, token.TEMPLATE, `a ${b + "}"} c`},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected = %s %q, got = %s %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Fatalf("tests[%d] - expected EOF after literal, got %s %q", i, next.Type, next.Literal)
+		}
+		if len(l.Errors()) != 0 {
+			t.Fatalf("tests[%d] - unexpected errors: %v", i, l.Errors())
+		}
+	}
+}
+
+func TestStringLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let s = \"open", "1:9: unterminated string literal"},
+		{"\"bad \\q\"", "1:6: unknown escape sequence \\q"},
+		{"`open", "1:1: unterminated raw string literal"},
+		{"\"${x\"", "1:2: unterminated string interpolation"},
+		{"\"\\u{41\"; \"x}\"", "1:2: unterminated unicode escape"},
+		{"\"\\u{41\n}\"", "1:2: unterminated unicode escape"},
+		{"\"\\u{0000041}\"", "1:2: invalid unicode escape, expected at most 6 hex digits"},
+		{"\"\\u{110000}\"", "1:2: invalid unicode escape \\u{110000}"},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+		errors := l.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Fatalf("tests[%d] - expected first error %q, got %v", i, tt.expected, errors)
+		}
+	}
+}
+
+func TestUnterminatedUnicodeEscapeKeepsClosingQuote(t *testing.T) {
+	l := New(`"\u{41"; "x}"`)
+	expected := []struct {
+		tokenType token.TokenType
+		literal   string
+	}{
+		{token.STRING, ""},
+		{token.SEMICOLON, ";"},
+		{token.STRING, "x}"},
+	}
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt.tokenType || tok.Literal != tt.literal {
+			t.Fatalf("tests[%d] - expected %s %q, got %s %q", i, tt.tokenType, tt.literal, tok.Type, tok.Literal)
+		}
+	}
+	if tok := l.NextToken(); tok.Type != token.EOF {
+		t.Fatalf("expected EOF, got %s %q", tok.Type, tok.Literal)
+	}
+	if len(l.Errors()) != 1 {
+		t.Fatalf("expected one error, got %v", l.Errors())
+	}
+}
+
+func TestLogicalOperatorTokens(t *testing.T) {
+	l := New("a && b || c")
+	expected := []token.TokenType{token.IDENT, token.AND, token.IDENT, token.OR, token.IDENT, token.EOF}
+	for i, tokenType := range expected {
+		if tok := l.NextToken(); tok.Type != tokenType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected = %q, got = %q", i, tokenType, tok.Type)
+		}
+	}
+}
+
+func TestArithmeticAndBitwiseOperatorTokens(t *testing.T) {
+	input := `a <= b >= c ** d % e & f | g ^ h << i >> j; x += 1; x -= 1; x *= 2; x /= 2; a < b > c * d`
+	expected := []struct {
+		tokenType token.TokenType
+		literal   string
+	}{
+		{token.IDENT, "a"}, {token.LTE, "<="}, {token.IDENT, "b"}, {token.GTE, ">="},
+		{token.IDENT, "c"}, {token.POWER, "**"}, {token.IDENT, "d"}, {token.MODULO, "%"},
+		{token.IDENT, "e"}, {token.BITAND, "&"}, {token.IDENT, "f"}, {token.BITOR, "|"},
+		{token.IDENT, "g"}, {token.BITXOR, "^"}, {token.IDENT, "h"}, {token.SHL, "<<"},
+		{token.IDENT, "i"}, {token.SHR, ">>"}, {token.IDENT, "j"}, {token.SEMICOLON, ";"},
+		{token.IDENT, "x"}, {token.PLUS_ASSIGN, "+="}, {token.INT, "1"}, {token.SEMICOLON, ";"},
+		{token.IDENT, "x"}, {token.MINUS_ASSIGN, "-="}, {token.INT, "1"}, {token.SEMICOLON, ";"},
+		{token.IDENT, "x"}, {token.ASTERISK_ASSIGN, "*="}, {token.INT, "2"}, {token.SEMICOLON, ";"},
+		{token.IDENT, "x"}, {token.FSLASH_ASSIGN, "/="}, {token.INT, "2"}, {token.SEMICOLON, ";"},
+		{token.IDENT, "a"}, {token.LT, "<"}, {token.IDENT, "b"}, {token.GT, ">"},
+		{token.IDENT, "c"}, {token.ASTERISK, "*"}, {token.IDENT, "d"}, {token.EOF, "\x00"},
+	}
+
+	l := New(input)
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt.tokenType || tok.Literal != tt.literal {
+			t.Fatalf("tests[%d] - wrong token. expected=%q %q, got=%q %q", i, tt.tokenType, tt.literal, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestRangeAndInTokens(t *testing.T) {
+	input := `for (i in 0..10) { p.x; 1.5 } ...rest`
+	expected := []struct {
+		tokenType token.TokenType
+		literal   string
+	}{
+		{token.FOR, "for"}, {token.LPAREN, "("}, {token.IDENT, "i"}, {token.IN, "in"},
+		{token.INT, "0"}, {token.RANGE, ".."}, {token.INT, "10"}, {token.RPAREN, ")"},
+		{token.LBRACE, "{"}, {token.IDENT, "p"}, {token.DOT, "."}, {token.IDENT, "x"},
+		{token.SEMICOLON, ";"}, {token.FLOAT, "1.5"}, {token.RBRACE, "}"},
+		{token.ELLIPSIS, "..."}, {token.IDENT, "rest"},
+	}
+
+	l := New(input)
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt.tokenType || tok.Literal != tt.literal {
+			t.Fatalf("tests[%d] - wrong token. expected=%q %q, got=%q %q", i, tt.tokenType, tt.literal, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestMatchTokens(t *testing.T) {
+	input := `match (x) { E.A(_v) => 1, _ => a == b = c }`
+	expected := []struct {
+		tokenType token.TokenType
+		literal   string
+	}{
+		{token.MATCH, "match"}, {token.LPAREN, "("}, {token.IDENT, "x"}, {token.RPAREN, ")"},
+		{token.LBRACE, "{"}, {token.IDENT, "E"}, {token.DOT, "."}, {token.IDENT, "A"},
+		{token.LPAREN, "("}, {token.IDENT, "_v"}, {token.RPAREN, ")"}, {token.ARROW, "=>"},
+		{token.INT, "1"}, {token.COMMA, ","}, {token.IDENT, "_"}, {token.ARROW, "=>"},
+		{token.IDENT, "a"}, {token.EQUALITY, "=="}, {token.IDENT, "b"}, {token.ASSIGN, "="},
+		{token.IDENT, "c"}, {token.RBRACE, "}"},
+	}
+
+	l := New(input)
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt.tokenType || tok.Literal != tt.literal {
+			t.Fatalf("tests[%d] - wrong token. expected=%q %q, got=%q %q", i, tt.tokenType, tt.literal, tok.Type, tok.Literal)
+		}
+	}
+}
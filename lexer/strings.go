@@ -0,0 +1,266 @@
+package lexer
+
+import (
+	"fmt"
+	"mutant/token"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// StringPart is one segment of a double-quoted string literal.
+// Text segments hold decoded characters; expression segments hold the
+// source between `${` and `}` so the parser can lower them to concatenation.
+type StringPart struct {
+	Text   string
+	Source string
+	IsExpr bool
+	Pos    token.Position // position of Source when IsExpr is true
+}
+
+// stringScanner walks string literal bodies while tracking source positions
+type stringScanner struct {
+	src    string
+	i      int
+	pos    token.Position
+	errors []string
+}
+
+func (s *stringScanner) peek(offset int) byte {
+	if s.i+offset >= len(s.src) {
+		return 0
+	}
+	return s.src[s.i+offset]
+}
+
+func (s *stringScanner) advance(n int) {
+	for ; n > 0 && s.i < len(s.src); n-- {
+		if s.src[s.i] == '\n' {
+			s.pos.Line++
+			s.pos.Column = 0
+		}
+		s.pos.Column++
+		s.i++
+	}
+}
+
+func (s *stringScanner) errorf(pos token.Position, format string, args ...interface{}) {
+	s.errors = append(s.errors, pos.String()+": "+fmt.Sprintf(format, args...))
+}
+
+// scanQuoted scans a double-quoted body up to its closing quote, or to the end of
+// src when untilEOF is set. It reports whether a closing quote was found.
+func (s *stringScanner) scanQuoted(untilEOF bool) ([]StringPart, bool) {
+	parts := []StringPart{}
+	var text strings.Builder
+
+	flush := func() {
+		if text.Len() > 0 {
+			parts = append(parts, StringPart{Text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for {
+		if s.i >= len(s.src) {
+			flush()
+			return parts, untilEOF
+		}
+
+		ch := s.src[s.i]
+		switch {
+		case ch == '"' && !untilEOF:
+			flush()
+			s.advance(1)
+			return parts, true
+		case ch == '\\':
+			s.scanEscape(&text)
+		case ch == '$' && s.peek(1) == '{':
+			flush()
+			start := s.pos
+			s.advance(2)
+			exprPos := s.pos
+			exprStart := s.i
+			if !s.skipInterpolation() {
+				s.errorf(start, "unterminated string interpolation")
+				return parts, false
+			}
+			source := s.src[exprStart:s.i]
+			if strings.TrimSpace(source) == "" {
+				s.errorf(start, "empty string interpolation")
+			}
+			parts = append(parts, StringPart{Source: source, IsExpr: true, Pos: exprPos})
+			s.advance(1)
+		default:
+			text.WriteByte(ch)
+			s.advance(1)
+		}
+	}
+}
+
+// maxUnicodeDigits is the most hex digits a \u{...} escape may hold
+const maxUnicodeDigits = 6
+
+// scanEscape decodes the escape sequence at the scanner position into text
+func (s *stringScanner) scanEscape(text *strings.Builder) {
+	pos := s.pos
+	next := s.peek(1)
+	switch next {
+	case 'n':
+		text.WriteByte('\n')
+	case 't':
+		text.WriteByte('\t')
+	case 'r':
+		text.WriteByte('\r')
+	case '0':
+		text.WriteByte(0)
+	case '"', '\'', '\\', '$', '`':
+		text.WriteByte(next)
+	case 'u':
+		if s.peek(2) != '{' {
+			s.errorf(pos, "invalid unicode escape, expected \\u{...}")
+			s.advance(2)
+			return
+		}
+		// The closing brace must come within the digits; the search stops at
+		// the string's closing quote and the end of the line.
+		start := s.i + 3
+		end := start
+		stop := func(i int) bool { return i >= len(s.src) || strings.IndexByte("}\"\n", s.src[i]) >= 0 }
+		for !stop(end) && end-start < maxUnicodeDigits {
+			end++
+		}
+		if end >= len(s.src) || s.src[end] != '}' {
+			if !stop(end) {
+				s.errorf(pos, "invalid unicode escape, expected at most %d hex digits", maxUnicodeDigits)
+			} else {
+				s.errorf(pos, "unterminated unicode escape")
+			}
+			s.advance(end - s.i)
+			return
+		}
+		digits := s.src[start:end]
+		value, err := strconv.ParseUint(digits, 16, 32)
+		if err != nil || len(digits) == 0 || !utf8.ValidRune(rune(value)) {
+			s.errorf(pos, "invalid unicode escape \\u{%s}", digits)
+		} else {
+			text.WriteRune(rune(value))
+		}
+		s.advance(end + 1 - s.i)
+		return
+	case 0:
+		s.advance(1)
+		return
+	default:
+		s.errorf(pos, "unknown escape sequence \\%c", next)
+	}
+	s.advance(2)
+}
+
+// skipInterpolation moves to the `}` closing an interpolation, skipping nested
+// braces and string literals inside the expression
+func (s *stringScanner) skipInterpolation() bool {
+	depth := 0
+	for s.i < len(s.src) {
+		switch s.src[s.i] {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return true
+			}
+			depth--
+		case '"':
+			// Nested literals are diagnosed when the expression itself is lexed.
+			reported := len(s.errors)
+			s.advance(1)
+			_, ok := s.scanQuoted(false)
+			s.errors = s.errors[:reported]
+			if !ok {
+				return false
+			}
+			continue
+		case '`':
+			s.advance(1)
+			if !s.skipRaw() {
+				return false
+			}
+			continue
+		}
+		s.advance(1)
+	}
+	return false
+}
+
+// skipRaw moves past the closing backtick of a raw string
+func (s *stringScanner) skipRaw() bool {
+	for s.i < len(s.src) {
+		if s.src[s.i] == '`' {
+			s.advance(1)
+			return true
+		}
+		s.advance(1)
+	}
+	return false
+}
+
+// ScanStringParts splits the raw body of an interpolated string literal
+// (the text between its quotes) into decoded text and expression segments.
+// pos is the position of the first character of raw.
+func ScanStringParts(raw string, pos token.Position) ([]StringPart, []string) {
+	s := &stringScanner{src: raw, pos: pos}
+	parts, _ := s.scanQuoted(true)
+	return parts, s.errors
+}
+
+// readString reads a double-quoted literal starting at the opening quote.
+// Plain strings become STRING tokens with escapes decoded; strings containing
+// `${...}` become TEMPLATE tokens whose literal is the raw body.
+func (l *Lexer) readString(pos token.Position) token.Token {
+	start := l.position + 1
+	bodyPos := pos
+	bodyPos.Column++
+
+	s := &stringScanner{src: l.input, i: start, pos: bodyPos}
+	parts, terminated := s.scanQuoted(false)
+	l.errors = append(l.errors, s.errors...)
+
+	end := s.i
+	if terminated {
+		end-- // leave the closing quote for NextToken to consume
+	} else {
+		l.errors = append(l.errors, pos.String()+": unterminated string literal")
+	}
+	for l.position < end {
+		l.readRune()
+	}
+
+	for _, part := range parts {
+		if part.IsExpr {
+			return token.Token{Type: token.TEMPLATE, Literal: l.input[start:end], Pos: pos}
+		}
+	}
+
+	var text strings.Builder
+	for _, part := range parts {
+		text.WriteString(part.Text)
+	}
+	return token.Token{Type: token.STRING, Literal: text.String(), Pos: pos}
+}
+
+// readRawString reads a backtick literal; raw strings keep their bytes verbatim
+// and may span lines
+func (l *Lexer) readRawString(pos token.Position) token.Token {
+	start := l.position + 1
+	for {
+		l.readRune()
+		if l.ch == '`' {
+			break
+		}
+		if l.ch == 0 {
+			l.errors = append(l.errors, pos.String()+": unterminated raw string literal")
+			return token.Token{Type: token.STRING, Literal: l.input[start:], Pos: pos}
+		}
+	}
+	return token.Token{Type: token.STRING, Literal: l.input[start:l.position], Pos: pos}
+}
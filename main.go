@@ -12,6 +12,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -22,6 +23,7 @@ const (
 	RELEASECMD = "release"
 	GENCMD     = "gen"
 	RUNCMD     = "run"
+	INFOCMD    = "info"
 	VERSION    = "Version: 2.1.0"
 )
 
@@ -45,6 +47,7 @@ func main() {
 					secureMode = false
 				}
 				configureSecurityLogging(os.Args, devMode)
+				configureBuiltinCapabilities(os.Args)
 				if password == "" && devMode {
 					password = mutil.GetPwd()
 				}
@@ -95,12 +98,20 @@ func main() {
 			fmt.Println("\t\tOptional: --security-log-level <none|error|info|debug|trace> (active in --dev mode).")
 			fmt.Println("\t\tAlias: --log-level <none|error|info|debug|trace> (active in --dev mode).")
 			fmt.Println("\t\tOptional: --signer-auth to enforce trusted signer key verification in secure mode.")
+			fmt.Println("\t\tOptional: --capabilities <all|none|command_exec,filesystem,network> to grant risky builtin groups.")
+			fmt.Println("\t\tDefaults to MUTANT_BUILTIN_CAPABILITIES, then the protection profile (minimal allows all).")
 			fmt.Println("\t\tDefault is --secure (fail-closed security behavior).")
 			fmt.Println()
+			fmt.Println("\tmutant info [<FILENAME>.mu | <BINARY>]")
+			fmt.Println("\t\tShow the signer key and capability manifest of compiled bytecode or a standalone binary.")
+			fmt.Println("\t\tWithout a file, inspects the running executable's embedded payload.")
+			fmt.Println("\t\tOptional: --capabilities <list> to compare against a specific grant.")
+			fmt.Println()
 			fmt.Println("\tmutant gen <FILENAME>.mut [-password|-pwd]")
 			fmt.Println("\t\tCompile mutant source code into bytecode with optional password.")
 			fmt.Println("\t\tOptional: -mutation <0-10> to control polymorphism level (default: 3).")
 			fmt.Println("\t\tOptional: -seed <INT64> to set polymorphism seed (default: current timestamp).")
+			fmt.Println("\t\tOptional: -O <0-2> to optimize: 1 folds constants and drops dead code, 2 also threads jumps (default: 0).")
 			fmt.Println("\tmutant gen --release-assets [-out <DIR>]")
 			fmt.Println("\t\tGenerate embedded release runtime assets files (index + data/*.bin).")
 			fmt.Println("\t\tAlso supported: mutant gen assets [-out <DIR>].")
@@ -113,6 +124,7 @@ func main() {
 			fmt.Println("\t\tOptional: -mutation <0-10> to control polymorphism level (default: 3).")
 			fmt.Println("\t\tOptional: -seed <INT64> to set polymorphism seed (default: current timestamp).")
 			fmt.Println("\t\tIf omitted, deterministic compatibility mode (weaker obfuscation) is used.")
+			fmt.Println("\t\tOptional: -O <0-2> to set the optimization level (default: 0).")
 			fmt.Println("")
 			fmt.Println("\t\tPossible values for -os: darwin | linux | windows.")
 			fmt.Println("\t\tPossible values for -arch: amd64 | arm64 | arm | 386 | x86. (386 & x86 have same meaning here)")
@@ -136,7 +148,7 @@ func main() {
 
 		if strings.HasSuffix(os.Args[1], global.MutantSourceCodeFileExtention) {
 			pwd := mutil.GetPwd()
-			cli.CompileCode(os.Args[1], "", "", false, pwd, defaultPolymorphicLevel, time.Now().UnixNano())
+			cli.CompileCode(os.Args[1], "", "", false, pwd, defaultPolymorphicLevel, time.Now().UnixNano(), 0)
 			return
 		}
 
@@ -147,6 +159,18 @@ func main() {
 		}
 	}
 
+	if len(os.Args) >= 2 && os.Args[1] == INFOCMD {
+		src, err := prepareInfo(os.Args)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		configureBuiltinCapabilities(os.Args)
+		cli.ShowInfo(src)
+		return
+	}
+
 	// General CLI: support password for compile/run (non-release, non-gen)
 	if len(os.Args) >= 2 && os.Args[1] != RELEASECMD && os.Args[1] != GENCMD {
 		// Try to find a file argument anywhere in the args
@@ -168,8 +192,9 @@ func main() {
 				secureMode = false
 			}
 			configureSecurityLogging(os.Args, devMode)
+			configureBuiltinCapabilities(os.Args)
 			if strings.HasSuffix(fileArg, global.MutantSourceCodeFileExtention) {
-				cli.CompileCode(fileArg, "", "", false, password, defaultPolymorphicLevel, time.Now().UnixNano())
+				cli.CompileCode(fileArg, "", "", false, password, defaultPolymorphicLevel, time.Now().UnixNano(), 0)
 				return
 			}
 			if strings.HasSuffix(fileArg, global.MutantByteCodeCompiledFileExtension) {
@@ -195,26 +220,26 @@ func main() {
 	}
 
 	if len(os.Args) >= 2 && (os.Args[1] == GENCMD || os.Args[1] == RUNCMD) {
-		src, password, mutationLevel, mutationSeed, err := prepareGenRun(os.Args)
+		src, password, mutationLevel, mutationSeed, optimizationLevel, err := prepareGenRun(os.Args)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
 
 		fmt.Println("Generating Bytecode....")
-		cli.CompileCode(src, "", "", false, password, mutationLevel, mutationSeed)
+		cli.CompileCode(src, "", "", false, password, mutationLevel, mutationSeed, optimizationLevel)
 		return
 	}
 
 	if len(os.Args) >= 2 && os.Args[1] == RELEASECMD {
-		src, goos, goarch, password, mutationLevel, mutationSeed, err := prepareRelease(os.Args)
+		src, goos, goarch, password, mutationLevel, mutationSeed, optimizationLevel, err := prepareRelease(os.Args)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
 
 		fmt.Println("Compiling Release Build....")
-		cli.CompileCode(src, goos, goarch, true, password, mutationLevel, mutationSeed)
+		cli.CompileCode(src, goos, goarch, true, password, mutationLevel, mutationSeed, optimizationLevel)
 		return
 	}
 }
@@ -226,7 +251,7 @@ func shouldAttemptEmbeddedRun(args []string) bool {
 
 	for _, arg := range args[1:] {
 		switch arg {
-		case RELEASECMD, GENCMD, RUNCMD, "-h", "--help", "-v", "--version", "-em", "--enableMacros":
+		case RELEASECMD, GENCMD, RUNCMD, INFOCMD, "-h", "--help", "-v", "--version", "-em", "--enableMacros":
 			return false
 		}
 
@@ -295,6 +320,34 @@ func configureSecurityLogging(args []string, devMode bool) {
 	}
 }
 
+// extractCapabilitiesArg scans args for --capabilities <list> or --capabilities=<list>.
+func extractCapabilitiesArg(args []string) (string, bool) {
+	for i := 0; i < len(args)-1; i++ {
+		if args[i] == "--capabilities" || args[i] == "-capabilities" {
+			return strings.TrimSpace(args[i+1]), true
+		}
+	}
+	for i := 0; i < len(args); i++ {
+		if strings.HasPrefix(args[i], "--capabilities=") {
+			return strings.TrimSpace(strings.TrimPrefix(args[i], "--capabilities=")), true
+		}
+	}
+	return "", false
+}
+
+// configureBuiltinCapabilities lets the CLI flag override MUTANT_BUILTIN_CAPABILITIES.
+// An empty flag value is treated as an explicit deny-all.
+func configureBuiltinCapabilities(args []string) {
+	capabilities, ok := extractCapabilitiesArg(args)
+	if !ok {
+		return
+	}
+	if capabilities == "" {
+		capabilities = "none"
+	}
+	_ = os.Setenv(security.BuiltinCapabilitiesEnv, capabilities)
+}
+
 // extractSignerAuthArg scans args for explicit signer-auth flags.
 // Defaults to disabled unless --signer-auth is supplied.
 func extractSignerAuthArg(args []string) bool {
@@ -328,9 +381,40 @@ func extractPasswordArg(args []string) string {
 	return ""
 }
 
-func prepareRelease(args []string) (string, string, string, string, int, int64, error) {
+// extractOptimizationArg finds -O <level> or -O=<level> anywhere in args, so
+// the level may also follow the source file
+func extractOptimizationArg(args []string) (int, error) {
+	value := ""
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-O" && i+1 < len(args) {
+			value = args[i+1]
+		} else if strings.HasPrefix(args[i], "-O=") {
+			value = strings.TrimPrefix(args[i], "-O=")
+		}
+	}
+	if value == "" {
+		return 0, nil
+	}
+
+	level, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid optimization level %q, expected 0, 1 or 2", value)
+	}
+	return level, checkOptimizationLevel(level)
+}
+
+// checkOptimizationLevel rejects levels outside 0-2, which the flag package
+// parses as plain integers
+func checkOptimizationLevel(level int) error {
+	if level < 0 || level > 2 {
+		return fmt.Errorf("invalid optimization level %q, expected 0, 1 or 2", strconv.Itoa(level))
+	}
+	return nil
+}
+
+func prepareRelease(args []string) (string, string, string, string, int, int64, int, error) {
 	var goos, goarch, src, password string
-	var mutationLevel int
+	var mutationLevel, optimizationLevel int
 	var mutationSeed int64
 
 	releasecmd := flag.NewFlagSet(RELEASECMD, flag.ExitOnError)
@@ -342,9 +426,10 @@ func prepareRelease(args []string) (string, string, string, string, int, int64,
 	releasecmd.StringVar(&password, "pwd", "", "Short for -password")
 	releasecmd.IntVar(&mutationLevel, "mutation", defaultPolymorphicLevel, "Polymorphic mutation level (0-10)")
 	releasecmd.Int64Var(&mutationSeed, "seed", 0, "Polymorphic seed (default: current timestamp)")
+	releasecmd.IntVar(&optimizationLevel, "O", 0, "Optimization level (0-2)")
 
 	if err := releasecmd.Parse(args[2:]); err != nil {
-		return "", "", "", "", 0, 0, err
+		return "", "", "", "", 0, 0, 0, err
 	}
 
 	if src == "" {
@@ -355,29 +440,40 @@ func prepareRelease(args []string) (string, string, string, string, int, int64,
 		password = extractPasswordArg(args)
 	}
 
+	if optimizationLevel == 0 {
+		level, err := extractOptimizationArg(args)
+		if err != nil {
+			return "", "", "", "", 0, 0, 0, err
+		}
+		optimizationLevel = level
+	}
+	if err := checkOptimizationLevel(optimizationLevel); err != nil {
+		return "", "", "", "", 0, 0, 0, err
+	}
+
 	if releasecmd.Parsed() {
 		if src == "" {
-			return "", "", "", "", 0, 0, errors.New("mutant source code file path is required, please use -src flag")
+			return "", "", "", "", 0, 0, 0, errors.New("mutant source code file path is required, please use -src flag")
 		}
 
 		if !strings.HasSuffix(src, global.MutantSourceCodeFileExtention) {
-			return "", "", "", "", 0, 0, errors.New("incorrect file extension, this program only works for mutant source code files")
+			return "", "", "", "", 0, 0, 0, errors.New("incorrect file extension, this program only works for mutant source code files")
 		}
 
 		absSrc, err := filepath.Abs(src)
 		if err != nil {
-			return "", "", "", "", 0, 0, err
+			return "", "", "", "", 0, 0, 0, err
 		}
 
-		return absSrc, goos, goarch, password, mutationLevel, mutationSeed, nil
+		return absSrc, goos, goarch, password, mutationLevel, mutationSeed, optimizationLevel, nil
 	}
 
-	return "", "", "", "", 0, 0, errors.New("could not parse values")
+	return "", "", "", "", 0, 0, 0, errors.New("could not parse values")
 }
 
-func prepareGenRun(args []string) (string, string, int, int64, error) {
+func prepareGenRun(args []string) (string, string, int, int64, int, error) {
 	var src, password string
-	var mutationLevel int
+	var mutationLevel, optimizationLevel int
 	var mutationSeed int64
 
 	gencmd := flag.NewFlagSet(GENCMD, flag.ExitOnError)
@@ -387,9 +483,10 @@ func prepareGenRun(args []string) (string, string, int, int64, error) {
 	gencmd.StringVar(&password, "pwd", "", "Short for -password")
 	gencmd.IntVar(&mutationLevel, "mutation", defaultPolymorphicLevel, "Polymorphic mutation level (0-10)")
 	gencmd.Int64Var(&mutationSeed, "seed", 0, "Polymorphic seed (default: current timestamp)")
+	gencmd.IntVar(&optimizationLevel, "O", 0, "Optimization level (0-2)")
 
 	if err := gencmd.Parse(args[2:]); err != nil {
-		return "", "", 0, 0, err
+		return "", "", 0, 0, 0, err
 	}
 
 	if src == "" {
@@ -400,24 +497,53 @@ func prepareGenRun(args []string) (string, string, int, int64, error) {
 		password = extractPasswordArg(args)
 	}
 
+	if optimizationLevel == 0 {
+		level, err := extractOptimizationArg(args)
+		if err != nil {
+			return "", "", 0, 0, 0, err
+		}
+		optimizationLevel = level
+	}
+	if err := checkOptimizationLevel(optimizationLevel); err != nil {
+		return "", "", 0, 0, 0, err
+	}
+
 	if gencmd.Parsed() {
 		if src == "" {
-			return "", "", 0, 0, errors.New("mutant source code file path is required, please use -src flag")
+			return "", "", 0, 0, 0, errors.New("mutant source code file path is required, please use -src flag")
 		}
 
 		if !strings.HasSuffix(src, global.MutantSourceCodeFileExtention) {
-			return "", "", 0, 0, errors.New("incorrect file extension, this program only works for mutant source code files")
+			return "", "", 0, 0, 0, errors.New("incorrect file extension, this program only works for mutant source code files")
 		}
 
 		absSrc, err := filepath.Abs(src)
 		if err != nil {
-			return "", "", 0, 0, err
+			return "", "", 0, 0, 0, err
 		}
 
-		return absSrc, password, mutationLevel, mutationSeed, nil
+		return absSrc, password, mutationLevel, mutationSeed, optimizationLevel, nil
+	}
+
+	return "", "", 0, 0, 0, errors.New("could not parse values")
+}
+
+// prepareInfo returns the artifact to inspect; without an explicit path the running
+// executable is inspected so standalone binaries can describe themselves.
+func prepareInfo(args []string) (string, error) {
+	for i := 2; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--capabilities" || arg == "-capabilities" {
+			i++
+			continue
+		}
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		return arg, nil
 	}
 
-	return "", "", 0, 0, errors.New("could not parse values")
+	return os.Executable()
 }
 
 func hasReleaseAssetsArg(args []string) bool {
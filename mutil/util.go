@@ -151,7 +151,7 @@ func EncryptObject(obj object.Object, length int, password string) (object.Objec
 			}
 			pairs[hashKey] = object.HashPair{Key: encKey, Value: encValue}
 		}
-		encObj = &object.Hash{Pairs: pairs}
+		encObj = &object.Hash{Pairs: pairs, Order: slices.Clone(hashObj.Order)}
 
 	case object.STRUCT_OBJ:
 		structObj := obj.(*object.Struct)
@@ -189,6 +189,36 @@ func EncryptObject(obj object.Object, length int, password string) (object.Objec
 		}
 		encObj = &object.Closure{Fn: closureObj.Fn, Free: free}
 
+	case object.BOUND_METHOD_OBJ:
+		boundObj := obj.(*object.BoundMethod)
+		encReceiver, encErr := EncryptObject(boundObj.Receiver, length, password)
+		if encErr != nil {
+			return nil, encErr
+		}
+		encMethod, encErr := EncryptObject(boundObj.Method, length, password)
+		if encErr != nil {
+			return nil, encErr
+		}
+		encObj = &object.BoundMethod{Receiver: encReceiver, Name: boundObj.Name, Method: encMethod}
+
+	case object.ITERATOR_OBJ:
+		iterObj := obj.(*object.Iterator)
+		encIter := &object.Iterator{Values: make([]object.Object, len(iterObj.Values))}
+		if iterObj.Keys != nil {
+			encIter.Keys = make([]object.Object, len(iterObj.Keys))
+		}
+		for i := range iterObj.Values {
+			if encIter.Values[i], err = EncryptObject(iterObj.Values[i], length, password); err != nil {
+				return nil, err
+			}
+			if encIter.Keys != nil {
+				if encIter.Keys[i], err = EncryptObject(iterObj.Keys[i], length, password); err != nil {
+					return nil, err
+				}
+			}
+		}
+		encObj = encIter
+
 	case object.LUA_PATCH_OBJ:
 		patchObj := obj.(*object.LuaPatch)
 		xored, err := security.SecureXOR(patchObj.EncryptedPayload, int64(length), password)
@@ -201,7 +231,8 @@ func EncryptObject(obj object.Object, length int, password string) (object.Objec
 			ChecksumExpected: patchObj.ChecksumExpected,
 		}
 
-	case object.COMPILED_FN_OBJ, object.BUILTIN_OBJ:
+	case object.COMPILED_FN_OBJ, object.BUILTIN_OBJ, object.CELL_OBJ:
+		// A cell is shared by identity; the VM encrypts its contents itself.
 		encObj = obj
 
 	default:
@@ -292,7 +323,7 @@ func DecryptObject(obj object.Object, length int, password string) (object.Objec
 			}
 			pairs[hashKey] = object.HashPair{Key: decKey, Value: decValue}
 		}
-		return &object.Hash{Pairs: pairs}, nil
+		return &object.Hash{Pairs: pairs, Order: slices.Clone(hashObj.Order)}, nil
 
 	case object.STRUCT_OBJ:
 		structObj := decObj.(*object.Struct)
@@ -318,6 +349,24 @@ func DecryptObject(obj object.Object, length int, password string) (object.Objec
 		}
 		return &object.EnumValue{TypeName: enumObj.TypeName, Tag: enumObj.Tag, Value: decValue}, nil
 
+	case object.ITERATOR_OBJ:
+		iterObj := decObj.(*object.Iterator)
+		decIter := &object.Iterator{Values: make([]object.Object, len(iterObj.Values))}
+		if iterObj.Keys != nil {
+			decIter.Keys = make([]object.Object, len(iterObj.Keys))
+		}
+		for i := range iterObj.Values {
+			if decIter.Values[i], err = DecryptObject(iterObj.Values[i], length, password); err != nil {
+				return nil, err
+			}
+			if decIter.Keys != nil {
+				if decIter.Keys[i], err = DecryptObject(iterObj.Keys[i], length, password); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return decIter, nil
+
 	case object.LUA_PATCH_OBJ:
 		patchObj := decObj.(*object.LuaPatch)
 		xored, err := security.SecureXOR(patchObj.EncryptedPayload, int64(length), password)
@@ -342,7 +391,19 @@ func DecryptObject(obj object.Object, length int, password string) (object.Objec
 		}
 		return &object.Closure{Fn: closureObj.Fn, Free: free}, nil
 
-	case object.COMPILED_FN_OBJ, object.BUILTIN_OBJ:
+	case object.BOUND_METHOD_OBJ:
+		boundObj := decObj.(*object.BoundMethod)
+		receiver, decErr := DecryptObject(boundObj.Receiver, length, password)
+		if decErr != nil {
+			return nil, decErr
+		}
+		method, decErr := DecryptObject(boundObj.Method, length, password)
+		if decErr != nil {
+			return nil, decErr
+		}
+		return &object.BoundMethod{Receiver: receiver, Name: boundObj.Name, Method: method}, nil
+
+	case object.COMPILED_FN_OBJ, object.BUILTIN_OBJ, object.CELL_OBJ:
 		return decObj, nil
 	}
 
@@ -9,3 +9,14 @@ type Closure struct {
 
 func (c *Closure) Type() ObjectType { return CLOSURE_OBJ }
 func (c *Closure) Inspect() string  { return fmt.Sprintf("Closure[%p]", c) }
+
+// Cell holds a variable captured by a closure. The function that declared the
+// variable and every closure that captured it share the same cell, so an
+// assignment on either side is seen by all of them. The VM keeps Value
+// encrypted like any other stack slot.
+type Cell struct {
+	Value Object
+}
+
+func (c *Cell) Type() ObjectType { return CELL_OBJ }
+func (c *Cell) Inspect() string  { return fmt.Sprintf("Cell[%p]", c) }
@@ -5,11 +5,44 @@ import (
 	"mutant/code"
 )
 
+// CompiledFunction is a function body produced by the compiler. The last
+// NumDefaults of its NumParams parameters may be omitted by the caller; a
+// Variadic function receives any further arguments as an array in the local
+// slot right after its parameters.
 type CompiledFunction struct {
 	Instructions code.Instructions
 	NumLocals    int
 	NumParams    int
+	NumDefaults  int
+	Variadic     bool
+	Handlers     []ExceptionHandler
 }
 
 func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FN_OBJ }
 func (cf *CompiledFunction) Inspect() string  { return fmt.Sprintf("Compiled Function[%p]", cf) }
+
+// ArityError describes why numArgs arguments do not fit a parameter list of
+// params parameters, the last defaults of which are optional. It returns an
+// empty string when the call is valid.
+func ArityError(params, defaults int, variadic bool, numArgs int) string {
+	required := params - defaults
+	switch {
+	case numArgs >= required && (numArgs <= params || variadic):
+		return ""
+	case variadic:
+		return fmt.Sprintf("wrong number of arguments. want at least %d, got=%d", required, numArgs)
+	case defaults > 0:
+		return fmt.Sprintf("wrong number of arguments. want=%d to %d, got=%d", required, params, numArgs)
+	default:
+		return fmt.Sprintf("wrong number of arguments. want=%d, got=%d", params, numArgs)
+	}
+}
+
+// ExceptionHandler is one entry of a function's handler table. The OpTry at
+// Start activates it; exceptions raised before the matching OpEndTry at End
+// resume execution at Target with the exception on the stack.
+type ExceptionHandler struct {
+	Start  int
+	End    int
+	Target int
+}
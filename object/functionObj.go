@@ -6,20 +6,42 @@ import (
 	"strings"
 )
 
+// Function is a closure of the tree-walking evaluator. Defaults and Rest
+// mirror the fields of ast.FunctionLiteral.
 type Function struct {
 	Parameters []*ast.Identifier
+	Defaults   []ast.Expression
+	Rest       *ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
 
+// NumDefaults returns how many parameters have a default value.
+func (f *Function) NumDefaults() int {
+	n := 0
+	for _, def := range f.Defaults {
+		if def != nil {
+			n++
+		}
+	}
+	return n
+}
+
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
 	params := []string{}
 
-	for _, p := range f.Parameters {
+	for i, p := range f.Parameters {
+		if i < len(f.Defaults) && f.Defaults[i] != nil {
+			params = append(params, p.String()+" = "+f.Defaults[i].String())
+			continue
+		}
 		params = append(params, p.String())
 	}
+	if f.Rest != nil {
+		params = append(params, "..."+f.Rest.String())
+	}
 
 	out.WriteString("fn")
 	out.WriteString("(")
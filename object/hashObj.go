@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"hash/fnv"
 	"math"
+	"sort"
 	"strings"
 )
 
@@ -18,7 +19,54 @@ type HashPair struct {
 	Value Object
 }
 
-type Hash struct{ Pairs map[HashKey]HashPair }
+// Hash maps hashable keys to values. Order lists the keys in the order they
+// were first set; pairs written straight into Pairs without Set come after
+// them, sorted by key type and then key value.
+type Hash struct {
+	Pairs map[HashKey]HashPair
+	Order []HashKey
+}
+
+// Set stores pair under key. A new key is appended to the order, while an
+// existing key keeps its position and only its value changes.
+func (h *Hash) Set(key HashKey, pair HashPair) {
+	if h.Pairs == nil {
+		h.Pairs = make(map[HashKey]HashPair)
+	}
+	if _, exists := h.Pairs[key]; !exists {
+		h.Order = append(h.Order, key)
+	}
+	h.Pairs[key] = pair
+}
+
+// OrderedPairs returns the pairs in insertion order
+func (h *Hash) OrderedPairs() []HashPair {
+	pairs := make([]HashPair, 0, len(h.Pairs))
+	seen := make(map[HashKey]struct{}, len(h.Order))
+	for _, key := range h.Order {
+		pair, ok := h.Pairs[key]
+		if _, dup := seen[key]; !ok || dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		pairs = append(pairs, pair)
+	}
+	if len(pairs) == len(h.Pairs) {
+		return pairs
+	}
+
+	rest := make([]HashPair, 0, len(h.Pairs)-len(pairs))
+	for key, pair := range h.Pairs {
+		if _, ok := seen[key]; !ok {
+			rest = append(rest, pair)
+		}
+	}
+	sort.Slice(rest, func(i, j int) bool {
+		return lessKey(rest[i].Key, rest[j].Key)
+	})
+	pairs = append(pairs, rest...)
+	return pairs
+}
 
 type Hashable interface{ HashKey() HashKey }
 
@@ -51,7 +99,7 @@ func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 	pairs := []string{}
 
-	for _, pair := range h.Pairs {
+	for _, pair := range h.OrderedPairs() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
 	}
 
@@ -0,0 +1,72 @@
+package object
+
+// Iterator is the snapshot a for-in loop walks. Keys is nil for arrays and
+// strings, whose keys are element positions.
+type Iterator struct {
+	Keys   []Object
+	Values []Object
+}
+
+func (it *Iterator) Type() ObjectType { return ITERATOR_OBJ }
+func (it *Iterator) Inspect() string  { return "<iterator>" }
+
+// NewIterator snapshots an array, string (by rune) or hash for iteration
+func NewIterator(obj Object) (*Iterator, bool) {
+	switch obj := obj.(type) {
+	case *Array:
+		values := make([]Object, len(obj.Elements))
+		copy(values, obj.Elements)
+		return &Iterator{Values: values}, true
+	case *String:
+		values := make([]Object, 0, len(obj.Value))
+		for _, ch := range obj.Value {
+			values = append(values, &String{Value: string(ch)})
+		}
+		return &Iterator{Values: values}, true
+	case *Hash:
+		pairs := obj.OrderedPairs()
+		it := &Iterator{Keys: make([]Object, len(pairs)), Values: make([]Object, len(pairs))}
+		for i, pair := range pairs {
+			it.Keys[i] = pair.Key
+			it.Values[i] = pair.Value
+		}
+		return it, true
+	}
+	return nil, false
+}
+
+func (it *Iterator) Len() int { return len(it.Values) }
+
+func (it *Iterator) Key(i int) Object {
+	if it.Keys == nil {
+		return &Integer{Value: int64(i)}
+	}
+	return it.Keys[i]
+}
+
+func (it *Iterator) Value(i int) Object { return it.Values[i] }
+
+// Single is what a one-variable loop binds: keys for hashes, elements otherwise
+func (it *Iterator) Single(i int) Object {
+	if it.Keys != nil {
+		return it.Keys[i]
+	}
+	return it.Values[i]
+}
+
+// lessKey orders hash keys by type and then value, for pairs that have no
+// insertion position
+func lessKey(a, b Object) bool {
+	if a.Type() != b.Type() {
+		return a.Type() < b.Type()
+	}
+	switch a := a.(type) {
+	case *Integer:
+		return a.Value < b.(*Integer).Value
+	case *Float:
+		return a.Value < b.(*Float).Value
+	case *Boolean:
+		return !a.Value && b.(*Boolean).Value
+	}
+	return a.Inspect() < b.Inspect()
+}
@@ -0,0 +1,56 @@
+package object
+
+import "math/bits"
+
+// IntPow raises base to a non-negative exponent by repeated squaring. It
+// reports false when the result does not fit in an INTEGER; the ** operator,
+// constant folding and the pow builtin all treat that as an error.
+func IntPow(base, exp int64) (int64, bool) {
+	result := int64(1)
+	for exp > 0 {
+		if exp&1 == 1 {
+			var ok bool
+			if result, ok = mulInt(result, base); !ok {
+				return 0, false
+			}
+		}
+		exp >>= 1
+		if exp > 0 {
+			var ok bool
+			if base, ok = mulInt(base, base); !ok {
+				return 0, false
+			}
+		}
+	}
+	return result, true
+}
+
+// mulInt multiplies two integers, reporting false when the product does not
+// fit in 64 bits
+func mulInt(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	negative := (a < 0) != (b < 0)
+	hi, lo := bits.Mul64(absUint(a), absUint(b))
+	if hi != 0 {
+		return 0, false
+	}
+	if negative {
+		if lo > 1<<63 {
+			return 0, false
+		}
+		return -int64(lo), true
+	}
+	if lo >= 1<<63 {
+		return 0, false
+	}
+	return int64(lo), true
+}
+
+func absUint(v int64) uint64 {
+	if v < 0 {
+		return uint64(-v)
+	}
+	return uint64(v)
+}
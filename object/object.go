@@ -24,6 +24,9 @@ const (
 	BREAK_OBJ        = "BREAK"
 	CONTINUE_OBJ     = "CONTINUE"
 	LUA_PATCH_OBJ    = "LUA_PATCH"
+	ITERATOR_OBJ     = "ITERATOR"
+	BOUND_METHOD_OBJ = "BOUND_METHOD"
+	CELL_OBJ         = "CELL"
 )
 
 type Object interface {
@@ -30,3 +30,22 @@ func (s *Struct) Inspect() string {
 
 	return out.String()
 }
+
+// BoundMethod is a struct method paired with the receiver it was read from.
+// Calling it passes Receiver as the method's implicit `self` argument.
+type BoundMethod struct {
+	Receiver Object
+	Name     string
+	Method   Object
+}
+
+func (bm *BoundMethod) Type() ObjectType {
+	return BOUND_METHOD_OBJ
+}
+
+func (bm *BoundMethod) Inspect() string {
+	if s, ok := bm.Receiver.(*Struct); ok {
+		return fmt.Sprintf("<method %s.%s>", s.TypeName, bm.Name)
+	}
+	return fmt.Sprintf("<method %s>", bm.Name)
+}
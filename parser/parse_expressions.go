@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"fmt"
 	"mutant/ast"
 	"mutant/token"
 )
@@ -35,6 +34,121 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return exp
 }
 
+func (p *Parser) parseMatchExpression() ast.Expression {
+	exp := &ast.MatchExpression{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	exp.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		exp.Arms = append(exp.Arms, arm)
+
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		} else if !p.peekTokenIs(token.RBRACE) {
+			p.errorAt(p.peekToken.Pos, "expected ',' or '}' after match arm, got %s", p.peekToken.Type)
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	if len(exp.Arms) == 0 {
+		p.errorAt(exp.Token.Pos, "match requires at least one arm")
+		return nil
+	}
+
+	return exp
+}
+
+// parseMatchArm parses `pattern => body` with the current token on the
+// pattern. The body is a block or a single expression.
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Token: p.curToken}
+
+	switch {
+	case p.curTokenIs(token.IDENT) && p.curToken.Literal == "_":
+		arm.Wildcard = true
+	case p.curTokenIs(token.IDENT):
+		if !p.peekTokenIs(token.DOT) {
+			p.errorAt(arm.Token.Pos, "invalid match pattern %s", arm.Token.Literal)
+			return nil
+		}
+		arm.Enum = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		arm.Tag = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			if p.curToken.Literal != "_" {
+				arm.Binding = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			}
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+	default:
+		arm.Literal = p.parseExpression(PREFIX)
+		if !isLiteralPattern(arm.Literal) {
+			p.errorAt(arm.Token.Pos, "invalid match pattern %s", arm.Token.Literal)
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		arm.Body = p.parseBlockStatement()
+		return arm
+	}
+
+	p.nextToken()
+	bodyToken := p.curToken
+	body := p.parseExpression(LOWEST)
+	arm.Body = &ast.BlockStatement{
+		Token:      bodyToken,
+		Statements: []ast.Statement{&ast.ExpressionStatement{Token: bodyToken, Expression: body}},
+	}
+	return arm
+}
+
+func isLiteralPattern(exp ast.Expression) bool {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.Boolean:
+		return true
+	case *ast.PrefixExpression:
+		switch exp.Right.(type) {
+		case *ast.IntegerLiteral, *ast.FloatLiteral:
+			return exp.Operator == "-"
+		}
+	}
+	return false
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	// defer untrace(trace("parsePrefixExpression"))
 
@@ -59,12 +173,26 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	prec := p.curPrecedence()
+	if p.curTokenIs(token.POWER) {
+		// ** is right-associative: 2 ** 3 ** 2 == 2 ** (3 ** 2)
+		prec--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(prec)
 
 	return expression
 }
 
+func (p *Parser) parseRangeExpression(start ast.Expression) ast.Expression {
+	expression := &ast.RangeExpression{Token: p.curToken, Start: start}
+
+	prec := p.curPrecedence()
+	p.nextToken()
+	expression.End = p.parseExpression(prec)
+
+	return expression
+}
+
 func (p *Parser) parseGroupedExpression() ast.Expression {
 	p.nextToken()
 	exp := p.parseExpression(LOWEST)
@@ -154,19 +282,36 @@ func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
 	switch left.(type) {
 	case *ast.Identifier, *ast.FieldExpression:
 	default:
-		msg := fmt.Sprintf("invalid assignment target: %T", left)
-		p.errors = append(p.errors, msg)
+		p.errorAt(p.curToken.Pos, "invalid assignment target: %T", left)
 		return nil
 	}
 
 	exp := &ast.AssignExpression{Token: p.curToken, Left: left}
+	operator, compound := compoundOperators[p.curToken.Type]
 	precedence := p.curPrecedence()
 	p.nextToken()
 	exp.Value = p.parseExpression(precedence - 1)
 
+	// x += v is lowered to x = x + v so later stages only see plain assignment
+	if compound {
+		exp.Value = &ast.InfixExpression{
+			Token:    token.Token{Type: token.TokenType(operator), Literal: operator, Pos: exp.Token.Pos},
+			Operator: operator,
+			Left:     left,
+			Right:    exp.Value,
+		}
+	}
+
 	return exp
 }
 
+var compoundOperators = map[token.TokenType]string{
+	token.PLUS_ASSIGN:     "+",
+	token.MINUS_ASSIGN:    "-",
+	token.ASTERISK_ASSIGN: "*",
+	token.FSLASH_ASSIGN:   "/",
+}
+
 func (p *Parser) parseFieldExpression(left ast.Expression) ast.Expression {
 	exp := &ast.FieldExpression{Token: p.curToken, Left: left}
 
@@ -189,8 +334,7 @@ func (p *Parser) parseStructLiteralExpression(left ast.Expression) ast.Expressio
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
 		if !p.curTokenIs(token.IDENT) {
-			msg := fmt.Sprintf("expected struct literal field identifier, got %s", p.curToken.Type)
-			p.errors = append(p.errors, msg)
+			p.errorAt(p.curToken.Pos, "expected struct literal field identifier, got %s", p.curToken.Type)
 			return nil
 		}
 
@@ -1,8 +1,8 @@
 package parser
 
 import (
-	"fmt"
 	"mutant/ast"
+	"mutant/lexer"
 	"mutant/token"
 	"strconv"
 )
@@ -12,8 +12,7 @@ func (p *Parser) parseFloatLiteral() ast.Expression {
 	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
 
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as float", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorAt(p.curToken.Pos, "could not parse %q as float", p.curToken.Literal)
 	}
 
 	lit.Value = value
@@ -28,8 +27,7 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorAt(p.curToken.Pos, "could not parse %q as integer", p.curToken.Literal)
 		return nil
 	}
 
@@ -42,6 +40,64 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// parseTemplateLiteral lowers "a ${x} b" to ("a" + str(x)) + " b".
+// Lexical errors inside the template were already reported by the lexer.
+func (p *Parser) parseTemplateLiteral() ast.Expression {
+	tok := p.curToken
+	bodyPos := tok.Pos
+	bodyPos.Column++
+	parts, _ := lexer.ScanStringParts(tok.Literal, bodyPos)
+
+	var result ast.Expression
+	for _, part := range parts {
+		var segment ast.Expression
+		if part.IsExpr {
+			value := p.parseInterpolation(part)
+			if value == nil {
+				return nil
+			}
+			segment = &ast.StringifyExpression{Token: tok, Value: value}
+		} else {
+			segment = &ast.StringLiteral{Token: token.Token{Type: token.STRING, Literal: part.Text, Pos: tok.Pos}, Value: part.Text}
+		}
+
+		if result == nil {
+			result = segment
+			continue
+		}
+		result = &ast.InfixExpression{
+			Token:    token.Token{Type: token.PLUS, Literal: "+", Pos: tok.Pos},
+			Operator: "+",
+			Left:     result,
+			Right:    segment,
+		}
+	}
+
+	if result == nil {
+		return &ast.StringLiteral{Token: token.Token{Type: token.STRING, Pos: tok.Pos}}
+	}
+	return result
+}
+
+// parseInterpolation parses the expression source of a single ${...} segment
+func (p *Parser) parseInterpolation(part lexer.StringPart) ast.Expression {
+	sub := New(lexer.NewAt(part.Source, part.Pos))
+	if sub.curTokenIs(token.EOF) {
+		return nil
+	}
+
+	expr := sub.parseExpression(LOWEST)
+	if !sub.peekTokenIs(token.EOF) {
+		sub.errorAt(sub.peekToken.Pos, "unexpected %s in string interpolation", sub.peekToken.Type)
+	}
+	sub.errors = append(sub.l.Errors(), sub.errors...)
+	p.errors = append(p.errors, sub.errors...)
+	if len(sub.errors) > 0 {
+		return nil
+	}
+	return expr
+}
+
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	lit := &ast.FunctionLiteral{Token: p.curToken}
 
@@ -49,7 +105,9 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	lit.Parameters = p.parseFunctionParameters()
+	if !p.parseParameterList(lit) {
+		return nil
+	}
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
@@ -79,6 +137,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
 		hash.Pairs[key] = value
+		hash.Keys = append(hash.Keys, key)
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
 		}
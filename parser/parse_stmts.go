@@ -1,9 +1,11 @@
 package parser
 
 import (
-	"fmt"
 	"mutant/ast"
 	"mutant/token"
+	"path"
+	"strings"
+	"unicode"
 )
 
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
@@ -12,7 +14,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	p.nextToken()
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
 		stmt := p.parseStatement()
-		if (stmt.String() != "") && (stmt.TokenLiteral() != "") {
+		if stmt != nil && (stmt.String() != "") && (stmt.TokenLiteral() != "") {
 			block.Statements = append(block.Statements, stmt)
 		}
 		p.nextToken()
@@ -28,6 +30,12 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseReturnStatement()
 	case token.FOR:
 		return p.parseForStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.TRY:
+		return p.parseTryStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
 	case token.BREAK:
 		return p.parseBreakStatement()
 	case token.CONTINUE:
@@ -57,7 +65,7 @@ func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
 	return stmt
 }
 
-func (p *Parser) parseForStatement() *ast.ForStatement {
+func (p *Parser) parseForStatement() ast.Statement {
 	stmt := &ast.ForStatement{Token: p.curToken}
 
 	if !p.expectPeek(token.LPAREN) {
@@ -65,6 +73,10 @@ func (p *Parser) parseForStatement() *ast.ForStatement {
 	}
 
 	p.nextToken()
+	if p.curTokenIs(token.IDENT) && (p.peekTokenIs(token.IN) || p.peekTokenIs(token.COMMA)) {
+		return p.parseForInStatement(stmt.Token)
+	}
+
 	if !p.curTokenIs(token.SEMICOLON) {
 		switch p.curToken.Type {
 		case token.LET:
@@ -75,8 +87,7 @@ func (p *Parser) parseForStatement() *ast.ForStatement {
 	}
 
 	if !p.curTokenIs(token.SEMICOLON) {
-		msg := fmt.Sprintf("expected token %s in for init section, got %s", token.SEMICOLON, p.curToken.Type)
-		p.errors = append(p.errors, msg)
+		p.errorAt(p.curToken.Pos, "expected token %s in for init section, got %s", token.SEMICOLON, p.curToken.Type)
 		return nil
 	}
 
@@ -104,6 +115,89 @@ func (p *Parser) parseForStatement() *ast.ForStatement {
 	return stmt
 }
 
+// parseForInStatement parses the rest of `for (v in xs)` or `for (k, v in xs)`
+// with the current token on the first loop variable
+func (p *Parser) parseForInStatement(forToken token.Token) ast.Statement {
+	stmt := &ast.ForInStatement{Token: forToken}
+	stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Key = stmt.Value
+		stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStatement()
+	return stmt
+}
+
+// parseImportStatement parses `import "path.mut"` or `import name`, with an
+// optional `as alias`. Without an alias the namespace is the file's base name.
+func (p *Parser) parseImportStatement() ast.Statement {
+	stmt := &ast.ImportStatement{Token: p.curToken}
+
+	switch p.peekToken.Type {
+	case token.STRING:
+		p.nextToken()
+		stmt.Path = p.curToken.Literal
+	case token.IDENT:
+		p.nextToken()
+		stmt.Path = p.curToken.Literal + ".mut"
+	default:
+		p.errorAt(p.peekToken.Pos, "expected module path or name after import, got %s", p.peekToken.Type)
+		return nil
+	}
+	pathToken := p.curToken
+
+	if p.peekTokenIs(token.IDENT) && p.peekToken.Literal == "as" {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	} else {
+		name := strings.TrimSuffix(path.Base(stmt.Path), path.Ext(stmt.Path))
+		if !isIdentifier(name) {
+			p.errorAt(pathToken.Pos, "cannot derive a module name from %q, use `as name`", stmt.Path)
+			return nil
+		}
+		nameToken := token.Token{Type: token.IDENT, Literal: name, Pos: pathToken.Pos}
+		stmt.Name = &ast.Identifier{Token: nameToken, Value: name}
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// isIdentifier reports whether name would lex as a single non-keyword identifier
+func isIdentifier(name string) bool {
+	for i, ch := range name {
+		if !unicode.IsLetter(ch) && (i == 0 || !(unicode.IsDigit(ch) || ch == '_')) {
+			return false
+		}
+	}
+	return name != "" && token.LookupIdent(name) == token.IDENT
+}
+
 func (p *Parser) parseStructStatement() *ast.StructStatement {
 	stmt := &ast.StructStatement{Token: p.curToken, Fields: []*ast.Identifier{}}
 
@@ -119,9 +213,19 @@ func (p *Parser) parseStructStatement() *ast.StructStatement {
 
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
+		if p.curTokenIs(token.FUNCTION) {
+			method := p.parseStructMethod()
+			if method == nil {
+				return nil
+			}
+			stmt.Methods = append(stmt.Methods, method)
+			if p.peekTokenIs(token.SEMICOLON) {
+				p.nextToken()
+			}
+			continue
+		}
 		if !p.curTokenIs(token.IDENT) {
-			msg := fmt.Sprintf("expected struct field identifier, got %s", p.curToken.Type)
-			p.errors = append(p.errors, msg)
+			p.errorAt(p.curToken.Pos, "expected struct field identifier, got %s", p.curToken.Type)
 			return nil
 		}
 
@@ -131,8 +235,7 @@ func (p *Parser) parseStructStatement() *ast.StructStatement {
 		if p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.COMMA) {
 			p.nextToken()
 		} else if !p.peekTokenIs(token.RBRACE) {
-			msg := fmt.Sprintf("expected ';' or '}' in struct declaration, got %s", p.peekToken.Type)
-			p.errors = append(p.errors, msg)
+			p.errorAt(p.peekToken.Pos, "expected ';' or '}' in struct declaration, got %s", p.peekToken.Type)
 			return nil
 		}
 	}
@@ -148,6 +251,42 @@ func (p *Parser) parseStructStatement() *ast.StructStatement {
 	return stmt
 }
 
+// parseStructMethod parses `fn name(params) { body }` inside a struct body,
+// with the current token on `fn`.
+func (p *Parser) parseStructMethod() *ast.StructMethod {
+	method := &ast.StructMethod{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	method.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	fn := &ast.FunctionLiteral{Token: method.Token}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	if !p.parseParameterList(fn) {
+		return nil
+	}
+	params := fn.Parameters
+	if fn.Rest != nil {
+		params = append(params[:len(params):len(params)], fn.Rest)
+	}
+	for _, param := range params {
+		if param.Value == "self" {
+			p.errorAt(param.Token.Pos, "method %s cannot declare a parameter named self", method.Name.Value)
+			return nil
+		}
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	fn.Body = p.parseBlockStatement()
+	method.Function = fn
+
+	return method
+}
+
 func (p *Parser) parseEnumStatement() *ast.EnumStatement {
 	stmt := &ast.EnumStatement{Token: p.curToken, Variants: []*ast.Identifier{}}
 
@@ -164,19 +303,35 @@ func (p *Parser) parseEnumStatement() *ast.EnumStatement {
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
 		if !p.curTokenIs(token.IDENT) {
-			msg := fmt.Sprintf("expected enum variant identifier, got %s", p.curToken.Type)
-			p.errors = append(p.errors, msg)
+			p.errorAt(p.curToken.Pos, "expected enum variant identifier, got %s", p.curToken.Type)
 			return nil
 		}
 
 		variant := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 		stmt.Variants = append(stmt.Variants, variant)
 
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			if stmt.Payloads == nil {
+				stmt.Payloads = map[string]*ast.Identifier{}
+			}
+			stmt.Payloads[variant.Value] = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if p.peekTokenIs(token.COMMA) {
+				p.errorAt(p.peekToken.Pos, "enum variant %s carries a single payload", variant.Value)
+				return nil
+			}
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+
 		if p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.SEMICOLON) {
 			p.nextToken()
 		} else if !p.peekTokenIs(token.RBRACE) {
-			msg := fmt.Sprintf("expected ',' or '}' in enum declaration, got %s", p.peekToken.Type)
-			p.errors = append(p.errors, msg)
+			p.errorAt(p.peekToken.Pos, "expected ',' or '}' in enum declaration, got %s", p.peekToken.Type)
 			return nil
 		}
 	}
@@ -202,11 +357,75 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
-func (p *Parser) parseLetStatement() *ast.LetStatement {
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseTryStatement parses `try { } catch (e) { } finally { }`. The catch
+// binding is optional and at least one of catch and finally must follow.
+func (p *Parser) parseTryStatement() ast.Statement {
+	stmt := &ast.TryStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			stmt.CatchParam = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Finally = p.parseBlockStatement()
+	}
+
+	if stmt.Catch == nil && stmt.Finally == nil {
+		p.errorAt(stmt.Token.Pos, "try requires a catch or finally block")
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+func (p *Parser) parseLetStatement() ast.Statement {
+	if p.peekTokenIs(token.LSQUARE) || p.peekTokenIs(token.LBRACE) {
+		return p.parseDestructureStatement(p.curToken)
+	}
+
 	stmt := &ast.LetStatement{Token: p.curToken}
 	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
+	if p.peekTokenIs(token.LBRACE) {
+		return p.parseDestructureStatement(stmt.Token)
+	}
 
 	stmt.Name = &ast.Identifier{
 		Token: p.curToken,
@@ -230,3 +449,92 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	return stmt
 }
+
+// parseDestructureStatement parses the pattern and value of a destructuring
+// let. The current token is `let` for array and hash patterns, or the struct
+// name for struct patterns.
+func (p *Parser) parseDestructureStatement(letToken token.Token) ast.Statement {
+	stmt := &ast.DestructureStatement{Token: letToken}
+	closing := token.TokenType(token.RBRACE)
+
+	switch {
+	case p.curTokenIs(token.IDENT):
+		stmt.Kind = ast.StructPattern
+		stmt.Struct = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	case p.peekTokenIs(token.LSQUARE):
+		stmt.Kind = ast.ArrayPattern
+		closing = token.RSQUARE
+	default:
+		stmt.Kind = ast.HashPattern
+	}
+	p.nextToken()
+
+	seen := map[string]bool{}
+	for !p.peekTokenIs(closing) {
+		p.nextToken()
+
+		if p.curTokenIs(token.ELLIPSIS) {
+			if stmt.Kind != ast.ArrayPattern {
+				p.errorAt(p.curToken.Pos, "rest bindings are only supported in array patterns")
+				return nil
+			}
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			stmt.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if seen[stmt.Rest.Value] {
+				p.errorAt(p.curToken.Pos, "duplicate binding %s in destructuring pattern", stmt.Rest.Value)
+				return nil
+			}
+			if !p.peekTokenIs(closing) {
+				p.errorAt(p.peekToken.Pos, "rest binding must be the last element of an array pattern")
+				return nil
+			}
+			break
+		}
+
+		if !p.curTokenIs(token.IDENT) {
+			p.errorAt(p.curToken.Pos, "expected identifier in destructuring pattern, got %s", p.curToken.Type)
+			return nil
+		}
+		name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if name.Value == "_" && stmt.Kind != ast.ArrayPattern {
+			p.errorAt(name.Token.Pos, "`_` can only skip elements of an array pattern")
+			return nil
+		}
+		if name.Value != "_" {
+			if seen[name.Value] {
+				p.errorAt(name.Token.Pos, "duplicate binding %s in destructuring pattern", name.Value)
+				return nil
+			}
+			seen[name.Value] = true
+		}
+		stmt.Names = append(stmt.Names, name)
+
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		} else if !p.peekTokenIs(closing) {
+			p.errorAt(p.peekToken.Pos, "expected ',' or '%s' in destructuring pattern, got %s", closing, p.peekToken.Type)
+			return nil
+		}
+	}
+	p.nextToken()
+
+	if len(stmt.Names) == 0 && stmt.Rest == nil {
+		p.errorAt(stmt.Token.Pos, "destructuring pattern binds no names")
+		return nil
+	}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if !p.curTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
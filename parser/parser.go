@@ -11,30 +11,54 @@ const (
 	_ int = iota
 	LOWEST
 	ASSIGNMENT
+	LOGICALOR
+	LOGICALAND
 	EQUALS
 	LESSGREATER
+	RANGE
+	BITOR
+	BITXOR
+	BITAND
+	SHIFT
 	SUM
 	PRODUCT
 	PREFIX
+	POWER
 	CALL
 	INDEX
 	FIELD
 )
 
 var precedences = map[token.TokenType]int{
-	token.ASSIGN:     ASSIGNMENT,
-	token.EQUALITY:   EQUALS,
-	token.INEQUALITY: EQUALS,
-	token.LT:         LESSGREATER,
-	token.GT:         LESSGREATER,
-	token.PLUS:       SUM,
-	token.MINUS:      SUM,
-	token.FSLASH:     PRODUCT,
-	token.ASTERISK:   PRODUCT,
-	token.LPAREN:     CALL,
-	token.LSQUARE:    INDEX,
-	token.DOT:        FIELD,
-	token.LBRACE:     CALL,
+	token.ASSIGN:          ASSIGNMENT,
+	token.PLUS_ASSIGN:     ASSIGNMENT,
+	token.MINUS_ASSIGN:    ASSIGNMENT,
+	token.ASTERISK_ASSIGN: ASSIGNMENT,
+	token.FSLASH_ASSIGN:   ASSIGNMENT,
+	token.OR:              LOGICALOR,
+	token.AND:             LOGICALAND,
+	token.EQUALITY:        EQUALS,
+	token.INEQUALITY:      EQUALS,
+	token.LT:              LESSGREATER,
+	token.GT:              LESSGREATER,
+	token.LTE:             LESSGREATER,
+	token.GTE:             LESSGREATER,
+	token.RANGE:           RANGE,
+	token.BITOR:           BITOR,
+	token.BITXOR:          BITXOR,
+	token.BITAND:          BITAND,
+	token.SHL:             SHIFT,
+	token.SHR:             SHIFT,
+	token.PLUS:            SUM,
+	token.MINUS:           SUM,
+	token.FSLASH:          PRODUCT,
+	token.ASTERISK:        PRODUCT,
+	token.MODULO:          PRODUCT,
+	token.POWER:           POWER,
+	token.LPAREN:          CALL,
+	token.LSQUARE:         INDEX,
+	token.DOT:             FIELD,
+	token.LBRACE:          CALL,
 }
 
 type (
@@ -64,8 +88,10 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.TEMPLATE, p.parseTemplateLiteral)
 	p.registerPrefix(token.LSQUARE, p.parseArrayLiteral)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
@@ -75,9 +101,25 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
 	p.registerInfix(token.EQUALITY, p.parseInfixExpression)
 	p.registerInfix(token.INEQUALITY, p.parseInfixExpression)
+	p.registerInfix(token.MODULO, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LTE, p.parseInfixExpression)
+	p.registerInfix(token.GTE, p.parseInfixExpression)
+	p.registerInfix(token.BITAND, p.parseInfixExpression)
+	p.registerInfix(token.BITOR, p.parseInfixExpression)
+	p.registerInfix(token.BITXOR, p.parseInfixExpression)
+	p.registerInfix(token.SHL, p.parseInfixExpression)
+	p.registerInfix(token.SHR, p.parseInfixExpression)
+	p.registerInfix(token.POWER, p.parseInfixExpression)
+	p.registerInfix(token.RANGE, p.parseRangeExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
 	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.FSLASH_ASSIGN, p.parseAssignExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LSQUARE, p.parseIndexExpression)
 	p.registerInfix(token.DOT, p.parseFieldExpression)
@@ -107,6 +149,11 @@ func (p *Parser) ParseProgram() *ast.Program {
 		}
 		p.nextToken()
 	}
+
+	program.Comments = p.l.Comments()
+	if lexErrors := p.l.Errors(); len(lexErrors) > 0 {
+		p.errors = append(append([]string{}, lexErrors...), p.errors...)
+	}
 	return program
 }
 
@@ -148,6 +195,67 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	return identifiers
 }
 
+// parseParameterList parses the parameters of a function literal or method
+// into fn, starting with the current token on `(`. A parameter may be followed
+// by `= default`, and the last one may be a `...rest` parameter.
+func (p *Parser) parseParameterList(fn *ast.FunctionLiteral) bool {
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return true
+	}
+
+	hasDefaults := false
+	for {
+		p.nextToken()
+		if fn.Rest != nil {
+			p.errorAt(fn.Rest.Token.Pos, "rest parameter ...%s must be the last parameter", fn.Rest.Value)
+			return false
+		}
+
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return false
+			}
+			fn.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if p.peekTokenIs(token.ASSIGN) {
+				p.errorAt(fn.Rest.Token.Pos, "rest parameter ...%s cannot have a default value", fn.Rest.Value)
+				return false
+			}
+		} else {
+			if !p.curTokenIs(token.IDENT) {
+				p.errorAt(p.curToken.Pos, "expected parameter name, got %s", p.curToken.Type)
+				return false
+			}
+			ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+			var def ast.Expression
+			if p.peekTokenIs(token.ASSIGN) {
+				p.nextToken()
+				p.nextToken()
+				if def = p.parseExpression(LOWEST); def == nil {
+					return false
+				}
+				hasDefaults = true
+			} else if hasDefaults {
+				p.errorAt(ident.Token.Pos, "parameter %s without a default cannot follow a parameter with a default", ident.Value)
+				return false
+			}
+			fn.Parameters = append(fn.Parameters, ident)
+			fn.Defaults = append(fn.Defaults, def)
+		}
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !hasDefaults {
+		fn.Defaults = nil
+	}
+	return p.expectPeek(token.RPAREN)
+}
+
 func (p *Parser) parseCallArguments() []ast.Expression {
 	args := []ast.Expression{}
 
@@ -184,8 +292,7 @@ func (p *Parser) expectPeek(tokenType token.TokenType) bool {
 func (p *Parser) peekTokenIs(tokenType token.TokenType) bool { return p.peekToken.Type == tokenType }
 func (p *Parser) curTokenIs(tokenType token.TokenType) bool  { return p.curToken.Type == tokenType }
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, but got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.peekToken.Pos, "expected next token to be %s, but got %s instead", t, p.peekToken.Type)
 }
 
 func (p *Parser) peekPrecedence() int {
@@ -211,6 +318,14 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 }
 
 func (p *Parser) notPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
+	p.errorAt(p.curToken.Pos, "no prefix parse function for %s found", t)
+}
+
+// errorAt records a parser error prefixed with the source position it refers to
+func (p *Parser) errorAt(pos token.Position, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if pos.IsValid() {
+		msg = pos.String() + ": " + msg
+	}
 	p.errors = append(p.errors, msg)
 }
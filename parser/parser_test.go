@@ -3,6 +3,7 @@ package parser
 import (
 	"mutant/ast"
 	"mutant/lexer"
+	"strings"
 	"testing"
 )
 
@@ -268,6 +269,24 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		{"add(a + b + c * d / f + g)", "add((((a + b) + ((c * d) / f)) + g))"},
 		{"a * [1, 2, 3, 4][b * c] * d", "((a * ([1, 2, 3, 4][(b * c)])) * d)"},
 		{"add(a * b[2], b[1], 2 * [1, 2][1])", "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))"},
+		{"a || b && c", "(a || (b && c))"},
+		{"a == b && c < d || !e", "(((a == b) && (c < d)) || (!e))"},
+		{"x = a || b", "(x = (a || b))"},
+		{"a % b * c", "((a % b) * c)"},
+		{"a + b % c", "(a + (b % c))"},
+		{"a <= b == c >= d", "((a <= b) == (c >= d))"},
+		{"a ** b ** c", "(a ** (b ** c))"},
+		{"-a ** b", "(-(a ** b))"},
+		{"a * b ** c", "(a * (b ** c))"},
+		{"a | b ^ c & d", "(a | (b ^ (c & d)))"},
+		{"a & b == c", "((a & b) == c)"},
+		{"a << b + c", "(a << (b + c))"},
+		{"a & b << c", "(a & (b << c))"},
+		{"a < b | c", "(a < (b | c))"},
+		{"x += a * b", "(x = (x + (a * b)))"},
+		{"x -= 1", "(x = (x - 1))"},
+		{"x *= y = 2", "(x = (x * (y = 2)))"},
+		{"p.x /= 2", "((p.x) = ((p.x) / 2))"},
 	}
 
 	for _, tt := range tests {
@@ -456,6 +475,67 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestDefaultAndRestParameterParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(x, timeout = 3000) { x }", "fn(x, timeout = 3000) x"},
+		{"fn(a = 1, b = a * 2) { b }", "fn(a = 1, b = (a * 2)) b"},
+		{"fn(first, ...rest) { rest }", "fn(first, ...rest) rest"},
+		{"fn(...all) { all }", "fn(...all) all"},
+		{"fn(x, y = [1, 2], ...more) { x }", "fn(x, y = [1, 2], ...more) x"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function, ok := stmt.Expression.(*ast.FunctionLiteral)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T", stmt.Expression)
+		}
+		if got := function.String(); got != tt.expected {
+			t.Errorf("wrong function string. want=%q, got=%q", tt.expected, got)
+		}
+	}
+
+	p := New(lexer.New("fn(x, y = 2, ...z) { x }"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	function := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if len(function.Parameters) != 2 || function.NumDefaults() != 1 || function.DefaultFor(0) != nil {
+		t.Fatalf("wrong parameters. got=%v defaults=%v", function.Parameters, function.Defaults)
+	}
+	if function.Rest == nil || function.Rest.Value != "z" {
+		t.Fatalf("wrong rest parameter. got=%v", function.Rest)
+	}
+}
+
+func TestDefaultAndRestParameterErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(a = 1, b) { b }", "1:11: parameter b without a default cannot follow a parameter with a default"},
+		{"fn(...r, b) { b }", "1:7: rest parameter ...r must be the last parameter"},
+		{"fn(...r = []) { r }", "1:7: rest parameter ...r cannot have a default value"},
+		{"fn(1) { 1 }", "1:4: expected parameter name, got INT"},
+		{"struct P { fn m(...self) { 1 } }", "1:20: method m cannot declare a parameter named self"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong parser errors for %q. want first=%q, got=%q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := "add(1, 2 * 3, 4 + 5);"
 
@@ -826,6 +906,161 @@ func TestForStatementParsing(t *testing.T) {
 	}
 }
 
+func TestForInStatementParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		key      string
+		value    string
+		iterable string
+	}{
+		{"for (x in xs) { x; }", "", "x", "xs"},
+		{"for (k, v in {\"a\": 1}) { v; }", "k", "v", "{a:1}"},
+		{"for (i in 0..n + 1) { i; }", "", "i", "(0..(n + 1))"},
+		{"for (ch in \"abc\") { ch; }", "", "ch", "abc"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.ForInStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ForInStatement. got=%T", program.Statements[0])
+		}
+		if tt.key == "" && stmt.Key != nil {
+			t.Errorf("expected no key binding, got=%s", stmt.Key)
+		}
+		if tt.key != "" && (stmt.Key == nil || stmt.Key.Value != tt.key) {
+			t.Errorf("wrong key binding. expected=%s, got=%v", tt.key, stmt.Key)
+		}
+		if stmt.Value.Value != tt.value {
+			t.Errorf("wrong value binding. expected=%s, got=%s", tt.value, stmt.Value.Value)
+		}
+		if stmt.Iterable.String() != tt.iterable {
+			t.Errorf("wrong iterable. expected=%s, got=%s", tt.iterable, stmt.Iterable.String())
+		}
+		if len(stmt.Body.Statements) != 1 {
+			t.Fatalf("for body should have 1 statement. got=%d", len(stmt.Body.Statements))
+		}
+	}
+}
+
+func TestImportStatementParsing(t *testing.T) {
+	tests := []struct {
+		input string
+		path  string
+		name  string
+	}{
+		{`import "lib/util.mut"`, "lib/util.mut", "util"},
+		{`import util;`, "util.mut", "util"},
+		{`import "lib/string-tools.mut" as st`, "lib/string-tools.mut", "st"},
+		{`import util as u;`, "util.mut", "u"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.ImportStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ImportStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Path != tt.path {
+			t.Errorf("wrong import path. expected=%q, got=%q", tt.path, stmt.Path)
+		}
+		if stmt.Name.Value != tt.name {
+			t.Errorf("wrong namespace. expected=%q, got=%q", tt.name, stmt.Name.Value)
+		}
+	}
+}
+
+func TestImportStatementErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import 5`, "1:8: expected module path or name after import, got INT"},
+		{`import "lib/string-tools.mut"`, "1:8: cannot derive a module name from \"lib/string-tools.mut\", use `as name`"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong parser errors for %q. want first=%q, got=%q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestTryStatementParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`try { risky(); } catch (e) { puts(e); }`, "try { risky() } catch (e) { puts(e) }"},
+		{`try { risky(); } finally { done(); }`, "try { risky() } finally { done() }"},
+		{`try { risky(); } catch { retry(); } finally { done(); }`, "try { risky() } catch { retry() } finally { done() }"},
+		{`throw "bad input";`, "throw bad input;"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+		if got := program.Statements[0].String(); got != tt.expected {
+			t.Errorf("wrong statement. expected=%q, got=%q", tt.expected, got)
+		}
+	}
+
+	p := New(lexer.New(`try { a; } catch (err) { b; }`))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt, ok := program.Statements[0].(*ast.TryStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.TryStatement. got=%T", program.Statements[0])
+	}
+	if stmt.CatchParam == nil || stmt.CatchParam.Value != "err" {
+		t.Errorf("wrong catch binding. got=%v", stmt.CatchParam)
+	}
+	if stmt.Finally != nil {
+		t.Errorf("expected no finally block. got=%s", stmt.Finally.String())
+	}
+}
+
+func TestTryStatementErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`try { a; }`, "1:1: try requires a catch or finally block"},
+		{`try { a; } catch (1) { b; }`, "1:19: expected next token to be IDENT, but got INT instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong parser errors for %q. want first=%q, got=%q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
 func TestBreakAndContinueParsing(t *testing.T) {
 	input := `for (; true; ) { continue; break; }`
 
@@ -847,6 +1082,70 @@ func TestBreakAndContinueParsing(t *testing.T) {
 	}
 }
 
+func TestDestructureStatementParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		kind     ast.PatternKind
+		names    []string
+		rest     string
+		expected string
+	}{
+		{`let [a, _, b, ...rest] = xs;`, ast.ArrayPattern, []string{"a", "_", "b"}, "rest", "let [a, _, b, ...rest] = xs;"},
+		{`let [...all] = xs;`, ast.ArrayPattern, nil, "all", "let [...all] = xs;"},
+		{`let {status, body} = resp;`, ast.HashPattern, []string{"status", "body"}, "", "let {status, body} = resp;"},
+		{`let Point {x, y} = p;`, ast.StructPattern, []string{"x", "y"}, "", "let Point {x, y} = p;"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.DestructureStatement)
+		if !ok {
+			t.Fatalf("%q: statement is not ast.DestructureStatement. got=%T", tt.input, program.Statements[0])
+		}
+		if stmt.Kind != tt.kind || len(stmt.Names) != len(tt.names) {
+			t.Fatalf("%q: wrong pattern kind=%d names=%d", tt.input, stmt.Kind, len(stmt.Names))
+		}
+		for i, name := range tt.names {
+			if stmt.Names[i].Value != name {
+				t.Errorf("%q: wrong name %d. want=%s, got=%s", tt.input, i, name, stmt.Names[i].Value)
+			}
+		}
+		if (stmt.Rest == nil && tt.rest != "") || (stmt.Rest != nil && stmt.Rest.Value != tt.rest) {
+			t.Errorf("%q: wrong rest binding. got=%v", tt.input, stmt.Rest)
+		}
+		if stmt.String() != tt.expected {
+			t.Errorf("wrong string. want=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestDestructureStatementErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let [a, ...r, b] = xs;`, "1:13: rest binding must be the last element of an array pattern"},
+		{`let {a, ...r} = h;`, "1:9: rest bindings are only supported in array patterns"},
+		{`let [a, a] = xs;`, "1:9: duplicate binding a in destructuring pattern"},
+		{`let {_} = h;`, "1:6: `_` can only skip elements of an array pattern"},
+		{`let [1] = xs;`, "1:6: expected identifier in destructuring pattern, got INT"},
+		{`let {a b} = h;`, "1:8: expected ',' or '}' in destructuring pattern, got IDENT"},
+		{`let [] = xs;`, "1:1: destructuring pattern binds no names"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong parser errors for %q. want first=%q, got=%q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
 func TestStructStatementParsing(t *testing.T) {
 	input := `struct Point { x; y; };`
 
@@ -871,6 +1170,35 @@ func TestStructStatementParsing(t *testing.T) {
 	}
 }
 
+func TestStructMethodParsing(t *testing.T) {
+	input := `struct Point { x; y; fn add(o) { self.x + o.x }; fn zero() { 0 } }`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.StructStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.StructStatement. got=%T", program.Statements[0])
+	}
+	if len(stmt.Fields) != 2 || len(stmt.Methods) != 2 {
+		t.Fatalf("expected 2 fields and 2 methods, got=%d and %d", len(stmt.Fields), len(stmt.Methods))
+	}
+	add := stmt.Methods[0]
+	if add.Name.Value != "add" || len(add.Function.Parameters) != 1 || add.Function.Parameters[0].Value != "o" {
+		t.Fatalf("wrong first method. got=%s", add)
+	}
+	if got, want := stmt.String(), "struct Point { x; y; fn add(o) ((self.x) + (o.x)) fn zero() 0 }"; got != want {
+		t.Errorf("wrong struct string. want=%q, got=%q", want, got)
+	}
+
+	p = New(lexer.New(`struct P { fn m(self) { 1 } }`))
+	p.ParseProgram()
+	if errors := p.Errors(); len(errors) == 0 || errors[0] != "1:17: method m cannot declare a parameter named self" {
+		t.Errorf("wrong parser errors for explicit self. got=%q", errors)
+	}
+}
+
 func TestEnumStatementParsing(t *testing.T) {
 	input := `enum Color { Red, Green, Blue };`
 
@@ -892,6 +1220,82 @@ func TestEnumStatementParsing(t *testing.T) {
 	}
 }
 
+func TestEnumPayloadVariantParsing(t *testing.T) {
+	input := `enum Result { Ok(value), Err(message), Pending }`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.EnumStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.EnumStatement. got=%T", program.Statements[0])
+	}
+	if len(stmt.Payloads) != 2 || stmt.Payloads["Ok"].Value != "value" || stmt.Payloads["Err"].Value != "message" {
+		t.Fatalf("wrong enum payloads. got=%v", stmt.Payloads)
+	}
+	if got, want := stmt.String(), "enum Result { Ok(value), Err(message), Pending }"; got != want {
+		t.Errorf("wrong enum string. want=%q, got=%q", want, got)
+	}
+}
+
+func TestMatchExpressionParsing(t *testing.T) {
+	input := `match (r) { Result.Ok(v) => v, Result.Err(_) => { 0 }, 1 => "one", -2 => "neg", _ => null }`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	match, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("expression is not ast.MatchExpression. got=%T", stmt.Expression)
+	}
+	if !testIdentifier(t, match.Subject, "r") {
+		return
+	}
+	if len(match.Arms) != 5 {
+		t.Fatalf("expected 5 match arms, got=%d", len(match.Arms))
+	}
+
+	ok0 := match.Arms[0]
+	if ok0.Enum.Value != "Result" || ok0.Tag.Value != "Ok" || ok0.Binding.Value != "v" {
+		t.Errorf("wrong first arm pattern. got=%s", ok0.Pattern())
+	}
+	if match.Arms[1].Binding != nil || match.Arms[1].Tag.Value != "Err" {
+		t.Errorf("`_` payload should not bind. got=%s", match.Arms[1].Pattern())
+	}
+	if !testLiteralExpression(t, match.Arms[2].Literal, 1) {
+		return
+	}
+	if !match.Arms[4].Wildcard {
+		t.Errorf("last arm should be a wildcard. got=%s", match.Arms[4].Pattern())
+	}
+}
+
+func TestMatchExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match (x) { }`, "1:1: match requires at least one arm"},
+		{`match (x) { y => 1 }`, "1:13: invalid match pattern y"},
+		{`match (x) { a + 1 => 1 }`, "1:13: invalid match pattern a"},
+		{`match (x) { [1] => 1 }`, "1:13: invalid match pattern ["},
+		{`match (x) { 1 => 1 2 => 2 }`, "1:20: expected ',' or '}' after match arm, got INT"},
+		{`enum E { A(x, y) }`, "1:13: enum variant A carries a single payload"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong parser errors for %q. want first=%q, got=%q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
 func TestFieldExpressionParsing(t *testing.T) {
 	input := `Color.Red;`
 
@@ -962,3 +1366,64 @@ func TestAssignmentExpressionParsing(t *testing.T) {
 		t.Fatalf("assignment target should be ast.FieldExpression. got=%T", assign.Left)
 	}
 }
+
+func TestParserErrorsIncludePositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 5;\nlet = 10;", "2:5: expected next token to be IDENT, but got = instead"},
+		{"struct Point {\n  x;\n  5;\n}", "3:3: expected struct field identifier, got INT"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q", tt.input)
+		}
+		if errors[0] != tt.expected {
+			t.Errorf("wrong first error. want=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestParseProgramKeepsCommentsAndLexerErrors(t *testing.T) {
+	p := New(lexer.New("// answer\nlet x = 42;"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("expected 1 statement, got=%d", len(program.Statements))
+	}
+	if len(program.Comments) != 1 || program.Comments[0].Literal != "// answer" {
+		t.Fatalf("unexpected program comments: %+v", program.Comments)
+	}
+
+	p = New(lexer.New("let x = 1; /* open"))
+	p.ParseProgram()
+	errors := p.Errors()
+	if len(errors) == 0 || errors[0] != "1:12: unterminated block comment" {
+		t.Fatalf("expected unterminated comment error first, got %v", errors)
+	}
+}
+
+func TestTemplateLiteralLowersToConcatenation(t *testing.T) {
+	p := New(lexer.New(`"a ${x + 1} b"`))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	if got := stmt.Expression.String(); got != "((a  + str((x + 1))) +  b)" {
+		t.Fatalf("unexpected lowering, got=%q", got)
+	}
+
+	p = New(lexer.New("let s = \"line\n${1 +}\";"))
+	p.ParseProgram()
+	errors := p.Errors()
+	if len(errors) == 0 || !strings.HasPrefix(errors[0], "2:") {
+		t.Fatalf("expected interpolation error on line 2, got %v", errors)
+	}
+}
@@ -162,6 +162,7 @@ func Start(in io.Reader, out io.Writer, version string, enableMacros bool) {
 			errrs.PrintCompilerError(out, err.Error())
 			continue
 		}
+		errrs.PrintCompilerWarnings(out, comp.Warnings())
 
 		byteCode := comp.ByteCode()
 		byteCode = mutil.EncryptByteCode(byteCode, replPassword)
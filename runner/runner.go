@@ -18,6 +18,7 @@ import (
 	"mutant/vm"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 var (
@@ -34,6 +35,11 @@ func Run(srcpath string, password string, secureMode bool, enforceSignerAuth boo
 		}()
 	}
 
+	capabilityPolicy, err := security.ResolveBuiltinCapabilityPolicy()
+	if err != nil {
+		return err, errrs.ERROR
+	}
+
 	signedCode, err := os.ReadFile(srcpath)
 	if err != nil {
 		return err, errrs.ERROR
@@ -76,6 +82,10 @@ func Run(srcpath string, password string, secureMode bool, enforceSignerAuth boo
 		}
 	}
 
+	if err := enforceCapabilityManifest(signedCode, secureMode, capabilityPolicy); err != nil {
+		return err, errrs.ERROR
+	}
+
 	if err := enforceAntiRev(secureMode, "pre-decode"); err != nil {
 		return err, errrs.ERROR
 	}
@@ -89,7 +99,58 @@ func Run(srcpath string, password string, secureMode bool, enforceSignerAuth boo
 		return err, errrs.ERROR
 	}
 
-	return runvm(bytecode, password, secureMode)
+	return runvm(bytecode, password, secureMode, capabilityPolicy)
+}
+
+// enforceCapabilityManifest refuses to decode programs whose embedded manifest requests
+// capabilities the active policy does not grant. Artifacts without a manifest are still
+// covered by the VM's per-call capability check.
+func enforceCapabilityManifest(signedCode []byte, secureMode bool, capabilityPolicy map[string]struct{}) error {
+	manifest, found, err := security.ReadCapabilityManifest(signedCode)
+	if err != nil {
+		if !found {
+			// Envelope parse errors are reported by signature verification and decode.
+			return nil
+		}
+		security.RecordSignatureFailure("capability-manifest")
+		return security.ApplyTamperResponse("signature_failed", "capability-manifest", secureMode, err)
+	}
+	if !found {
+		return nil
+	}
+
+	missing := manifest.MissingCapabilities(capabilityPolicy)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	security.RecordCapabilityDenied("pre-decode-manifest")
+	return fmt.Errorf("%w: program requires %s", security.ErrCapabilityDenied, strings.Join(missing, ", "))
+}
+
+// ReadCapabilityManifest loads the capability manifest from a compiled file or standalone binary.
+func ReadCapabilityManifest(srcpath string) (*security.CapabilityManifest, string, bool, error) {
+	signedCode, err := os.ReadFile(srcpath)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	signedCode, err = extractStandaloneSignedCode(signedCode)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	signer, err := security.SignerPublicKeyHex(signedCode)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	manifest, found, err := security.ReadCapabilityManifest(signedCode)
+	if err != nil {
+		return nil, signer, found, err
+	}
+
+	return manifest, signer, found, nil
 }
 
 func enforceAntiRev(secureMode bool, stage string) error {
@@ -277,13 +338,14 @@ func deriveStandaloneTailCanary(payload []byte, checksum []byte) []byte {
 	return digest[:8]
 }
 
-func runvm(bytecode *compiler.ByteCode, password string, secureMode bool) (error, errrs.ErrorType) {
-	if err := executeLuaPatchesBeforeVM(bytecode, password, secureMode); err != nil {
+func runvm(bytecode *compiler.ByteCode, password string, secureMode bool, capabilityPolicy map[string]struct{}) (error, errrs.ErrorType) {
+	if err := executeLuaPatchesBeforeVM(bytecode, password, secureMode, capabilityPolicy); err != nil {
 		return err, errrs.ERROR
 	}
 
 	globals := make([]object.Object, global.GlobalSize)
 	machine := vm.NewWithPasswordAndGlobalStoreMode(bytecode, password, globals, secureMode)
+	machine.SetBuiltinCapabilityPolicy(capabilityPolicy)
 	defer machine.CleanupSensitiveData(true)
 
 	if err := machine.Run(); err != nil {
@@ -297,14 +359,14 @@ func runvm(bytecode *compiler.ByteCode, password string, secureMode bool) (error
 	return nil, ""
 }
 
-func executeLuaPatchesBeforeVM(bytecode *compiler.ByteCode, password string, secureMode bool) error {
+func executeLuaPatchesBeforeVM(bytecode *compiler.ByteCode, password string, secureMode bool, capabilityPolicy map[string]struct{}) error {
 	if bytecode == nil || len(bytecode.LuaPatches) == 0 {
 		return nil
 	}
 
 	ctx := &luaruntime.APIContext{
 		Globals:             map[string]object.Object{},
-		BuiltinCapabilities: resolveLuaBuiltinCapabilities(),
+		BuiltinCapabilities: security.BuiltinCapabilityList(capabilityPolicy),
 	}
 
 	if err := executeLuaPatches(bytecode.LuaPatches, password, len(bytecode.Instructions), ctx); err != nil {
@@ -315,15 +377,6 @@ func executeLuaPatchesBeforeVM(bytecode *compiler.ByteCode, password string, sec
 	return nil
 }
 
-func resolveLuaBuiltinCapabilities() []string {
-	defaults := security.DefaultBuiltinCapabilityPolicy()
-	caps := make([]string, 0, len(defaults))
-	for capability := range defaults {
-		caps = append(caps, capability)
-	}
-	return caps
-}
-
 func registerTypes() {
 	gob.Register(&object.Float{})
 	gob.Register(&object.Integer{})
@@ -340,8 +393,10 @@ func registerTypes() {
 	gob.Register(&object.Macro{})
 	gob.Register(&object.CompiledFunction{})
 	gob.Register(&object.Closure{})
+	gob.Register(&object.Cell{})
 	gob.Register(&object.Encrypted{})
 	gob.Register(&object.Struct{})
 	gob.Register(&object.EnumValue{})
+	gob.Register(&object.BoundMethod{})
 	gob.Register(&object.LuaPatch{})
 }
@@ -207,6 +207,45 @@ func TestRunSecureModeWithoutSignerAuthFlagSkipsSignatureVerification(t *testing
 	}
 }
 
+func TestRunRejectsManifestCapabilitiesBeforeDecode(t *testing.T) {
+	keyPair, err := security.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %v", err)
+	}
+
+	manifest := security.NewCapabilityManifest([]security.CapabilityManifestEntry{
+		{Builtin: "cmd", Capability: security.CapabilityCommandExec},
+	})
+	signed, err := security.SignCodeWithManifest("not-valid-metadata", manifest, keyPair.PrivateKey)
+	if err != nil {
+		t.Fatalf("failed to sign payload: %v", err)
+	}
+	path := writeTempPayload(t, signed)
+
+	t.Setenv(security.BuiltinCapabilitiesEnv, "none")
+	err, errType := Run(path, "", false, false)
+	if !errors.Is(err, security.ErrCapabilityDenied) {
+		t.Fatalf("expected ErrCapabilityDenied, got: %v", err)
+	}
+	if errType != errrs.ERROR {
+		t.Fatalf("expected errrs.ERROR, got %q", errType)
+	}
+
+	t.Setenv(security.BuiltinCapabilitiesEnv, security.CapabilityCommandExec)
+	err, _ = Run(path, "", false, false)
+	if err == nil || errors.Is(err, security.ErrCapabilityDenied) {
+		t.Fatalf("expected granted manifest to proceed to decode failure, got: %v", err)
+	}
+
+	read, signer, found, err := ReadCapabilityManifest(path)
+	if err != nil || !found {
+		t.Fatalf("expected manifest to be readable, found=%v err=%v", found, err)
+	}
+	if signer != toHex(t, keyPair.PublicKey) || len(read.Capabilities) != 1 {
+		t.Fatalf("unexpected manifest info: signer=%s manifest=%+v", signer, read)
+	}
+}
+
 func TestExtractStandaloneSignedCodeRejectsProvenanceMismatch(t *testing.T) {
 	payload := []byte("signed-payload")
 	binaryData := makeStandaloneBinaryBlob(t, payload)
@@ -272,7 +311,7 @@ func TestExecuteLuaPatchesBeforeVMSucceeds(t *testing.T) {
 		},
 	}
 
-	err = executeLuaPatchesBeforeVM(bytecode, password, true)
+	err = executeLuaPatchesBeforeVM(bytecode, password, true, security.DefaultBuiltinCapabilityPolicy())
 	if err != nil {
 		t.Fatalf("expected patch execution to succeed, got: %v", err)
 	}
@@ -299,13 +338,13 @@ func TestExecuteLuaPatchesBeforeVMEnforcesTamperPolicy(t *testing.T) {
 	}
 
 	t.Setenv(security.TamperResponseEnv, security.TamperResponseTerminate)
-	err = executeLuaPatchesBeforeVM(bytecode, password, true)
+	err = executeLuaPatchesBeforeVM(bytecode, password, true, security.DefaultBuiltinCapabilityPolicy())
 	if err == nil {
 		t.Fatalf("expected secure mode terminate policy to fail")
 	}
 
 	t.Setenv(security.TamperResponseEnv, security.TamperResponseWarn)
-	err = executeLuaPatchesBeforeVM(bytecode, password, false)
+	err = executeLuaPatchesBeforeVM(bytecode, password, false, security.DefaultBuiltinCapabilityPolicy())
 	if err != nil {
 		t.Fatalf("expected compat warn policy to continue, got: %v", err)
 	}
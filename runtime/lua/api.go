@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"math"
 
+	"mutant/builtin"
 	"mutant/object"
+	"mutant/security"
 
 	lua "github.com/yuin/gopher-lua"
 )
@@ -15,7 +17,7 @@ type APIContext struct {
 	// Globals map holds read-only access to Mutant global variables
 	Globals map[string]object.Object
 
-	// BuiltinCapabilities describes which builtins the patch can access
+	// BuiltinCapabilities lists the capability groups granted by the runtime policy
 	BuiltinCapabilities []string
 
 	// PatchName is the name of the executing patch (for logging/debugging)
@@ -56,17 +58,15 @@ func RegisterMutantAPI(vm *SandboxedVM, ctx *APIContext) error {
 		return 1
 	}))
 
-	// Register mutant.can_use_builtin(name) - check if patch has capability
+	// Register mutant.can_use_builtin(name) - check if the runtime policy grants the builtin's capability
+	granted := make(map[string]struct{}, len(ctx.BuiltinCapabilities))
+	for _, capability := range ctx.BuiltinCapabilities {
+		granted[capability] = struct{}{}
+	}
 	state.SetField(mutantTable, "can_use_builtin", state.NewFunction(func(l *lua.LState) int {
 		name := l.CheckString(1)
-		hasCapability := false
-		for _, cap := range ctx.BuiltinCapabilities {
-			if cap == name {
-				hasCapability = true
-				break
-			}
-		}
-		l.Push(lua.LBool(hasCapability))
+		capability, known := builtin.BuiltinCapability(name)
+		l.Push(lua.LBool(known && security.BuiltinCapabilityAllowed(granted, capability)))
 		return 1
 	}))
 
@@ -213,7 +213,7 @@ func luaToObjectValue(value lua.LValue) object.Object {
 			hashKey, ok := key.(object.Hashable)
 			if ok {
 				pair := object.HashPair{Key: key, Value: value}
-				hashObj.Pairs[hashKey.HashKey()] = pair
+				hashObj.Set(hashKey.HashKey(), pair)
 			}
 		})
 		return hashObj
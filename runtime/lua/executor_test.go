@@ -35,6 +35,49 @@ func TestExecutePatchesRunsEncryptedPatch(t *testing.T) {
 	}
 }
 
+func TestExecutePatchesCanUseBuiltinFollowsCapabilityPolicy(t *testing.T) {
+	password := "lua-capability-test"
+	inslen := 222
+	ctx := &APIContext{
+		Globals:             map[string]object.Object{},
+		BuiltinCapabilities: []string{security.CapabilityNetwork},
+	}
+
+	tests := []struct {
+		source  string
+		wantErr bool
+	}{
+		{`if not mutant.can_use_builtin("http_get") then error("denied") end`, false},
+		{`if not mutant.can_use_builtin("len") then error("denied") end`, false},
+		{`if mutant.can_use_builtin("fs_read") then return 1 end error("denied")`, true},
+		{`if mutant.can_use_builtin("no_such_builtin") then return 1 end error("denied")`, true},
+	}
+
+	for _, tt := range tests {
+		plaintext := []byte(tt.source)
+		encrypted, err := security.SecureXOR(plaintext, int64(inslen), password)
+		if err != nil {
+			t.Fatalf("failed to encrypt patch payload: %v", err)
+		}
+
+		patches := map[string]*object.LuaPatch{
+			"capability_patch": {
+				Name:             "capability_patch",
+				EncryptedPayload: encrypted,
+				ChecksumExpected: object.ComputeChecksum(plaintext),
+			},
+		}
+
+		err = ExecutePatches(patches, password, inslen, ctx)
+		if tt.wantErr && err == nil {
+			t.Fatalf("expected %q to be denied", tt.source)
+		}
+		if !tt.wantErr && err != nil {
+			t.Fatalf("expected %q to be allowed, got: %v", tt.source, err)
+		}
+	}
+}
+
 func TestExecutePatchesRejectsUnsafeGlobals(t *testing.T) {
 	password := "lua-sandbox-test"
 	inslen := 123
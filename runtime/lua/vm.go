@@ -59,17 +59,25 @@ func (vm *SandboxedVM) Open() error {
 	if vm.state == nil {
 		return fmt.Errorf("failed to create Lua state")
 	}
-	vm.state.OpenLibs()
 
-	// Validate allowed library names to keep policy intent explicit.
+	// Open only the whitelisted libraries; os, io and friends stay unreachable.
 	for _, libName := range vm.config.AllowedLibs {
 		switch libName {
-		case "math", "string", "table", "base":
-			continue
+		case "math":
+			lua.OpenMath(vm.state)
+		case "string":
+			lua.OpenString(vm.state)
+		case "table":
+			lua.OpenTable(vm.state)
+		case "base":
+			lua.OpenBase(vm.state)
 		default:
+			vm.state.Close()
+			vm.state = nil
 			return fmt.Errorf("unsupported library: %s", libName)
 		}
 	}
+	stripUnsafeGlobals(vm.state)
 
 	return nil
 }
@@ -0,0 +1,192 @@
+package security
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CapabilityManifestTag marks the optional manifest section of a signed envelope.
+// Format: HEADER|-|ENCODED_DATA|-|CAPS|MANIFEST_HEX|MANIFEST_SIGNATURE_HEX|-|SIGNATURE|-|PUBKEY|-|FOOTER
+const CapabilityManifestTag = "CAPS"
+
+const capabilityManifestVersion = 1
+
+// ErrCapabilityManifestInvalid error is returned when a manifest section cannot be parsed or verified
+var ErrCapabilityManifestInvalid = errors.New("invalid capability manifest")
+
+// CapabilityManifestEntry records one capability-gated builtin referenced by a program.
+type CapabilityManifestEntry struct {
+	Builtin    string `json:"builtin"`
+	Capability string `json:"capability"`
+}
+
+// CapabilityManifest lists the capability groups a compiled program may request at run time.
+type CapabilityManifest struct {
+	Version      int                       `json:"version"`
+	Capabilities []string                  `json:"capabilities"`
+	Builtins     []CapabilityManifestEntry `json:"builtins"`
+}
+
+// NewCapabilityManifest builds a manifest with deterministic ordering from builtin references.
+func NewCapabilityManifest(entries []CapabilityManifestEntry) *CapabilityManifest {
+	manifest := &CapabilityManifest{
+		Version:      capabilityManifestVersion,
+		Capabilities: []string{},
+		Builtins:     []CapabilityManifestEntry{},
+	}
+
+	seenBuiltins := map[string]struct{}{}
+	seenCapabilities := map[string]struct{}{}
+	for _, entry := range entries {
+		if entry.Capability == "" {
+			continue
+		}
+		if _, ok := seenBuiltins[entry.Builtin]; !ok {
+			seenBuiltins[entry.Builtin] = struct{}{}
+			manifest.Builtins = append(manifest.Builtins, entry)
+		}
+		if _, ok := seenCapabilities[entry.Capability]; !ok {
+			seenCapabilities[entry.Capability] = struct{}{}
+			manifest.Capabilities = append(manifest.Capabilities, entry.Capability)
+		}
+	}
+
+	sort.Strings(manifest.Capabilities)
+	sort.Slice(manifest.Builtins, func(i, j int) bool {
+		return manifest.Builtins[i].Builtin < manifest.Builtins[j].Builtin
+	})
+
+	return manifest
+}
+
+// MissingCapabilities returns the manifest capabilities the policy does not grant.
+func (m *CapabilityManifest) MissingCapabilities(policy map[string]struct{}) []string {
+	missing := []string{}
+	for _, capability := range m.Capabilities {
+		if !BuiltinCapabilityAllowed(policy, capability) {
+			missing = append(missing, capability)
+		}
+	}
+	return missing
+}
+
+// SignCodeWithManifest signs the encoded payload and embeds a manifest signed by the same key.
+// The manifest signature also covers the payload digest so it cannot be moved to another artifact.
+func SignCodeWithManifest(encodedString string, manifest *CapabilityManifest, privateKey []byte) ([]byte, error) {
+	if manifest == nil {
+		return SignCode(encodedString, privateKey)
+	}
+
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	manifestSignature, err := SignBytecode(capabilityManifestSigningInput(manifestJSON, encodedString), privateKey, "v2.1.0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign capability manifest: %w", err)
+	}
+
+	section := strings.Join([]string{
+		CapabilityManifestTag,
+		hex.EncodeToString(manifestJSON),
+		hex.EncodeToString(manifestSignature.Signature),
+	}, SEPERATOR)
+
+	signed, err := SignCode(encodedString, privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Insert the manifest section between the payload and the payload signature.
+	prefix := HEADER + OUTER_SEPERATOR + encodedString + OUTER_SEPERATOR
+	rest := strings.TrimPrefix(string(signed), prefix)
+	return []byte(prefix + section + OUTER_SEPERATOR + rest), nil
+}
+
+// ReadCapabilityManifest extracts and verifies the manifest section of a signed envelope.
+// The boolean result is false for artifacts built before manifests were embedded.
+func ReadCapabilityManifest(signedCode []byte) (*CapabilityManifest, bool, error) {
+	encodedData, _, publicKey, err := parseSignedCode(signedCode)
+	if err != nil {
+		return nil, false, err
+	}
+
+	section, found := findCapabilityManifestSection(signedCode)
+	if !found {
+		return nil, false, nil
+	}
+
+	fields := strings.Split(section, SEPERATOR)
+	if len(fields) != 3 {
+		return nil, true, ErrCapabilityManifestInvalid
+	}
+
+	manifestJSON, err := hex.DecodeString(fields[1])
+	if err != nil {
+		return nil, true, fmt.Errorf("%w: %v", ErrCapabilityManifestInvalid, err)
+	}
+	signature, err := hex.DecodeString(fields[2])
+	if err != nil {
+		return nil, true, fmt.Errorf("%w: %v", ErrCapabilityManifestInvalid, err)
+	}
+
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, true, fmt.Errorf("%w: invalid public key size", ErrCapabilityManifestInvalid)
+	}
+
+	codeSignature := &CodeSignature{
+		PublicKey: publicKey,
+		Signature: signature,
+		Algorithm: "Ed25519",
+		Version:   "v2.1.0",
+	}
+	if err := VerifyBytecode(capabilityManifestSigningInput(manifestJSON, encodedData), codeSignature); err != nil {
+		return nil, true, fmt.Errorf("%w: %v", ErrCapabilityManifestInvalid, err)
+	}
+
+	var manifest CapabilityManifest
+	if err := json.Unmarshal(manifestJSON, &manifest); err != nil {
+		return nil, true, fmt.Errorf("%w: %v", ErrCapabilityManifestInvalid, err)
+	}
+	if manifest.Version != capabilityManifestVersion {
+		return nil, true, fmt.Errorf("%w: unsupported version %d", ErrCapabilityManifestInvalid, manifest.Version)
+	}
+
+	return &manifest, true, nil
+}
+
+// SignerPublicKeyHex returns the hex encoded public key embedded in a signed envelope.
+func SignerPublicKeyHex(signedCode []byte) (string, error) {
+	_, _, publicKey, err := parseSignedCode(signedCode)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(publicKey), nil
+}
+
+func findCapabilityManifestSection(signedCode []byte) (string, bool) {
+	parts := strings.Split(string(signedCode), OUTER_SEPERATOR)
+	// Sections between the payload and the payload signature are optional metadata.
+	for i := 2; i < len(parts)-3; i++ {
+		if strings.HasPrefix(parts[i], CapabilityManifestTag+SEPERATOR) {
+			return parts[i], true
+		}
+	}
+	return "", false
+}
+
+func capabilityManifestSigningInput(manifestJSON []byte, encodedData string) []byte {
+	payloadDigest := sha256.Sum256([]byte(encodedData))
+	input := make([]byte, 0, len(manifestJSON)+len(SEPERATOR)+sha256.Size)
+	input = append(input, manifestJSON...)
+	input = append(input, SEPERATOR...)
+	input = append(input, payloadDigest[:]...)
+	return input
+}
@@ -52,4 +52,14 @@ var (
 
 	// ErrUntrustedSigner error is returned when signed payload key does not match trusted key
 	ErrUntrustedSigner = errors.New("untrusted signer public key")
+
+	// ErrCapabilityDenied error is returned when a builtin needs a capability the policy does not grant
+	ErrCapabilityDenied = errors.New("builtin capability not granted")
+
+	// ErrIntegrityFailed is wrapped around every error a failed integrity or
+	// tamper check returns under the terminate policy
+	ErrIntegrityFailed = errors.New("integrity check failed")
+
+	// ErrUnknownCapability error is returned when a capability policy names an unknown group
+	ErrUnknownCapability = errors.New("unknown builtin capability")
 )
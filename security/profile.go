@@ -2,11 +2,15 @@ package security
 
 import (
 	"crypto/sha256"
+	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"unicode"
 )
 
 const ProtectionProfileEnv = "MUTANT_PROTECTION_PROFILE"
+const BuiltinCapabilitiesEnv = "MUTANT_BUILTIN_CAPABILITIES"
 
 const (
 	ProtectionProfileMinimal  = "minimal"
@@ -15,6 +19,14 @@ const (
 	defaultProtectionProfile  = ProtectionProfileStandard
 )
 
+// Capability groups gating risky builtins. CapabilityAll grants every group.
+const (
+	CapabilityAll         = "all"
+	CapabilityCommandExec = "command_exec"
+	CapabilityFilesystem  = "filesystem"
+	CapabilityNetwork     = "network"
+)
+
 func ResolveProtectionProfile() string {
 	configured := strings.ToLower(strings.TrimSpace(os.Getenv(ProtectionProfileEnv)))
 	switch configured {
@@ -65,8 +77,82 @@ func defaultTamperResponseForProfile(secureMode bool) string {
 	}
 }
 
+// InstructionCacheSettings returns how many decrypted basic blocks each VM
+// frame may cache under the active profile, and whether an evicted block is
+// re-encrypted and checked against the instruction stream before it is
+// zeroed. Paranoid caches nothing, so every opcode and operand is decrypted
+// at the moment it is used.
+func InstructionCacheSettings() (blocks int, reencrypt bool) {
+	switch ResolveProtectionProfile() {
+	case ProtectionProfileMinimal:
+		return 256, false
+	case ProtectionProfileParanoid:
+		return 0, false
+	default:
+		return 64, true
+	}
+}
+
 func DefaultBuiltinCapabilityPolicy() map[string]struct{} {
-	return map[string]struct{}{"all": {}}
+	if ResolveProtectionProfile() == ProtectionProfileMinimal {
+		return map[string]struct{}{CapabilityAll: {}}
+	}
+	return map[string]struct{}{}
+}
+
+// ResolveBuiltinCapabilityPolicy returns the explicit MUTANT_BUILTIN_CAPABILITIES
+// allow-list when set, otherwise the protection profile default.
+func ResolveBuiltinCapabilityPolicy() (map[string]struct{}, error) {
+	raw := strings.TrimSpace(os.Getenv(BuiltinCapabilitiesEnv))
+	if raw == "" {
+		return DefaultBuiltinCapabilityPolicy(), nil
+	}
+	return ParseBuiltinCapabilityPolicy(raw)
+}
+
+// ParseBuiltinCapabilityPolicy parses a comma or space separated capability list.
+// The value "none" grants nothing and is accepted for explicit deny-all policies.
+func ParseBuiltinCapabilityPolicy(raw string) (map[string]struct{}, error) {
+	policy := map[string]struct{}{}
+	fields := strings.FieldsFunc(strings.ToLower(raw), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+
+	for _, field := range fields {
+		switch field {
+		case "none":
+			continue
+		case CapabilityAll, CapabilityCommandExec, CapabilityFilesystem, CapabilityNetwork:
+			policy[field] = struct{}{}
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrUnknownCapability, field)
+		}
+	}
+
+	return policy, nil
+}
+
+// BuiltinCapabilityAllowed reports whether policy grants capability.
+// Builtins without a required capability are always allowed.
+func BuiltinCapabilityAllowed(policy map[string]struct{}, capability string) bool {
+	if capability == "" {
+		return true
+	}
+	if _, ok := policy[CapabilityAll]; ok {
+		return true
+	}
+	_, ok := policy[capability]
+	return ok
+}
+
+// BuiltinCapabilityList returns the granted capabilities in sorted order.
+func BuiltinCapabilityList(policy map[string]struct{}) []string {
+	caps := make([]string, 0, len(policy))
+	for capability := range policy {
+		caps = append(caps, capability)
+	}
+	sort.Strings(caps)
+	return caps
 }
 
 func DeriveStandaloneProvenance(payload []byte, checksum []byte, profileCode byte) [32]byte {
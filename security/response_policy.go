@@ -40,13 +40,24 @@ func ApplyTamperResponse(event, stage string, secureMode bool, baseErr error) er
 		fmt.Fprintf(os.Stderr, "[security] event=%s stage=%s action=delay\n", event, stage)
 		return nil
 	default:
-		if baseErr != nil {
-			return baseErr
+		if baseErr == nil {
+			baseErr = errors.New("security policy violation")
 		}
-		return errors.New("security policy violation")
+		return &tamperError{err: baseErr}
 	}
 }
 
+// tamperError is a failure the terminate policy ends the run with. It reads
+// as the underlying error and matches ErrIntegrityFailed, so callers such as
+// the VM's try handling can tell it from ordinary runtime errors.
+type tamperError struct {
+	err error
+}
+
+func (e *tamperError) Error() string { return e.err.Error() }
+
+func (e *tamperError) Unwrap() []error { return []error{ErrIntegrityFailed, e.err} }
+
 func resolveTamperDelay() time.Duration {
 	const defaultDelayMs = 250
 	raw := strings.TrimSpace(os.Getenv(TamperDelayMsEnv))
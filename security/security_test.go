@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -155,6 +156,65 @@ func TestVerifyCodeWithTrustedPublicKeyRequiresTrustedKey(t *testing.T) {
 	}
 }
 
+func TestCapabilityManifestRoundTripAndTamper(t *testing.T) {
+	keyPair, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %v", err)
+	}
+
+	manifest := NewCapabilityManifest([]CapabilityManifestEntry{
+		{Builtin: "puts", Capability: ""},
+		{Builtin: "http_get", Capability: CapabilityNetwork},
+		{Builtin: "cmd", Capability: CapabilityCommandExec},
+		{Builtin: "http_get", Capability: CapabilityNetwork},
+	})
+
+	signedCode, err := SignCodeWithManifest("payload", manifest, keyPair.PrivateKey)
+	if err != nil {
+		t.Fatalf("failed to sign payload with manifest: %v", err)
+	}
+
+	if err := VerifyCode(signedCode); err != nil {
+		t.Fatalf("expected payload signature to remain valid, got: %v", err)
+	}
+	if got := GetEncryptedCode(signedCode); got != "payload" {
+		t.Fatalf("expected payload to round-trip, got %q", got)
+	}
+
+	read, found, err := ReadCapabilityManifest(signedCode)
+	if err != nil || !found {
+		t.Fatalf("expected manifest to be found, found=%v err=%v", found, err)
+	}
+	if strings.Join(read.Capabilities, ",") != "command_exec,network" {
+		t.Fatalf("unexpected manifest capabilities: %v", read.Capabilities)
+	}
+	if len(read.Builtins) != 2 || read.Builtins[0].Builtin != "cmd" {
+		t.Fatalf("unexpected manifest builtins: %+v", read.Builtins)
+	}
+
+	missing := read.MissingCapabilities(map[string]struct{}{CapabilityNetwork: {}})
+	if len(missing) != 1 || missing[0] != CapabilityCommandExec {
+		t.Fatalf("expected command_exec to be missing, got %v", missing)
+	}
+
+	forged := NewCapabilityManifest(nil)
+	forgedJSON, _ := json.Marshal(forged)
+	section, _ := findCapabilityManifestSection(signedCode)
+	fields := strings.Split(section, SEPERATOR)
+	tampered := strings.Replace(string(signedCode), fields[1], hex.EncodeToString(forgedJSON), 1)
+	if _, found, err := ReadCapabilityManifest([]byte(tampered)); !found || !errors.Is(err, ErrCapabilityManifestInvalid) {
+		t.Fatalf("expected forged manifest to be rejected, found=%v err=%v", found, err)
+	}
+
+	legacy, err := SignCode("payload", keyPair.PrivateKey)
+	if err != nil {
+		t.Fatalf("failed to sign legacy payload: %v", err)
+	}
+	if _, found, err := ReadCapabilityManifest(legacy); found || err != nil {
+		t.Fatalf("expected legacy artifact without manifest, found=%v err=%v", found, err)
+	}
+}
+
 func TestValidateArgon2Params(t *testing.T) {
 	if err := ValidateArgon2Params(DefaultArgon2Time, DefaultArgon2Memory, DefaultArgon2Threads); err != nil {
 		t.Fatalf("expected default params to be valid, got: %v", err)
@@ -356,6 +416,24 @@ func TestResolveTamperResponseFollowsProtectionProfile(t *testing.T) {
 	}
 }
 
+func TestInstructionCacheSettingsFollowProtectionProfile(t *testing.T) {
+	t.Setenv(ProtectionProfileEnv, ProtectionProfileParanoid)
+	if blocks, _ := InstructionCacheSettings(); blocks != 0 {
+		t.Fatalf("expected paranoid profile to disable the instruction cache, got %d blocks", blocks)
+	}
+
+	t.Setenv(ProtectionProfileEnv, ProtectionProfileStandard)
+	blocks, reencrypt := InstructionCacheSettings()
+	if blocks <= 0 || !reencrypt {
+		t.Fatalf("expected standard profile to cache with re-encryption, got blocks=%d reencrypt=%v", blocks, reencrypt)
+	}
+
+	t.Setenv(ProtectionProfileEnv, ProtectionProfileMinimal)
+	if minimal, _ := InstructionCacheSettings(); minimal < blocks {
+		t.Fatalf("expected minimal profile to cache at least %d blocks, got %d", blocks, minimal)
+	}
+}
+
 func TestDefaultBuiltinCapabilityPolicyFollowsProtectionProfile(t *testing.T) {
 	t.Setenv(ProtectionProfileEnv, ProtectionProfileMinimal)
 	policy := DefaultBuiltinCapabilityPolicy()
@@ -370,6 +448,52 @@ func TestDefaultBuiltinCapabilityPolicyFollowsProtectionProfile(t *testing.T) {
 	}
 }
 
+func TestResolveBuiltinCapabilityPolicyPrefersExplicitEnv(t *testing.T) {
+	t.Setenv(ProtectionProfileEnv, ProtectionProfileStandard)
+	t.Setenv(BuiltinCapabilitiesEnv, "network, filesystem")
+
+	policy, err := ResolveBuiltinCapabilityPolicy()
+	if err != nil {
+		t.Fatalf("expected explicit policy to parse, got: %v", err)
+	}
+	if !reflect.DeepEqual(BuiltinCapabilityList(policy), []string{CapabilityFilesystem, CapabilityNetwork}) {
+		t.Fatalf("unexpected policy: %+v", policy)
+	}
+	if BuiltinCapabilityAllowed(policy, CapabilityCommandExec) {
+		t.Fatalf("expected command_exec to stay denied")
+	}
+	if !BuiltinCapabilityAllowed(policy, "") {
+		t.Fatalf("expected ungated builtins to be allowed")
+	}
+
+	t.Setenv(ProtectionProfileEnv, ProtectionProfileMinimal)
+	t.Setenv(BuiltinCapabilitiesEnv, "none")
+	policy, err = ResolveBuiltinCapabilityPolicy()
+	if err != nil {
+		t.Fatalf("expected none policy to parse, got: %v", err)
+	}
+	if len(policy) != 0 {
+		t.Fatalf("expected explicit none to override minimal profile, got %+v", policy)
+	}
+
+	t.Setenv(BuiltinCapabilitiesEnv, "network,rootkit")
+	if _, err := ResolveBuiltinCapabilityPolicy(); !errors.Is(err, ErrUnknownCapability) {
+		t.Fatalf("expected ErrUnknownCapability, got: %v", err)
+	}
+}
+
+func TestBuiltinCapabilityAllowedHonorsAll(t *testing.T) {
+	policy, err := ParseBuiltinCapabilityPolicy("all")
+	if err != nil {
+		t.Fatalf("expected all policy to parse, got: %v", err)
+	}
+	for _, capability := range []string{CapabilityCommandExec, CapabilityFilesystem, CapabilityNetwork} {
+		if !BuiltinCapabilityAllowed(policy, capability) {
+			t.Fatalf("expected all to grant %s", capability)
+		}
+	}
+}
+
 func TestApplyTamperResponse(t *testing.T) {
 	t.Setenv(TamperResponseEnv, TamperResponseWarn)
 	if err := ApplyTamperResponse("debugger_detected", "test", true, ErrDebuggerDetected); err != nil {
@@ -22,6 +22,7 @@ var (
 	telemetryCommandBlocked   uint64
 	telemetryCommandSucceeded uint64
 	telemetryCommandFailed    uint64
+	telemetryCapabilityDenied uint64
 )
 
 func RecordDebuggerDetected(stage string) {
@@ -74,6 +75,11 @@ func RecordCommandFailed(stage string) {
 	auditEvent("command_failed", stage)
 }
 
+func RecordCapabilityDenied(stage string) {
+	atomic.AddUint64(&telemetryCapabilityDenied, 1)
+	auditEvent("capability_denied", stage)
+}
+
 func SecurityTelemetrySnapshot() map[string]uint64 {
 	return map[string]uint64{
 		"debugger_detected":         atomic.LoadUint64(&telemetryDebuggerDetected),
@@ -86,6 +92,7 @@ func SecurityTelemetrySnapshot() map[string]uint64 {
 		"command_blocked":           atomic.LoadUint64(&telemetryCommandBlocked),
 		"command_succeeded":         atomic.LoadUint64(&telemetryCommandSucceeded),
 		"command_failed":            atomic.LoadUint64(&telemetryCommandFailed),
+		"capability_denied":         atomic.LoadUint64(&telemetryCapabilityDenied),
 	}
 }
 
@@ -118,6 +125,7 @@ func ResetSecurityTelemetry() {
 	atomic.StoreUint64(&telemetryCommandBlocked, 0)
 	atomic.StoreUint64(&telemetryCommandSucceeded, 0)
 	atomic.StoreUint64(&telemetryCommandFailed, 0)
+	atomic.StoreUint64(&telemetryCapabilityDenied, 0)
 }
 
 func auditEvent(event, stage string) {
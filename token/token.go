@@ -1,15 +1,44 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position
+}
+
+// Position is a location in source code. Line and Column are 1-based;
+// a zero Line means the position is unknown.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// IsValid reports whether the position carries line information
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+// String formats the position as file:line:column, dropping the file when unknown
+func (p Position) String() string {
+	if !p.IsValid() {
+		if p.File != "" {
+			return p.File
+		}
+		return "-"
+	}
+	if p.File != "" {
+		return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
+	COMMENT = "COMMENT" // trivia only, never returned by Lexer.NextToken
 
 	// Identifiers + Literals
 	// ex: add, foobar, x, y, ....
@@ -17,6 +46,8 @@ const (
 	INT    = "INT"
 	FLOAT  = "FLOAT"
 	STRING = "STRING"
+	// TEMPLATE is a double-quoted string containing ${...}; Literal is the raw body
+	TEMPLATE = "TEMPLATE"
 
 	// Operators
 	ASSIGN     = "="
@@ -27,12 +58,31 @@ const (
 	MODULO     = "%"
 	BSLASH     = "\\"
 	DOT        = "."
+	RANGE      = ".."
+	ELLIPSIS   = "..."
+	POWER      = "**"
 	LT         = "<"
 	GT         = ">"
+	LTE        = "<="
+	GTE        = ">="
 	BANG       = "!"
 	EQUALITY   = "=="
 	INEQUALITY = "!="
 	COLON      = ":"
+	AND        = "&&"
+	OR         = "||"
+	BITAND     = "&"
+	BITOR      = "|"
+	BITXOR     = "^"
+	SHL        = "<<"
+	SHR        = ">>"
+	ARROW      = "=>"
+
+	// Compound assignment
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	FSLASH_ASSIGN   = "/="
 
 	// Delimiters
 	COMMA     = ","
@@ -58,6 +108,13 @@ const (
 	CONTINUE = "CONTINUE"
 	STRUCT   = "STRUCT"
 	ENUM     = "ENUM"
+	IN       = "IN"
+	IMPORT   = "IMPORT"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	THROW    = "THROW"
+	MATCH    = "MATCH"
 )
 
 var keywords = map[string]TokenType{
@@ -74,6 +131,13 @@ var keywords = map[string]TokenType{
 	"continue": CONTINUE,
 	"struct":   STRUCT,
 	"enum":     ENUM,
+	"in":       IN,
+	"import":   IMPORT,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"throw":    THROW,
+	"match":    MATCH,
 }
 
 // LookupIdent function takes in an identifier(string)
@@ -0,0 +1,46 @@
+package vm
+
+import (
+	"mutant/global"
+	"mutant/object"
+)
+
+// Captured variables live in cells shared by the declaring frame and its
+// closures. A local slot holds its value directly until a closure captures
+// it; OpCaptureLocal then moves the value into a new cell and leaves the cell
+// in the slot, so later reads and assignments in the declaring function go
+// through the cell too. `let` still rebinds the slot with OpSetLocal, which
+// gives every loop iteration a fresh variable.
+
+// loadVariable returns the decrypted value of a local slot or free variable,
+// reading through its cell if it has been captured.
+func (vm *VM) loadVariable(stored object.Object) object.Object {
+	if cell, ok := stored.(*object.Cell); ok {
+		stored = cell.Value
+	}
+	if stored == nil {
+		return global.Null
+	}
+	return vm.decryptForUse(stored)
+}
+
+// assignVariable stores value in the variable held by slot, writing through
+// its cell if it has been captured.
+func (vm *VM) assignVariable(slot *object.Object, value object.Object) {
+	if cell, ok := (*slot).(*object.Cell); ok {
+		cell.Value = vm.encryptForStorage(value)
+		return
+	}
+	*slot = vm.encryptForStorage(value)
+}
+
+// captureVariable returns the cell of the variable held by slot, moving the
+// value into a new cell on its first capture.
+func captureVariable(slot *object.Object) *object.Cell {
+	if cell, ok := (*slot).(*object.Cell); ok {
+		return cell
+	}
+	cell := &object.Cell{Value: *slot}
+	*slot = cell
+	return cell
+}
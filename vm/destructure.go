@@ -0,0 +1,69 @@
+package vm
+
+import (
+	"fmt"
+	"mutant/object"
+)
+
+// execUnpackArray pops an array and pushes its first count elements in order,
+// followed by an array of the remaining elements when rest is set.
+func (vm *VM) execUnpackArray(count int, rest bool) error {
+	value := vm.pop()
+	array, ok := value.(*object.Array)
+	if !ok {
+		return fmt.Errorf("cannot destructure %s as an array", value.Type())
+	}
+
+	length := len(array.Elements)
+	if rest && length < count {
+		return fmt.Errorf("array pattern expects at least %d elements, got %d", count, length)
+	}
+	if !rest && length != count {
+		return fmt.Errorf("array pattern expects %d elements, got %d", count, length)
+	}
+
+	for _, element := range array.Elements[:count] {
+		if err := vm.push(element); err != nil {
+			return err
+		}
+	}
+	if rest {
+		remaining := make([]object.Object, length-count)
+		copy(remaining, array.Elements[count:])
+		return vm.push(&object.Array{Elements: remaining})
+	}
+	return nil
+}
+
+// execUnpackKey pops a hash and pushes the value stored under a string key
+func (vm *VM) execUnpackKey(key string) error {
+	value := vm.pop()
+	hash, ok := value.(*object.Hash)
+	if !ok {
+		return fmt.Errorf("cannot destructure %s as a hash", value.Type())
+	}
+
+	pair, ok := hash.Pairs[(&object.String{Value: key}).HashKey()]
+	if !ok {
+		return fmt.Errorf("missing key %q in destructured hash", key)
+	}
+	return vm.push(pair.Value)
+}
+
+// execUnpackField pops a struct of the given type and pushes one field
+func (vm *VM) execUnpackField(field, typeName string) error {
+	value := vm.pop()
+	structObj, ok := value.(*object.Struct)
+	if !ok {
+		return fmt.Errorf("cannot destructure %s as %s", value.Type(), typeName)
+	}
+	if structObj.TypeName != typeName {
+		return fmt.Errorf("cannot destructure %s as %s", structObj.TypeName, typeName)
+	}
+
+	fieldValue, ok := structObj.Fields[field]
+	if !ok {
+		return fmt.Errorf("struct %s has no field %s", typeName, field)
+	}
+	return vm.push(fieldValue)
+}
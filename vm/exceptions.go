@@ -0,0 +1,117 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"mutant/object"
+	"mutant/security"
+)
+
+// thrownError carries a value raised by OpThrow, or a builtin failure raised
+// inside a try, out of execOpcode
+type thrownError struct {
+	value object.Object
+}
+
+func (e *thrownError) Error() string {
+	if errObj, ok := e.value.(*object.Error); ok {
+		return errObj.Message
+	}
+	return "uncaught exception: " + e.value.Inspect()
+}
+
+// exceptionValue is the object a catch block receives for err
+func exceptionValue(err error) object.Object {
+	var thrown *thrownError
+	if errors.As(err, &thrown) {
+		return thrown.value
+	}
+	return &object.Error{Message: err.Error()}
+}
+
+// isCatchable reports whether user code may handle err. Security policy
+// failures, including integrity and tamper failures under the terminate
+// policy, always terminate the run.
+func isCatchable(err error) bool {
+	return !errors.Is(err, security.ErrIntegrityFailed) &&
+		!errors.Is(err, security.ErrDebuggerDetected) &&
+		!errors.Is(err, security.ErrSandboxDetected) &&
+		!errors.Is(err, security.ErrCapabilityDenied)
+}
+
+// enterTry activates entry index of the current function's handler table for
+// the OpTry at ip. The entry must be the one compiled for this OpTry.
+func (vm *VM) enterTry(index, ip int) error {
+	frame := vm.currentFrame()
+	handlers := frame.cl.Fn.Handlers
+	if index >= len(handlers) {
+		return fmt.Errorf("OpTry: invalid handler index=%d, len=%d", index, len(handlers))
+	}
+
+	handler := handlers[index]
+	if handler.Start != ip || handler.End <= ip || handler.Target <= handler.End || handler.Target >= len(frame.Instructions()) {
+		security.RecordIntegrityFailure("vm-try")
+		return security.ApplyTamperResponse("integrity_failed", "vm-try", true, fmt.Errorf("handler %d does not match OpTry at ip=%d", index, ip))
+	}
+
+	frame.tries = append(frame.tries, tryRecord{handler: handler, sp: vm.stackPointer})
+	return nil
+}
+
+// inTry reports whether a try is active in the current frame or in any frame
+// that called it. Frames below the unwind floor count too: a failure raised in
+// a callback comes back out of the builtin that ran it and reaches their
+// handlers from there.
+func (vm *VM) inTry() bool {
+	for i := vm.frameIndex - 1; i >= 0; i-- {
+		if len(vm.frames[i].tries) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// unwind delivers err to the innermost active try handler: frames above the
+// handler's frame are discarded, its stack depth is restored and execution
+// resumes at the handler target with the exception pushed. The resumed frame
+// is re-verified so a handler cannot be used to jump into tampered code.
+// It reports false when err is not catchable or no handler is active above
+// the unwind floor.
+func (vm *VM) unwind(err error) (bool, error) {
+	if !isCatchable(err) {
+		return false, nil
+	}
+
+	target := -1
+	for i := vm.frameIndex - 1; i >= vm.unwindFloor; i-- {
+		if len(vm.frames[i].tries) > 0 {
+			target = i
+			break
+		}
+	}
+	if target < 0 {
+		return false, nil
+	}
+
+	for i := vm.frameIndex - 1; i > target; i-- {
+		if err := vm.releaseFrameCache(vm.frames[i], true); err != nil {
+			return false, err
+		}
+	}
+	vm.frameIndex = target + 1
+	frame := vm.currentFrame()
+	record := frame.tries[len(frame.tries)-1]
+	frame.tries = frame.tries[:len(frame.tries)-1]
+
+	vm.stackPointer = record.sp
+	frame.ip = record.handler.Target - 1
+
+	if err := vm.verifyFrameControlFlow(frame, "vm-unwind"); err != nil {
+		return false, err
+	}
+	if err := vm.verifyFrameIntegrity(frame, "vm-unwind"); err != nil {
+		return false, err
+	}
+
+	return true, vm.push(exceptionValue(err))
+}
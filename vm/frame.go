@@ -6,9 +6,19 @@ import (
 )
 
 type Frame struct {
-	cl *object.Closure
-	ip int
-	bp int
+	cl    *object.Closure
+	ip    int
+	bp    int
+	argc  int         // arguments supplied for named parameters, see OpJumpIfArg
+	tries []tryRecord // active try handlers, innermost last
+	cache *instructionCache
+}
+
+// tryRecord is a handler activated by OpTry together with the stack depth
+// to restore when an exception is delivered to it
+type tryRecord struct {
+	handler object.ExceptionHandler
+	sp      int
 }
 
 func NewFrame(cl *object.Closure, basePointer int) *Frame {
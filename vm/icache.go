@@ -0,0 +1,209 @@
+package vm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"mutant/code"
+	"mutant/security"
+)
+
+// maxBlockBytes bounds a decoded block; longer straight-line code is split
+// into several blocks
+const maxBlockBytes = 128
+
+// decodedBlock is the decrypted copy of instructions [start, end) of the
+// function a frame runs
+type decodedBlock struct {
+	start int
+	end   int
+	plain []byte
+}
+
+// instructionCache holds the decoded blocks of one frame, at most limit of
+// them, and evicts the oldest block first. It lives and dies with its frame.
+type instructionCache struct {
+	limit   int
+	blocks  map[int]*decodedBlock // by start position
+	order   []int                 // start positions, oldest first
+	current *decodedBlock         // block of the instruction being executed
+}
+
+// SetInstructionCache sets how many decrypted basic blocks each frame keeps
+// and whether evicted blocks are re-encrypted and checked before they are
+// zeroed. A limit of 0 disables the cache. New VMs take both from the
+// protection profile.
+func (vm *VM) SetInstructionCache(blocks int, reencrypt bool) {
+	vm.icacheBlocks = max(blocks, 0)
+	vm.icacheReencrypt = reencrypt
+}
+
+// fetchOpcode decrypts the opcode at ip of the current frame. With the cache
+// enabled it decodes the whole basic block starting there once, so the rest
+// of the block and its operands are read without further decryption.
+func (vm *VM) fetchOpcode(ins code.Instructions, ip int) (code.Opcode, error) {
+	if vm.icacheBlocks <= 0 {
+		opcodeByte, err := security.SecureXOROneAt(ins[ip], int64(vm.inslen), vm.password, int64(ip))
+		return code.Opcode(opcodeByte), err
+	}
+
+	block, err := vm.blockAt(vm.currentFrame(), ins, ip)
+	if err != nil {
+		return 0, err
+	}
+	return code.Opcode(block.plain[ip-block.start]), nil
+}
+
+func (vm *VM) blockAt(frame *Frame, ins code.Instructions, ip int) (*decodedBlock, error) {
+	cache := frame.cache
+	if cache == nil {
+		cache = &instructionCache{limit: vm.icacheBlocks, blocks: make(map[int]*decodedBlock)}
+		frame.cache = cache
+	}
+	if block := cache.current; block != nil && ip >= block.start && ip < block.end {
+		return block, nil
+	}
+	if block, ok := cache.blocks[ip]; ok {
+		cache.current = block
+		return block, nil
+	}
+
+	block, err := vm.decodeBlock(ins, ip)
+	if err != nil {
+		return nil, err
+	}
+	for len(cache.order) >= cache.limit {
+		oldest := cache.blocks[cache.order[0]]
+		cache.order = cache.order[1:]
+		delete(cache.blocks, oldest.start)
+		if err := vm.releaseBlock(ins, oldest, true); err != nil {
+			return nil, err
+		}
+	}
+	cache.blocks[ip] = block
+	cache.order = append(cache.order, ip)
+	cache.current = block
+	return block, nil
+}
+
+// decodeBlock decrypts the basic block starting at start: whole instructions
+// up to and including the first one that transfers control, or as many as
+// fit in maxBlockBytes.
+func (vm *VM) decodeBlock(ins code.Instructions, start int) (*decodedBlock, error) {
+	limit := min(len(ins), start+maxBlockBytes)
+	plain, err := security.SecureXORAt(ins[start:limit], int64(vm.inslen), vm.password, int64(start))
+	if err != nil {
+		return nil, err
+	}
+
+	end := start
+	for end < limit {
+		op := code.Opcode(plain[end-start])
+		def, err := code.Lookup(byte(op))
+		if err != nil {
+			break
+		}
+		width := 1
+		for _, w := range def.OperandWidths {
+			width += w
+		}
+		if end+width > limit {
+			break
+		}
+		end += width
+		if endsBasicBlock(op) {
+			break
+		}
+	}
+	if end == start {
+		// Leave a malformed instruction for the opcode handlers to report.
+		end = start + 1
+	}
+
+	security.SecureZero(plain[end-start:])
+	return &decodedBlock{start: start, end: end, plain: plain[:end-start]}, nil
+}
+
+func endsBasicBlock(op code.Opcode) bool {
+	switch op {
+	case code.OpJump, code.OpJumpFalse, code.OpJumpFalseOrPop, code.OpJumpTrueOrPop,
+		code.OpJumpIfArg, code.OpIterNext, code.OpReturnValue, code.OpReturn,
+		code.OpTailCall, code.OpThrow:
+		return true
+	}
+	return false
+}
+
+// releaseBlock zeroes a block leaving the cache. With re-encryption on and
+// verify set, the plaintext is first encrypted again and compared with the
+// instruction stream, so instructions changed while their plaintext was
+// cached are reported as tampering. Under the terminate policy the error
+// matches security.ErrIntegrityFailed, so a try cannot catch it.
+func (vm *VM) releaseBlock(ins code.Instructions, block *decodedBlock, verify bool) error {
+	defer security.SecureZero(block.plain)
+
+	if !verify || !vm.icacheReencrypt {
+		return nil
+	}
+	sealed, err := security.SecureXORAt(block.plain, int64(vm.inslen), vm.password, int64(block.start))
+	if err != nil {
+		return err
+	}
+	if block.end > len(ins) || !security.SecureCompare(sealed, ins[block.start:block.end]) {
+		security.RecordIntegrityFailure("vm-icache")
+		return security.ApplyTamperResponse("integrity_failed", "vm-icache", vm.secureMode,
+			fmt.Errorf("cached instructions at ip=%d no longer match the function", block.start))
+	}
+	return nil
+}
+
+// releaseFrameCache empties the cache of a frame that is being discarded
+func (vm *VM) releaseFrameCache(frame *Frame, verify bool) error {
+	if frame == nil || frame.cache == nil {
+		return nil
+	}
+	cache := frame.cache
+	frame.cache = nil
+
+	var ins code.Instructions
+	if frame.cl != nil && frame.cl.Fn != nil {
+		ins = frame.Instructions()
+	}
+	var firstErr error
+	for _, start := range cache.order {
+		if err := vm.releaseBlock(ins, cache.blocks[start], verify); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// cachedOperand returns the decrypted bytes [offset, offset+width) when the
+// current block of the current frame holds them
+func (vm *VM) cachedOperand(offset, width int) []byte {
+	cache := vm.currentFrame().cache
+	if cache == nil || cache.current == nil {
+		return nil
+	}
+	block := cache.current
+	if offset < block.start || offset+width > block.end {
+		return nil
+	}
+	return block.plain[offset-block.start : offset-block.start+width]
+}
+
+// readUint16 reads the 2-byte operand at offset of the current frame's
+// instructions ins, from the cache when possible
+func (vm *VM) readUint16(ins code.Instructions, offset int) (uint16, error) {
+	if plain := vm.cachedOperand(offset, 2); plain != nil {
+		return binary.BigEndian.Uint16(plain), nil
+	}
+	return code.ReadUint16(ins[offset:], int64(vm.inslen), vm.password, int64(offset))
+}
+
+// readUint8 reads the 1-byte operand at offset like readUint16
+func (vm *VM) readUint8(ins code.Instructions, offset int) (uint8, error) {
+	if plain := vm.cachedOperand(offset, 1); plain != nil {
+		return plain[0], nil
+	}
+	return code.ReadUint8(ins[offset:], int64(vm.inslen), vm.password, int64(offset))
+}
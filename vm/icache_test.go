@@ -0,0 +1,222 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"mutant/compiler"
+	"mutant/global"
+	"mutant/mutil"
+	"mutant/object"
+	"mutant/security"
+)
+
+const icacheLoopProgram = `
+let total = 0;
+for (let i = 0; i < 2000; i = i + 1) {
+	if (i % 3 == 0) {
+		total = total + i;
+	} else {
+		total = total - 1;
+	}
+}
+total;
+`
+
+const icacheRecursionProgram = `
+let fib = fn(n) {
+	if (n < 2) {
+		return n;
+	}
+	fib(n - 1) + fib(n - 2);
+};
+fib(15);
+`
+
+func encryptedByteCode(tb testing.TB, input string) (*compiler.ByteCode, string) {
+	tb.Helper()
+	comp := compiler.New()
+	if err := comp.Compile(parse(input)); err != nil {
+		tb.Fatalf("compiler error: %s", err)
+	}
+
+	byteCode := comp.ByteCode()
+	password := fmt.Sprint(security.DerivePasswordFromInstructions(byteCode.Instructions))
+	return mutil.EncryptByteCode(byteCode, password), password
+}
+
+func newCachedVM(byteCode *compiler.ByteCode, password string, blocks int, reencrypt bool) *VM {
+	vm := NewWithGlobalStoreAndPassword(byteCode, make([]object.Object, global.GlobalSize), password)
+	vm.SetInstructionCache(blocks, reencrypt)
+	return vm
+}
+
+func TestInstructionCacheKeepsResults(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{icacheLoopProgram, 665000},
+		{icacheRecursionProgram, 610},
+	}
+
+	for _, tt := range tests {
+		byteCode, password := encryptedByteCode(t, tt.input)
+		for _, blocks := range []int{0, 1, 2, 64} {
+			vm := newCachedVM(byteCode, password, blocks, true)
+			if err := vm.Run(); err != nil {
+				t.Fatalf("cache=%d: vm error: %s", blocks, err)
+			}
+			if err := testIntegerObject(tt.expected, vm.LastPoppedStackElement()); err != nil {
+				t.Fatalf("cache=%d: %s", blocks, err)
+			}
+		}
+	}
+}
+
+func TestInstructionCacheStaysBounded(t *testing.T) {
+	byteCode, password := encryptedByteCode(t, icacheLoopProgram)
+	vm := newCachedVM(byteCode, password, 2, true)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	cache := vm.frames[0].cache
+	if cache == nil {
+		t.Fatalf("expected the main frame to have cached blocks")
+	}
+	if len(cache.order) > 2 || len(cache.blocks) > 2 {
+		t.Fatalf("expected at most 2 cached blocks, got order=%d blocks=%d", len(cache.order), len(cache.blocks))
+	}
+}
+
+func TestInstructionCacheDisabledByParanoidProfile(t *testing.T) {
+	t.Setenv(security.ProtectionProfileEnv, security.ProtectionProfileParanoid)
+	byteCode, password := encryptedByteCode(t, "1 + 2;")
+	vm := NewWithGlobalStoreAndPassword(byteCode, make([]object.Object, global.GlobalSize), password)
+	if vm.icacheBlocks != 0 {
+		t.Fatalf("expected paranoid profile to disable the instruction cache, got %d blocks", vm.icacheBlocks)
+	}
+
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	if vm.currentFrame().cache != nil {
+		t.Fatalf("expected no cached blocks under the paranoid profile")
+	}
+}
+
+func TestInstructionCacheReleaseZeroesAndVerifies(t *testing.T) {
+	t.Setenv(security.TamperResponseEnv, security.TamperResponseTerminate)
+	byteCode, password := encryptedByteCode(t, "let x = 1; x + 2;")
+
+	t.Run("zeroes", func(t *testing.T) {
+		vm := newCachedVM(byteCode, password, 4, true)
+		frame := vm.currentFrame()
+		block, err := vm.blockAt(frame, frame.Instructions(), 0)
+		if err != nil {
+			t.Fatalf("decode error: %s", err)
+		}
+		plain := block.plain
+
+		if err := vm.releaseFrameCache(frame, true); err != nil {
+			t.Fatalf("expected untouched block to verify, got: %v", err)
+		}
+		for i, b := range plain {
+			if b != 0 {
+				t.Fatalf("expected released block to be zeroed, byte %d is %#x", i, b)
+			}
+		}
+	})
+
+	t.Run("detects tampering", func(t *testing.T) {
+		security.ResetSecurityTelemetry()
+		vm := newCachedVM(byteCode, password, 4, true)
+		frame := vm.currentFrame()
+		ins := frame.Instructions()
+		if _, err := vm.fetchOpcode(ins, 0); err != nil {
+			t.Fatalf("decode error: %s", err)
+		}
+		ins[0] ^= 0xFF
+		defer func() { ins[0] ^= 0xFF }()
+
+		if err := vm.releaseFrameCache(frame, true); !errors.Is(err, security.ErrIntegrityFailed) {
+			t.Fatalf("expected tampered instructions to fail verification, got: %v", err)
+		}
+		if security.SecurityTelemetrySnapshot()["integrity_failed"] == 0 {
+			t.Fatalf("expected integrity failure telemetry increment")
+		}
+	})
+
+	t.Run("skips verification without re-encryption", func(t *testing.T) {
+		vm := newCachedVM(byteCode, password, 4, false)
+		frame := vm.currentFrame()
+		ins := frame.Instructions()
+		if _, err := vm.fetchOpcode(ins, 0); err != nil {
+			t.Fatalf("decode error: %s", err)
+		}
+		ins[0] ^= 0xFF
+		defer func() { ins[0] ^= 0xFF }()
+
+		if err := vm.releaseFrameCache(frame, true); err != nil {
+			t.Fatalf("expected no verification without re-encryption, got: %v", err)
+		}
+	})
+}
+
+func TestInstructionCacheTamperingIsNotCatchable(t *testing.T) {
+	security.ResetSecurityTelemetry()
+	t.Setenv(security.TamperResponseEnv, security.TamperResponseTerminate)
+	byteCode, password := encryptedByteCode(t, `let f = fn() { 1 }; let r = 0; try { r = f(); } catch (e) { r = 99; }; r`)
+	vm := newCachedVM(byteCode, password, 4, true)
+	vm.integrityEvery = 0 // leave detection to the cache
+
+	vm.ensureFrameBoundaries()
+	for vm.frameIndex < 2 {
+		if err := vm.step(); err != nil {
+			t.Fatalf("vm error before entering f: %s", err)
+		}
+	}
+	if err := vm.step(); err != nil {
+		t.Fatalf("vm error inside f: %s", err)
+	}
+	// f's first block is cached now; change it underneath the cache so the
+	// return that releases it detects the tampering.
+	vm.currentFrame().Instructions()[0] ^= 0xFF
+
+	err := vm.Run()
+	if !errors.Is(err, security.ErrIntegrityFailed) {
+		t.Fatalf("expected ErrIntegrityFailed to escape the try, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "cached instructions") {
+		t.Fatalf("expected the cache to detect the tampering, got: %v", err)
+	}
+}
+
+func BenchmarkInstructionCache(b *testing.B) {
+	programs := []struct {
+		name  string
+		input string
+	}{
+		{"loop", icacheLoopProgram},
+		{"recursion", icacheRecursionProgram},
+	}
+
+	for _, program := range programs {
+		byteCode, password := encryptedByteCode(b, program.input)
+		for _, blocks := range []int{0, 64} {
+			b.Run(fmt.Sprintf("%s/cache=%d", program.name, blocks), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					vm := newCachedVM(byteCode, password, blocks, true)
+					b.StartTimer()
+					if err := vm.Run(); err != nil {
+						b.Fatalf("vm error: %s", err)
+					}
+				}
+			})
+		}
+	}
+}
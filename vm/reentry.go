@@ -0,0 +1,101 @@
+package vm
+
+import (
+	"fmt"
+	"mutant/ast"
+	"mutant/builtin"
+	"mutant/object"
+	"slices"
+)
+
+// CallFunction calls fn with args from inside a running program and runs it
+// to completion, so builtins can call back into mutant code. It implements
+// builtin.Caller.
+//
+// The call takes frames above the current one like any other call, with the
+// same integrity probes and frame checks. An error that fn does not catch
+// itself ends the call without reaching try handlers below it: the frames and
+// stack are restored to where the call began and the error is returned for
+// the builtin to pass on, so the program sees it raised by the builtin call.
+func (vm *VM) CallFunction(fn object.Object, args ...object.Object) (object.Object, error) {
+	switch fn.(type) {
+	case *object.Closure, *object.BoundMethod, *builtin.BuiltIn:
+	default:
+		return nil, fmt.Errorf("calling non-function and non-built-in")
+	}
+
+	sp, depth, floor := vm.stackPointer, vm.frameIndex, vm.unwindFloor
+	vm.unwindFloor = depth
+	defer func() { vm.unwindFloor = floor }()
+
+	if err := vm.push(fn); err != nil {
+		return nil, err
+	}
+	for _, arg := range args {
+		if err := vm.push(arg); err != nil {
+			return nil, vm.abandonCall(sp, depth, err)
+		}
+	}
+
+	if err := vm.execCall(len(args)); err != nil {
+		return nil, vm.abandonCall(sp, depth, err)
+	}
+	for vm.frameIndex > depth {
+		frame := vm.currentFrame()
+		if frame.ip >= len(frame.Instructions())-1 {
+			return nil, vm.abandonCall(sp, depth, fmt.Errorf("function ended without returning"))
+		}
+		if err := vm.step(); err != nil {
+			return nil, vm.abandonCall(sp, depth, err)
+		}
+	}
+
+	result := vm.pop()
+	vm.stackPointer = sp
+	return result, nil
+}
+
+// abandonCall discards the frames a failed CallFunction left above depth and
+// restores the stack pointer it started with, then returns err
+func (vm *VM) abandonCall(sp, depth int, err error) error {
+	for vm.frameIndex > depth {
+		if releaseErr := vm.releaseFrameCache(vm.popFrame(), true); releaseErr != nil {
+			return releaseErr
+		}
+	}
+	vm.stackPointer = sp
+	return err
+}
+
+// Regexps returns the compiled patterns cached for this program. It
+// implements builtin.Caller.
+func (vm *VM) Regexps() *builtin.RegexpCache {
+	return &vm.regexps
+}
+
+// StructFields returns the declared field names of a struct type. It
+// implements builtin.Caller.
+func (vm *VM) StructFields(typeName string) ([]string, bool) {
+	def, ok := vm.structDefs[typeName].([]*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	names := make([]string, len(def))
+	for i, ident := range def {
+		names[i] = ident.Value
+	}
+	return names, true
+}
+
+// EnumVariants returns the tag names of an enum type. It implements
+// builtin.Caller.
+func (vm *VM) EnumVariants(typeName string) ([]string, bool) {
+	tags, ok := vm.enumDefs[typeName].([]string)
+	return slices.Clone(tags), ok
+}
+
+// EnumHasPayload reports whether a tag of an enum type carries a payload. It
+// implements builtin.Caller.
+func (vm *VM) EnumHasPayload(typeName, tag string) bool {
+	return vm.enumPayloads[typeName][tag]
+}
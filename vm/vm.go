@@ -2,6 +2,7 @@ package vm
 
 import (
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"math"
 	"mutant/ast"
@@ -32,9 +33,19 @@ type VM struct {
 	nextSweepAt     uint64
 	frameIntegrity  map[*object.CompiledFunction][32]byte
 	frameBoundaries map[*object.CompiledFunction]map[int]struct{}
+	icacheBlocks    int                 // decoded blocks each frame may cache, see SetInstructionCache
+	icacheReencrypt bool                // verify evicted blocks against the instruction stream
+	unwindFloor     int                 // lowest frame whose try handlers may catch, see CallFunction
+	regexps         builtin.RegexpCache // compiled patterns of the regex builtins
 	secureMode      bool
-	structDefs      map[string]any // Struct definitions (field names)
-	enumDefs        map[string]any // Enum definitions (tag names)
+	structDefs      map[string]any             // Struct definitions (field names)
+	structMethods   map[string]map[string]int  // Struct method constant indices
+	enumDefs        map[string]any             // Enum definitions (tag names)
+	enumPayloads    map[string]map[string]bool // Enum tags that carry a payload
+
+	// capabilityPolicy lists the granted builtin capability groups.
+	// A nil policy leaves builtins unrestricted (REPL and embedding callers).
+	capabilityPolicy map[string]struct{}
 
 	enforceSecurityCheckOpcodes bool
 }
@@ -100,7 +111,7 @@ func (vm *VM) nextSweepInterval() uint64 {
 
 func New(bc *compiler.ByteCode) *VM {
 	mainInstructions := bc.Instructions
-	mainfn := &object.CompiledFunction{Instructions: mainInstructions}
+	mainfn := &object.CompiledFunction{Instructions: mainInstructions, Handlers: bc.Handlers}
 	frames := make([]*Frame, initialFrameCapacity)
 
 	mainClosure := &object.Closure{Fn: mainfn}
@@ -110,6 +121,7 @@ func New(bc *compiler.ByteCode) *VM {
 	frameIntegrity := make(map[*object.CompiledFunction][32]byte)
 	frameIntegrity[mainfn] = sha256.Sum256(mainfn.Instructions)
 	integritySeed := deriveIntegritySeed(mainInstructions)
+	icacheBlocks, icacheReencrypt := security.InstructionCacheSettings()
 
 	vm := &VM{
 		constants:       bc.Constants,
@@ -124,9 +136,13 @@ func New(bc *compiler.ByteCode) *VM {
 		integrityEvery:  64,
 		integrityJitter: integritySeed,
 		frameIntegrity:  frameIntegrity,
+		icacheBlocks:    icacheBlocks,
+		icacheReencrypt: icacheReencrypt,
 		secureMode:      true,
 		structDefs:      convertStructDefs(bc.StructDefs),
+		structMethods:   bc.StructMethods,
 		enumDefs:        convertEnumDefs(bc.EnumDefs),
+		enumPayloads:    bc.EnumPayloads,
 
 		enforceSecurityCheckOpcodes: false,
 	}
@@ -246,6 +262,9 @@ func (vm *VM) clearObjectSensitiveData(obj object.Object) {
 			vm.clearObjectSensitiveData(o.Free[i])
 			o.Free[i] = nil
 		}
+	case *object.Cell:
+		vm.clearObjectSensitiveData(o.Value)
+		o.Value = nil
 	}
 }
 
@@ -277,6 +296,7 @@ func (vm *VM) CleanupRuntimeSensitiveData(clearGlobals bool, clearConstants bool
 	}
 
 	for i := range vm.frames {
+		vm.releaseFrameCache(vm.frames[i], false)
 		vm.frames[i] = nil
 	}
 	vm.frameIndex = 0
@@ -288,6 +308,15 @@ func (vm *VM) CleanupSensitiveData(clearGlobals bool) {
 	vm.CleanupRuntimeSensitiveData(clearGlobals, true)
 }
 
+// SetBuiltinCapabilityPolicy restricts builtin calls to the granted capability groups.
+func (vm *VM) SetBuiltinCapabilityPolicy(policy map[string]struct{}) {
+	granted := make(map[string]struct{}, len(policy))
+	for capability := range policy {
+		granted[capability] = struct{}{}
+	}
+	vm.capabilityPolicy = granted
+}
+
 // GlobalStore returns the VM global storage slice reference.
 func (vm *VM) GlobalStore() []object.Object {
 	return vm.globals
@@ -461,9 +490,6 @@ func (vm *VM) verifyFrameControlFlow(frame *Frame, stage string) error {
 }
 
 func (vm *VM) Run() error {
-	var ip int
-	var ins code.Instructions
-	var op code.Opcode
 	vm.ensureFrameBoundaries()
 
 	if err := vm.validateSecurityCheckOpcodes("before-execution"); err != nil {
@@ -471,434 +497,658 @@ func (vm *VM) Run() error {
 	}
 
 	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
-		if err := vm.runIntegrityProbes(); err != nil {
+		if err := vm.step(); err != nil {
 			return err
 		}
+	}
 
-		vm.currentFrame().ip++
-		vm.stepCount++
+	if err := vm.validateSecurityCheckOpcodes("after-execution"); err != nil {
+		return err
+	}
+
+	return nil
+}
 
-		ip = vm.currentFrame().ip
-		ins = vm.currentFrame().Instructions()
+// step executes the next instruction of the current frame. An error it
+// cannot deliver to a try handler ends the run.
+func (vm *VM) step() error {
+	if err := vm.runIntegrityProbes(); err != nil {
+		return err
+	}
 
-		opcodeByte, err := security.SecureXOROneAt(ins[ip], int64(vm.inslen), vm.password, int64(ip))
-		if err != nil {
+	vm.currentFrame().ip++
+	vm.stepCount++
+
+	ip := vm.currentFrame().ip
+	ins := vm.currentFrame().Instructions()
+
+	op, err := vm.fetchOpcode(ins, ip)
+	if err != nil {
+		return err
+	}
+
+	if err := vm.execOpcode(op, ip, ins); err != nil {
+		caught, unwindErr := vm.unwind(err)
+		if unwindErr != nil {
+			return unwindErr
+		}
+		if !caught {
 			return err
 		}
-		op = code.Opcode(opcodeByte)
+	}
+	return nil
+}
 
-		switch op {
-		case code.OpChkDbg:
-			if isDebuggerPresent() {
-				security.RecordDebuggerDetected("vm-run")
-				if !vm.secureMode {
-					logSecurityWarning("debugger_detected", "vm-run")
-					continue
-				}
-				return security.ErrDebuggerDetected
-			}
-		case code.OpChkSnd:
-			if isSandboxed() {
-				security.RecordSandboxDetected("vm-run")
-				if !vm.secureMode {
-					logSecurityWarning("sandbox_detected", "vm-run")
-					continue
-				}
-				return security.ErrSandboxDetected
-			}
-		case code.OpConstant:
-			if ip+2 >= len(ins) {
-				return fmt.Errorf("OpConstant: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
-			}
-			constIndex, err := code.ReadUint16(ins[ip+1:], int64(vm.inslen), vm.password, int64(ip+1))
-			if err != nil {
-				return err
-			}
-			vm.currentFrame().ip += 2
+// execOpcode executes the decoded opcode op found at ip in ins. Errors it
+// returns are raised as exceptions by Run and may be caught by a try handler.
+func (vm *VM) execOpcode(op code.Opcode, ip int, ins code.Instructions) error {
+	switch op {
+	case code.OpChkDbg:
+		if isDebuggerPresent() {
+			security.RecordDebuggerDetected("vm-run")
+			if !vm.secureMode {
+				logSecurityWarning("debugger_detected", "vm-run")
+				return nil
+			}
+			return security.ErrDebuggerDetected
+		}
+	case code.OpChkSnd:
+		if isSandboxed() {
+			security.RecordSandboxDetected("vm-run")
+			if !vm.secureMode {
+				logSecurityWarning("sandbox_detected", "vm-run")
+				return nil
+			}
+			return security.ErrSandboxDetected
+		}
+	case code.OpConstant:
+		if ip+2 >= len(ins) {
+			return fmt.Errorf("OpConstant: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
+		}
+		constIndex, err := vm.readUint16(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		vm.currentFrame().ip += 2
 
-			if err := vm.push(vm.constants[constIndex]); err != nil {
-				return err
-			}
-		case code.OpBang:
-			if err := vm.execBangOperation(); err != nil {
-				return err
-			}
-		case code.OpMinus:
-			if err := vm.execMinusOperation(); err != nil {
-				return err
-			}
-		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv:
-			if err := vm.execBinaryOperation(op); err != nil {
-				return err
-			}
-		case code.OpTrue:
-			if err := vm.push(global.True); err != nil {
-				return err
-			}
-		case code.OpFalse:
-			if err := vm.push(global.False); err != nil {
-				return err
-			}
-		case code.OpArray:
-			if ip+2 >= len(ins) {
-				return fmt.Errorf("OpArray: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
-			}
-			res, err := code.ReadUint16(ins[ip+1:], int64(vm.inslen), vm.password, int64(ip+1))
-			if err != nil {
-				return err
-			}
-			numElements := int(res)
-			vm.currentFrame().ip += 2
-			array := vm.buildArray(vm.stackPointer-numElements, vm.stackPointer)
-			if err := vm.push(array); err != nil {
-				return err
-			}
-		case code.OpHash:
-			if ip+2 >= len(ins) {
-				return fmt.Errorf("OpHash: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
-			}
-			res, err := code.ReadUint16(ins[ip+1:], int64(vm.inslen), vm.password, int64(ip+1))
-			if err != nil {
-				return err
-			}
-			numElements := int(res)
-			vm.currentFrame().ip += 2
-			hash, err := vm.buildHash(vm.stackPointer-numElements, vm.stackPointer)
-			if err != nil {
-				return err
-			}
-			vm.stackPointer = vm.stackPointer - numElements
-			if err := vm.push(hash); err != nil {
-				return err
-			}
-		case code.OpEqual, code.OpUnEqual, code.OpGreater:
-			if err := vm.execComparison(op); err != nil {
-				return err
-			}
-		case code.OpJump:
-			if ip+2 >= len(ins) {
-				return fmt.Errorf("OpJump: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
-			}
-			res, err := code.ReadUint16(ins[ip+1:], int64(vm.inslen), vm.password, int64(ip+1))
-			if err != nil {
-				return err
-			}
-			pos := int(res)
+		if err := vm.push(vm.constants[constIndex]); err != nil {
+			return err
+		}
+	case code.OpBang:
+		if err := vm.execBangOperation(); err != nil {
+			return err
+		}
+	case code.OpMinus:
+		if err := vm.execMinusOperation(); err != nil {
+			return err
+		}
+	case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod, code.OpPow,
+		code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShl, code.OpShr:
+		if err := vm.execBinaryOperation(op); err != nil {
+			return err
+		}
+	case code.OpTrue:
+		if err := vm.push(global.True); err != nil {
+			return err
+		}
+	case code.OpFalse:
+		if err := vm.push(global.False); err != nil {
+			return err
+		}
+	case code.OpArray:
+		if ip+2 >= len(ins) {
+			return fmt.Errorf("OpArray: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
+		}
+		res, err := vm.readUint16(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		numElements := int(res)
+		vm.currentFrame().ip += 2
+		array := vm.buildArray(vm.stackPointer-numElements, vm.stackPointer)
+		vm.stackPointer = vm.stackPointer - numElements
+		if err := vm.push(array); err != nil {
+			return err
+		}
+	case code.OpHash:
+		if ip+2 >= len(ins) {
+			return fmt.Errorf("OpHash: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
+		}
+		res, err := vm.readUint16(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		numElements := int(res)
+		vm.currentFrame().ip += 2
+		hash, err := vm.buildHash(vm.stackPointer-numElements, vm.stackPointer)
+		if err != nil {
+			return err
+		}
+		vm.stackPointer = vm.stackPointer - numElements
+		if err := vm.push(hash); err != nil {
+			return err
+		}
+	case code.OpEqual, code.OpUnEqual, code.OpGreater, code.OpGreaterEqual:
+		if err := vm.execComparison(op); err != nil {
+			return err
+		}
+	case code.OpJump:
+		if ip+2 >= len(ins) {
+			return fmt.Errorf("OpJump: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
+		}
+		res, err := vm.readUint16(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		pos := int(res)
+		vm.currentFrame().ip = pos - 1
+	case code.OpJumpFalse:
+		if ip+2 >= len(ins) {
+			return fmt.Errorf("OpJumpFalse: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
+		}
+		res, err := vm.readUint16(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		pos := int(res)
+		vm.currentFrame().ip += 2
+		condition := vm.pop()
+		if !isTruthy(condition) {
 			vm.currentFrame().ip = pos - 1
-		case code.OpJumpFalse:
-			if ip+2 >= len(ins) {
-				return fmt.Errorf("OpJumpFalse: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
-			}
-			res, err := code.ReadUint16(ins[ip+1:], int64(vm.inslen), vm.password, int64(ip+1))
-			if err != nil {
-				return err
-			}
-			pos := int(res)
-			vm.currentFrame().ip += 2
-			condition := vm.pop()
-			if !isTruthy(condition) {
-				vm.currentFrame().ip = pos - 1
-			}
-		case code.OpSetGlobal:
-			if ip+2 >= len(ins) {
-				return fmt.Errorf("OpSetGlobal: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
-			}
-			globalIndex, err := code.ReadUint16(ins[ip+1:], int64(vm.inslen), vm.password, int64(ip+1))
-			if err != nil {
-				return err
-			}
-			vm.currentFrame().ip += 2
-			vm.ensureGlobalCapacity(int(globalIndex))
-			vm.globals[globalIndex] = vm.encryptForStorage(vm.pop())
-		case code.OpGetGlobal:
-			if ip+2 >= len(ins) {
-				return fmt.Errorf("OpGetGlobal: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
-			}
-			globalIndex, err := code.ReadUint16(ins[ip+1:], int64(vm.inslen), vm.password, int64(ip+1))
-			if err != nil {
-				return err
-			}
-			vm.currentFrame().ip += 2
-			vm.ensureGlobalCapacity(int(globalIndex))
-			if err := vm.push(vm.decryptForUse(vm.globals[globalIndex])); err != nil {
-				return err
-			}
-		case code.OpSetLocal:
-			if ip+1 >= len(ins) {
-				return fmt.Errorf("OpSetLocal: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
-			}
-			localIndex, err := code.ReadUint8(ins[ip+1:], int64(vm.inslen), vm.password, int64(ip+1))
-			if err != nil {
-				return err
-			}
-			vm.currentFrame().ip++
-			frame := vm.currentFrame()
-			obj := vm.pop()
-			vm.stack[frame.bp+int(localIndex)] = vm.encryptForStorage(obj)
-		case code.OpGetLocal:
-			if ip+1 >= len(ins) {
-				return fmt.Errorf("OpGetLocal: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
-			}
-			localIndex, err := code.ReadUint8(ins[ip+1:], int64(vm.inslen), vm.password, int64(ip+1))
-			if err != nil {
-				return err
-			}
-			vm.currentFrame().ip++
-			frame := vm.currentFrame()
-			if err := vm.push(vm.decryptForUse(vm.stack[frame.bp+int(localIndex)])); err != nil {
-				return err
-			}
-		case code.OpGetBuiltin:
-			if ip+1 >= len(ins) {
-				return fmt.Errorf("OpGetBuiltin: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
-			}
-			builtinIndex, err := code.ReadUint8(ins[ip+1:], int64(vm.inslen), vm.password, int64(ip+1))
-			if err != nil {
-				return err
-			}
-			vm.currentFrame().ip++
-			if int(builtinIndex) >= len(builtin.Builtins) {
-				return fmt.Errorf("OpGetBuiltin: invalid builtin index=%d, len=%d", builtinIndex, len(builtin.Builtins))
-			}
-			definition := builtin.Builtins[builtinIndex]
-			if err := vm.push(definition.Builtin); err != nil {
-				return err
-			}
-		case code.OpGetFree:
-			if ip+1 >= len(ins) {
-				return fmt.Errorf("OpGetFree: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
-			}
-			freeIndex, err := code.ReadUint8(ins[ip+1:], int64(vm.inslen), vm.password, int64(ip+1))
-			if err != nil {
-				return err
-			}
-			vm.currentFrame().ip++
-			currentClosure := vm.currentFrame().cl
-			if err := vm.push(vm.decryptForUse(currentClosure.Free[freeIndex])); err != nil {
-				return err
-			}
-		case code.OpIndex:
-			index := vm.pop()
-			left := vm.pop()
-			if err := vm.execIndexOperation(left, index); err != nil {
-				return err
-			}
-		case code.OpClosure:
-			if ip+3 >= len(ins) {
-				return fmt.Errorf("OpClosure: not enough bytes for operands at ip=%d, len=%d", ip, len(ins))
-			}
-			constIndex, err := code.ReadUint16(ins[ip+1:], int64(vm.inslen), vm.password, int64(ip+1))
-			if err != nil {
-				return err
-			}
-			numFree, err := code.ReadUint8(ins[ip+3:], int64(vm.inslen), vm.password, int64(ip+3))
-			if err != nil {
-				return err
-			}
-			vm.currentFrame().ip += 3
-			if err := vm.pushClosure(int(constIndex), int(numFree)); err != nil {
-				return err
-			}
-		case code.OpCurrentClosure:
-			currentClosure := vm.currentFrame().cl
-			if err := vm.push(vm.decryptForUse(currentClosure)); err != nil {
-				return err
-			}
-		case code.OpCall:
-			if ip+1 >= len(ins) {
-				return fmt.Errorf("OpCall: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
-			}
-			numArgs, err := code.ReadUint8(ins[ip+1:], int64(vm.inslen), vm.password, int64(ip+1))
-			if err != nil {
-				return err
-			}
-			vm.currentFrame().ip++
-			if err := vm.execCall(int(numArgs)); err != nil {
-				return err
-			}
-		case code.OpReturnValue:
-			returnValue := vm.pop()
-			frame := vm.popFrame()
-			vm.stackPointer = frame.bp - 1
-			if err := vm.push(returnValue); err != nil {
-				return err
-			}
-		case code.OpReturn:
-			frame := vm.popFrame()
-			vm.stackPointer = frame.bp - 1
-			if err := vm.push(global.Null); err != nil {
-				return err
-			}
-		case code.OpNull:
-			if err := vm.push(global.Null); err != nil {
-				return err
-			}
-		case code.OpPop:
-			vm.pop()
-		case code.OpBreak:
-			// Push break sentinel value
-			if err := vm.push(&object.Break{}); err != nil {
-				return err
-			}
-		case code.OpContinue:
-			// Push continue sentinel value
-			if err := vm.push(&object.Continue{}); err != nil {
-				return err
-			}
-		case code.OpMakeStruct:
-			if ip+3 >= len(ins) {
-				return fmt.Errorf("OpMakeStruct: not enough bytes for operands at ip=%d, len=%d", ip, len(ins))
-			}
-			typeIndex, err := code.ReadUint16(ins[ip+1:], int64(vm.inslen), vm.password, int64(ip+1))
-			if err != nil {
-				return err
-			}
-			fieldCountRaw, err := code.ReadUint8(ins[ip+3:], int64(vm.inslen), vm.password, int64(ip+3))
-			if err != nil {
+		}
+	case code.OpJumpFalseOrPop, code.OpJumpTrueOrPop:
+		if ip+2 >= len(ins) {
+			return fmt.Errorf("OpJumpFalseOrPop/OpJumpTrueOrPop: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
+		}
+		res, err := vm.readUint16(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		pos := int(res)
+		vm.currentFrame().ip += 2
+		condition := vm.pop()
+		if isTruthy(condition) == (op == code.OpJumpTrueOrPop) {
+			// The left operand decides the result, so it stays on the stack.
+			if err := vm.push(condition); err != nil {
 				return err
 			}
-			fieldCount := int(fieldCountRaw)
-			vm.currentFrame().ip += 3
+			vm.currentFrame().ip = pos - 1
+		}
+	case code.OpIterInit:
+		iterable := vm.pop()
+		iter, ok := object.NewIterator(iterable)
+		if !ok {
+			return fmt.Errorf("cannot iterate over %s", iterable.Type())
+		}
+		if err := vm.push(iter); err != nil {
+			return err
+		}
+	case code.OpJumpIfArg:
+		if ip+3 >= len(ins) {
+			return fmt.Errorf("OpJumpIfArg: not enough bytes for operands at ip=%d, len=%d", ip, len(ins))
+		}
+		res, err := vm.readUint16(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		paramIndex, err := vm.readUint8(ins, ip+3)
+		if err != nil {
+			return err
+		}
+		vm.currentFrame().ip += 3
+		if int(paramIndex) < vm.currentFrame().argc {
+			vm.currentFrame().ip = int(res) - 1
+		}
+	case code.OpIterNext:
+		if ip+3 >= len(ins) {
+			return fmt.Errorf("OpIterNext: not enough bytes for operands at ip=%d, len=%d", ip, len(ins))
+		}
+		res, err := vm.readUint16(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		bindings, err := vm.readUint8(ins, ip+3)
+		if err != nil {
+			return err
+		}
+		vm.currentFrame().ip += 3
+		if err := vm.execIterNext(int(res), int(bindings)); err != nil {
+			return err
+		}
+	case code.OpSetGlobal:
+		if ip+2 >= len(ins) {
+			return fmt.Errorf("OpSetGlobal: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
+		}
+		globalIndex, err := vm.readUint16(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		vm.currentFrame().ip += 2
+		vm.ensureGlobalCapacity(int(globalIndex))
+		vm.globals[globalIndex] = vm.encryptForStorage(vm.pop())
+	case code.OpGetGlobal:
+		if ip+2 >= len(ins) {
+			return fmt.Errorf("OpGetGlobal: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
+		}
+		globalIndex, err := vm.readUint16(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		vm.currentFrame().ip += 2
+		vm.ensureGlobalCapacity(int(globalIndex))
+		if err := vm.push(vm.decryptForUse(vm.globals[globalIndex])); err != nil {
+			return err
+		}
+	case code.OpSetLocal:
+		if ip+1 >= len(ins) {
+			return fmt.Errorf("OpSetLocal: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
+		}
+		localIndex, err := vm.readUint8(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		vm.currentFrame().ip++
+		frame := vm.currentFrame()
+		obj := vm.pop()
+		vm.stack[frame.bp+int(localIndex)] = vm.encryptForStorage(obj)
+	case code.OpGetLocal:
+		if ip+1 >= len(ins) {
+			return fmt.Errorf("OpGetLocal: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
+		}
+		localIndex, err := vm.readUint8(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		vm.currentFrame().ip++
+		frame := vm.currentFrame()
+		if err := vm.push(vm.loadVariable(vm.stack[frame.bp+int(localIndex)])); err != nil {
+			return err
+		}
+	case code.OpAssignLocal, code.OpCaptureLocal:
+		if ip+1 >= len(ins) {
+			return fmt.Errorf("OpAssignLocal/OpCaptureLocal: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
+		}
+		localIndex, err := vm.readUint8(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		vm.currentFrame().ip++
+		slot := &vm.stack[vm.currentFrame().bp+int(localIndex)]
+		if op == code.OpAssignLocal {
+			vm.assignVariable(slot, vm.pop())
+		} else if err := vm.push(captureVariable(slot)); err != nil {
+			return err
+		}
+	case code.OpGetBuiltin:
+		if ip+1 >= len(ins) {
+			return fmt.Errorf("OpGetBuiltin: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
+		}
+		builtinIndex, err := vm.readUint8(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		vm.currentFrame().ip++
+		if int(builtinIndex) >= len(builtin.Builtins) {
+			return fmt.Errorf("OpGetBuiltin: invalid builtin index=%d, len=%d", builtinIndex, len(builtin.Builtins))
+		}
+		definition := builtin.Builtins[builtinIndex]
+		if err := vm.push(definition.Builtin); err != nil {
+			return err
+		}
+	case code.OpGetFree:
+		if ip+1 >= len(ins) {
+			return fmt.Errorf("OpGetFree: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
+		}
+		freeIndex, err := vm.readUint8(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		vm.currentFrame().ip++
+		currentClosure := vm.currentFrame().cl
+		if err := vm.push(vm.loadVariable(currentClosure.Free[freeIndex])); err != nil {
+			return err
+		}
+	case code.OpSetFree, code.OpCaptureFree:
+		if ip+1 >= len(ins) {
+			return fmt.Errorf("OpSetFree/OpCaptureFree: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
+		}
+		freeIndex, err := vm.readUint8(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		vm.currentFrame().ip++
+		slot := &vm.currentFrame().cl.Free[freeIndex]
+		if op == code.OpSetFree {
+			vm.assignVariable(slot, vm.pop())
+		} else if err := vm.push(captureVariable(slot)); err != nil {
+			return err
+		}
+	case code.OpIndex:
+		index := vm.pop()
+		left := vm.pop()
+		if err := vm.execIndexOperation(left, index); err != nil {
+			return err
+		}
+	case code.OpClosure:
+		if ip+3 >= len(ins) {
+			return fmt.Errorf("OpClosure: not enough bytes for operands at ip=%d, len=%d", ip, len(ins))
+		}
+		constIndex, err := vm.readUint16(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		numFree, err := vm.readUint8(ins, ip+3)
+		if err != nil {
+			return err
+		}
+		vm.currentFrame().ip += 3
+		if err := vm.pushClosure(int(constIndex), int(numFree)); err != nil {
+			return err
+		}
+	case code.OpCurrentClosure:
+		currentClosure := vm.currentFrame().cl
+		if err := vm.push(vm.decryptForUse(currentClosure)); err != nil {
+			return err
+		}
+	case code.OpCall:
+		if ip+1 >= len(ins) {
+			return fmt.Errorf("OpCall: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
+		}
+		numArgs, err := vm.readUint8(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		vm.currentFrame().ip++
+		if err := vm.execCall(int(numArgs)); err != nil {
+			return err
+		}
+	case code.OpTailCall:
+		if ip+1 >= len(ins) {
+			return fmt.Errorf("OpTailCall: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
+		}
+		numArgs, err := vm.readUint8(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		vm.currentFrame().ip++
+		if err := vm.execTailCall(int(numArgs)); err != nil {
+			return err
+		}
+	case code.OpReturnValue:
+		if err := vm.returnFromFrame(vm.pop()); err != nil {
+			return err
+		}
+	case code.OpReturn:
+		if err := vm.returnFromFrame(global.Null); err != nil {
+			return err
+		}
+	case code.OpNull:
+		if err := vm.push(global.Null); err != nil {
+			return err
+		}
+	case code.OpPop:
+		vm.pop()
+	case code.OpBreak:
+		// Push break sentinel value
+		if err := vm.push(&object.Break{}); err != nil {
+			return err
+		}
+	case code.OpContinue:
+		// Push continue sentinel value
+		if err := vm.push(&object.Continue{}); err != nil {
+			return err
+		}
+	case code.OpMakeStruct:
+		if ip+3 >= len(ins) {
+			return fmt.Errorf("OpMakeStruct: not enough bytes for operands at ip=%d, len=%d", ip, len(ins))
+		}
+		typeIndex, err := vm.readUint16(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		fieldCountRaw, err := vm.readUint8(ins, ip+3)
+		if err != nil {
+			return err
+		}
+		fieldCount := int(fieldCountRaw)
+		vm.currentFrame().ip += 3
 
-			typeObj, ok := vm.decryptForUse(vm.constants[typeIndex]).(*object.String)
-			if !ok {
-				return fmt.Errorf("OpMakeStruct: type constant is not string at index=%d", typeIndex)
-			}
-			typeName := typeObj.Value
-
-			// Get field names from struct definition
-			fieldNames := []string{}
-			if structDefVal, exists := vm.structDefs[typeName]; exists {
-				if structDef, ok := structDefVal.([]*ast.Identifier); ok {
-					for _, ident := range structDef {
-						fieldNames = append(fieldNames, ident.Value)
-					}
+		typeObj, ok := vm.decryptForUse(vm.constants[typeIndex]).(*object.String)
+		if !ok {
+			return fmt.Errorf("OpMakeStruct: type constant is not string at index=%d", typeIndex)
+		}
+		typeName := typeObj.Value
+
+		// Get field names from struct definition
+		fieldNames := []string{}
+		if structDefVal, exists := vm.structDefs[typeName]; exists {
+			if structDef, ok := structDefVal.([]*ast.Identifier); ok {
+				for _, ident := range structDef {
+					fieldNames = append(fieldNames, ident.Value)
 				}
 			}
+		}
 
-			// If no definition found, this will cause an error in Inspect but allow execution
-			if len(fieldNames) != fieldCount {
-				return fmt.Errorf("struct %s expects %d fields, got %d", typeName, len(fieldNames), fieldCount)
-			}
+		// If no definition found, this will cause an error in Inspect but allow execution
+		if len(fieldNames) != fieldCount {
+			return fmt.Errorf("struct %s expects %d fields, got %d", typeName, len(fieldNames), fieldCount)
+		}
 
-			fields := make(map[string]object.Object)
-			for i := 0; i < fieldCount; i++ {
-				fieldValue := vm.pop()
-				// Pop in reverse order (last field popped first)
-				fieldName := fieldNames[fieldCount-1-i]
-				fields[fieldName] = fieldValue
-			}
+		fields := make(map[string]object.Object)
+		for i := 0; i < fieldCount; i++ {
+			fieldValue := vm.pop()
+			// Pop in reverse order (last field popped first)
+			fieldName := fieldNames[fieldCount-1-i]
+			fields[fieldName] = fieldValue
+		}
 
-			structObj := &object.Struct{
-				TypeName: typeName,
-				Fields:   fields,
-			}
-			if err := vm.push(structObj); err != nil {
-				return err
-			}
-		case code.OpGetField:
-			if ip+2 >= len(ins) {
-				return fmt.Errorf("OpGetField: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
-			}
-			fieldNameIndex, err := code.ReadUint16(ins[ip+1:], int64(vm.inslen), vm.password, int64(ip+1))
-			if err != nil {
-				return err
-			}
-			vm.currentFrame().ip += 2
+		structObj := &object.Struct{
+			TypeName: typeName,
+			Fields:   fields,
+		}
+		if err := vm.push(structObj); err != nil {
+			return err
+		}
+	case code.OpGetField:
+		if ip+2 >= len(ins) {
+			return fmt.Errorf("OpGetField: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
+		}
+		fieldNameIndex, err := vm.readUint16(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		vm.currentFrame().ip += 2
 
-			fieldObj, ok := vm.decryptForUse(vm.constants[fieldNameIndex]).(*object.String)
-			if !ok {
-				return fmt.Errorf("OpGetField: field constant is not string at index=%d", fieldNameIndex)
-			}
-			fieldName := fieldObj.Value
-			obj := vm.pop()
-			if structObj, ok := obj.(*object.Struct); ok {
-				if val, exists := structObj.Fields[fieldName]; exists {
-					if err := vm.push(val); err != nil {
-						return err
-					}
-				} else {
-					if err := vm.push(global.Null); err != nil {
-						return err
-					}
+		fieldObj, ok := vm.decryptForUse(vm.constants[fieldNameIndex]).(*object.String)
+		if !ok {
+			return fmt.Errorf("OpGetField: field constant is not string at index=%d", fieldNameIndex)
+		}
+		fieldName := fieldObj.Value
+		obj := vm.pop()
+		if structObj, ok := obj.(*object.Struct); ok {
+			if val, exists := structObj.Fields[fieldName]; exists {
+				if err := vm.push(val); err != nil {
+					return err
 				}
-			} else {
-				return fmt.Errorf("cannot access field on non-struct: %s", obj.Type())
-			}
-		case code.OpSetField:
-			if ip+2 >= len(ins) {
-				return fmt.Errorf("OpSetField: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
-			}
-			fieldNameIndex, err := code.ReadUint16(ins[ip+1:], int64(vm.inslen), vm.password, int64(ip+1))
-			if err != nil {
-				return err
-			}
-			vm.currentFrame().ip += 2
-
-			fieldObj, ok := vm.decryptForUse(vm.constants[fieldNameIndex]).(*object.String)
-			if !ok {
-				return fmt.Errorf("OpSetField: field constant is not string at index=%d", fieldNameIndex)
-			}
-			fieldName := fieldObj.Value
-			value := vm.pop()
-			obj := vm.pop()
-			if structObj, ok := obj.(*object.Struct); ok {
-				structObj.Fields[fieldName] = value
-				if err := vm.push(structObj); err != nil {
+			} else if method, exists := vm.boundMethod(structObj, fieldName); exists {
+				if err := vm.push(method); err != nil {
 					return err
 				}
 			} else {
-				return fmt.Errorf("cannot set field on non-struct: %s", obj.Type())
-			}
-		case code.OpEnumValue:
-			if ip+4 >= len(ins) {
-				return fmt.Errorf("OpEnumValue: not enough bytes for operands at ip=%d, len=%d", ip, len(ins))
+				if err := vm.push(global.Null); err != nil {
+					return err
+				}
 			}
-			typeIndex, err := code.ReadUint16(ins[ip+1:], int64(vm.inslen), vm.password, int64(ip+1))
-			if err != nil {
+		} else if errObj, ok := obj.(*object.Error); ok && fieldName == "message" {
+			if err := vm.push(&object.String{Value: errObj.Message}); err != nil {
 				return err
 			}
-			tagIndex, err := code.ReadUint16(ins[ip+3:], int64(vm.inslen), vm.password, int64(ip+3))
-			if err != nil {
+		} else {
+			return fmt.Errorf("cannot access field on non-struct: %s", obj.Type())
+		}
+	case code.OpSetField:
+		if ip+2 >= len(ins) {
+			return fmt.Errorf("OpSetField: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
+		}
+		fieldNameIndex, err := vm.readUint16(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		vm.currentFrame().ip += 2
+
+		fieldObj, ok := vm.decryptForUse(vm.constants[fieldNameIndex]).(*object.String)
+		if !ok {
+			return fmt.Errorf("OpSetField: field constant is not string at index=%d", fieldNameIndex)
+		}
+		fieldName := fieldObj.Value
+		value := vm.pop()
+		obj := vm.pop()
+		if structObj, ok := obj.(*object.Struct); ok {
+			structObj.Fields[fieldName] = value
+			if err := vm.push(structObj); err != nil {
 				return err
 			}
-			vm.currentFrame().ip += 4
+		} else {
+			return fmt.Errorf("cannot set field on non-struct: %s", obj.Type())
+		}
+	case code.OpEnumValue:
+		if ip+4 >= len(ins) {
+			return fmt.Errorf("OpEnumValue: not enough bytes for operands at ip=%d, len=%d", ip, len(ins))
+		}
+		typeIndex, err := vm.readUint16(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		tagIndex, err := vm.readUint16(ins, ip+3)
+		if err != nil {
+			return err
+		}
+		vm.currentFrame().ip += 4
 
-			typeObj, ok := vm.decryptForUse(vm.constants[typeIndex]).(*object.String)
-			if !ok {
-				return fmt.Errorf("OpEnumValue: type constant is not string at index=%d", typeIndex)
-			}
-			tagObj, ok := vm.decryptForUse(vm.constants[tagIndex]).(*object.String)
-			if !ok {
-				return fmt.Errorf("OpEnumValue: tag constant is not string at index=%d", tagIndex)
-			}
-			typeName := typeObj.Value
-			tagName := tagObj.Value
-			ordinal := -1
-
-			if tagsVal, ok := vm.enumDefs[typeName]; ok {
-				if tags, ok := tagsVal.([]string); ok {
-					for idx, t := range tags {
-						if t == tagName {
-							ordinal = idx
-							break
-						}
-					}
-					if ordinal < 0 {
-						return fmt.Errorf("unknown enum tag %s.%s", typeName, tagName)
+		typeObj, ok := vm.decryptForUse(vm.constants[typeIndex]).(*object.String)
+		if !ok {
+			return fmt.Errorf("OpEnumValue: type constant is not string at index=%d", typeIndex)
+		}
+		tagObj, ok := vm.decryptForUse(vm.constants[tagIndex]).(*object.String)
+		if !ok {
+			return fmt.Errorf("OpEnumValue: tag constant is not string at index=%d", tagIndex)
+		}
+		typeName := typeObj.Value
+		tagName := tagObj.Value
+		ordinal := -1
+
+		if tagsVal, ok := vm.enumDefs[typeName]; ok {
+			if tags, ok := tagsVal.([]string); ok {
+				for idx, t := range tags {
+					if t == tagName {
+						ordinal = idx
+						break
 					}
 				}
-			}
-
-			enumObj := &object.EnumValue{TypeName: typeName, Tag: tagName, Value: &object.Integer{Value: int64(ordinal)}}
-			if err := vm.push(enumObj); err != nil {
-				return err
+				if ordinal < 0 {
+					return fmt.Errorf("unknown enum tag %s.%s", typeName, tagName)
+				}
 			}
 		}
-	}
 
-	if err := vm.validateSecurityCheckOpcodes("after-execution"); err != nil {
-		return err
+		enumObj := &object.EnumValue{TypeName: typeName, Tag: tagName, Value: &object.Integer{Value: int64(ordinal)}}
+		if err := vm.push(enumObj); err != nil {
+			return err
+		}
+	case code.OpMakeEnum:
+		typeName, tagName, err := vm.readStringOperands("OpMakeEnum", ins, ip)
+		if err != nil {
+			return err
+		}
+		payload := vm.pop()
+		if err := vm.push(&object.EnumValue{TypeName: typeName, Tag: tagName, Value: payload}); err != nil {
+			return err
+		}
+	case code.OpMatchTag:
+		typeName, tagName, err := vm.readStringOperands("OpMatchTag", ins, ip)
+		if err != nil {
+			return err
+		}
+		subject := vm.pop()
+		enumObj, ok := subject.(*object.EnumValue)
+		if !ok || enumObj.TypeName != typeName {
+			return fmt.Errorf("cannot match %s against %s", subject.Inspect(), typeName)
+		}
+		if err := vm.push(nativeBoolToBooleanObject(enumObj.Tag == tagName)); err != nil {
+			return err
+		}
+	case code.OpEnumPayload:
+		enumObj, ok := vm.pop().(*object.EnumValue)
+		if !ok {
+			return fmt.Errorf("OpEnumPayload: operand is not an enum value at ip=%d", ip)
+		}
+		payload := enumObj.Value
+		if payload == nil {
+			payload = global.Null
+		}
+		if err := vm.push(payload); err != nil {
+			return err
+		}
+	case code.OpUnpackArray:
+		if ip+3 >= len(ins) {
+			return fmt.Errorf("OpUnpackArray: not enough bytes for operands at ip=%d, len=%d", ip, len(ins))
+		}
+		count, err := vm.readUint16(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		hasRest, err := vm.readUint8(ins, ip+3)
+		if err != nil {
+			return err
+		}
+		vm.currentFrame().ip += 3
+		if err := vm.execUnpackArray(int(count), hasRest == 1); err != nil {
+			return err
+		}
+	case code.OpUnpackKey:
+		if ip+2 >= len(ins) {
+			return fmt.Errorf("OpUnpackKey: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
+		}
+		keyIndex, err := vm.readUint16(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		vm.currentFrame().ip += 2
+		keyObj, ok := vm.decryptForUse(vm.constants[keyIndex]).(*object.String)
+		if !ok {
+			return fmt.Errorf("OpUnpackKey: key constant is not string at index=%d", keyIndex)
+		}
+		if err := vm.execUnpackKey(keyObj.Value); err != nil {
+			return err
+		}
+	case code.OpUnpackField:
+		fieldName, typeName, err := vm.readStringOperands("OpUnpackField", ins, ip)
+		if err != nil {
+			return err
+		}
+		if err := vm.execUnpackField(fieldName, typeName); err != nil {
+			return err
+		}
+	case code.OpTry:
+		if ip+2 >= len(ins) {
+			return fmt.Errorf("OpTry: not enough bytes for operand at ip=%d, len=%d", ip, len(ins))
+		}
+		handlerIndex, err := vm.readUint16(ins, ip+1)
+		if err != nil {
+			return err
+		}
+		vm.currentFrame().ip += 2
+		if err := vm.enterTry(int(handlerIndex), ip); err != nil {
+			return err
+		}
+	case code.OpEndTry:
+		frame := vm.currentFrame()
+		if len(frame.tries) == 0 {
+			return fmt.Errorf("OpEndTry: no active handler at ip=%d", ip)
+		}
+		frame.tries = frame.tries[:len(frame.tries)-1]
+	case code.OpThrow:
+		return &thrownError{value: vm.pop()}
 	}
 
 	return nil
@@ -1098,7 +1348,39 @@ func (vm *VM) execBinaryIntegerOperation(op code.Opcode, left, right object.Obje
 	case code.OpMul:
 		result = lval * rval
 	case code.OpDiv:
+		if rval == 0 {
+			return fmt.Errorf("division by zero")
+		}
 		result = lval / rval
+	case code.OpMod:
+		if rval == 0 {
+			return fmt.Errorf("modulo by zero")
+		}
+		result = lval % rval
+	case code.OpPow:
+		if rval < 0 {
+			// negative exponents cannot stay integral
+			return vm.push(&object.Float{Value: math.Pow(float64(lval), float64(rval))})
+		}
+		var ok bool
+		if result, ok = object.IntPow(lval, rval); !ok {
+			return fmt.Errorf("%d ** %d is out of range for INTEGER", lval, rval)
+		}
+	case code.OpBitAnd:
+		result = lval & rval
+	case code.OpBitOr:
+		result = lval | rval
+	case code.OpBitXor:
+		result = lval ^ rval
+	case code.OpShl, code.OpShr:
+		if rval < 0 {
+			return fmt.Errorf("negative shift count: %d", rval)
+		}
+		if op == code.OpShl {
+			result = lval << uint64(rval)
+		} else {
+			result = lval >> uint64(rval)
+		}
 	default:
 		return fmt.Errorf("Unknown integer operator: %d", op)
 	}
@@ -1129,6 +1411,10 @@ func (vm *VM) execBinaryFloatOperation(op code.Opcode, left, right object.Object
 		result = lval / rval
 	case code.OpMod:
 		result = math.Mod(lval, rval)
+	case code.OpPow:
+		result = math.Pow(lval, rval)
+	case code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShl, code.OpShr:
+		return fmt.Errorf("bitwise operators require integer operands: %s, %s", left.Type(), right.Type())
 	default:
 		return fmt.Errorf("Unknown float operator: %d", op)
 	}
@@ -1217,6 +1503,33 @@ func (vm *VM) execBangOperation() error {
 	}
 }
 
+// execIterNext pops a cursor and iterator and pushes the entry at the cursor,
+// or jumps to exit once the iterator is exhausted
+func (vm *VM) execIterNext(exit, bindings int) error {
+	cursor, ok := vm.pop().(*object.Integer)
+	if !ok {
+		return fmt.Errorf("OpIterNext: cursor is not an integer")
+	}
+	iter, ok := vm.pop().(*object.Iterator)
+	if !ok {
+		return fmt.Errorf("OpIterNext: operand is not an iterator")
+	}
+
+	i := int(cursor.Value)
+	if i >= iter.Len() {
+		vm.currentFrame().ip = exit - 1
+		return nil
+	}
+
+	if bindings == 2 {
+		if err := vm.push(iter.Key(i)); err != nil {
+			return err
+		}
+		return vm.push(iter.Value(i))
+	}
+	return vm.push(iter.Single(i))
+}
+
 func (vm *VM) execMinusOperation() error {
 	operand := vm.pop()
 	assertion := mutil.AssertObjectTypes(string(operand.Type()), object.INTEGER_OBJ, object.FLOAT_OBJ)
@@ -1251,6 +1564,9 @@ func (vm *VM) execComparison(op code.Opcode) error {
 	if ans1 && ans2 {
 		return vm.execFloatComparison(op, left, right)
 	}
+	if ltype == object.STRING_OBJ && rtype == object.STRING_OBJ {
+		return vm.execStringComparison(op, left, right)
+	}
 
 	switch op {
 	case code.OpEqual:
@@ -1258,8 +1574,42 @@ func (vm *VM) execComparison(op code.Opcode) error {
 	case code.OpUnEqual:
 		return vm.push(nativeBoolToBooleanObject(right.Inspect() != left.Inspect()))
 	default:
-		return fmt.Errorf("unknown operator: %d (%s %s)", op, left.Type(), right.Type())
+		return comparisonError(op, left, right)
+	}
+}
+
+// execStringComparison orders strings byte-wise, as the evaluator does
+func (vm *VM) execStringComparison(op code.Opcode, left, right object.Object) error {
+	leftValue := left.(*object.String).Value
+	rightValue := right.(*object.String).Value
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(rightValue == leftValue))
+	case code.OpUnEqual:
+		return vm.push(nativeBoolToBooleanObject(rightValue != leftValue))
+	case code.OpGreater:
+		return vm.push(nativeBoolToBooleanObject(leftValue > rightValue))
+	case code.OpGreaterEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue >= rightValue))
+	default:
+		return comparisonError(op, left, right)
+	}
+}
+
+// comparisonError reports an unsupported comparison in the evaluator's
+// words. a < b and a <= b reach the VM with their operands swapped, so they
+// are reported as b > a and b >= a.
+func comparisonError(op code.Opcode, left, right object.Object) error {
+	symbol := map[code.Opcode]string{
+		code.OpEqual:        "==",
+		code.OpUnEqual:      "!=",
+		code.OpGreater:      ">",
+		code.OpGreaterEqual: ">=",
+	}[op]
+	if left.Type() != right.Type() {
+		return fmt.Errorf("type mismatch: %s%s%s", left.Type(), symbol, right.Type())
 	}
+	return fmt.Errorf("unknown operator: %s%s%s", left.Type(), symbol, right.Type())
 }
 
 func (vm *VM) execFloatComparison(op code.Opcode, left, right object.Object) error {
@@ -1272,6 +1622,8 @@ func (vm *VM) execFloatComparison(op code.Opcode, left, right object.Object) err
 		return vm.push(nativeBoolToBooleanObject(rightValue != leftValue))
 	case code.OpGreater:
 		return vm.push(nativeBoolToBooleanObject(leftValue > rightValue))
+	case code.OpGreaterEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue >= rightValue))
 	default:
 		return fmt.Errorf("unknown operator: %d", op)
 	}
@@ -1286,6 +1638,8 @@ func (vm *VM) execIntegerComparison(op code.Opcode, left, right object.Object) e
 		return vm.push(nativeBoolToBooleanObject(rightValue != leftValue))
 	case code.OpGreater:
 		return vm.push(nativeBoolToBooleanObject(leftValue > rightValue))
+	case code.OpGreaterEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue >= rightValue))
 	default:
 		return fmt.Errorf("unknown operator: %d", op)
 	}
@@ -1300,7 +1654,7 @@ func (vm *VM) buildArray(startIndex, endIndex int) object.Object {
 }
 
 func (vm *VM) buildHash(startIndex, endIndex int) (object.Object, error) {
-	hashedPairs := make(map[object.HashKey]object.HashPair)
+	hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
 	for i := startIndex; i < endIndex; i += 2 {
 		key := vm.decryptForUse(vm.stack[i])
 		value := vm.decryptForUse(vm.stack[i+1])
@@ -1310,9 +1664,9 @@ func (vm *VM) buildHash(startIndex, endIndex int) (object.Object, error) {
 		if !ok {
 			return nil, fmt.Errorf("unusable as a hashkey: %s", key.Type())
 		}
-		hashedPairs[hashKey.HashKey()] = pair
+		hash.Set(hashKey.HashKey(), pair)
 	}
-	return &object.Hash{Pairs: hashedPairs}, nil
+	return hash, nil
 }
 
 func (vm *VM) currentFrame() *Frame { return vm.frames[vm.frameIndex-1] }
@@ -1331,9 +1685,10 @@ func (vm *VM) popFrame() *Frame {
 
 func (vm *VM) execCall(numArgs int) error {
 	var callee object.Object
-	if vm.stack[vm.stackPointer-1-numArgs].Type() == object.CLOSURE_OBJ || vm.stack[vm.stackPointer-1-numArgs].Type() == object.BUILTIN_OBJ {
+	switch vm.stack[vm.stackPointer-1-numArgs].Type() {
+	case object.CLOSURE_OBJ, object.BUILTIN_OBJ, object.BOUND_METHOD_OBJ:
 		callee = vm.stack[vm.stackPointer-1-numArgs]
-	} else {
+	default:
 		callee = vm.stack[0]
 	}
 
@@ -1342,24 +1697,131 @@ func (vm *VM) execCall(numArgs int) error {
 		return vm.callClosure(calleeType, numArgs)
 	case *builtin.BuiltIn:
 		return vm.callBuiltin(calleeType, numArgs)
+	case *object.BoundMethod:
+		return vm.callBoundMethod(calleeType, numArgs)
 
 	default:
 		return fmt.Errorf("calling non-function and non-built-in")
 	}
 }
 
+// returnFromFrame leaves the current frame, dropping its locals and callee
+// slot, and pushes value for the caller
+func (vm *VM) returnFromFrame(value object.Object) error {
+	frame := vm.popFrame()
+	vm.stackPointer = frame.bp - 1
+	if err := vm.releaseFrameCache(frame, true); err != nil {
+		return err
+	}
+	return vm.push(value)
+}
+
+// execTailCall performs a call whose result the current frame returns
+// unchanged. For closures and bound methods the current frame is verified and
+// discarded first, and the callee with its arguments is moved down into its
+// callee slot, so recursion in tail position runs in constant frame space.
+// A frame that fails verification ends the run; like every integrity failure
+// it matches security.ErrIntegrityFailed, so no try in a caller can catch it.
+// Builtins do not take a frame, so they are called normally and their result
+// returned.
+func (vm *VM) execTailCall(numArgs int) error {
+	calleeIndex := vm.stackPointer - 1 - numArgs
+	switch vm.stack[calleeIndex].Type() {
+	case object.CLOSURE_OBJ, object.BOUND_METHOD_OBJ:
+	default:
+		if err := vm.execCall(numArgs); err != nil {
+			return err
+		}
+		return vm.returnFromFrame(vm.pop())
+	}
+	if vm.frameIndex <= 1 {
+		return vm.execCall(numArgs)
+	}
+
+	frame := vm.currentFrame()
+	if err := vm.verifyFrameIntegrity(frame, "vm-tail-call"); err != nil {
+		return err
+	}
+	vm.popFrame()
+	if err := vm.releaseFrameCache(frame, true); err != nil {
+		return err
+	}
+
+	base := frame.bp - 1
+	copy(vm.stack[base:], vm.stack[calleeIndex:vm.stackPointer])
+	vm.stackPointer = base + 1 + numArgs
+	return vm.execCall(numArgs)
+}
+
+// callClosure enters cl with numArgs arguments on the stack. Omitted
+// parameters with defaults are left for the function's OpJumpIfArg prologue
+// to fill in; for a variadic function the arguments past its named
+// parameters are packed into the rest array.
 func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
-	if numArgs != cl.Fn.NumParams {
-		return fmt.Errorf("wrong number of arguments. want=%d, got=%d", cl.Fn.NumParams, numArgs)
+	fn := cl.Fn
+	if msg := object.ArityError(fn.NumParams, fn.NumDefaults, fn.Variadic, numArgs); msg != "" {
+		return errors.New(msg)
+	}
+
+	base := vm.stackPointer - numArgs
+	argc := min(numArgs, fn.NumParams)
+	var rest object.Object
+	if fn.Variadic {
+		rest = vm.buildArray(base+argc, vm.stackPointer)
+	}
+
+	// The remaining local slots may still hold cells of a finished frame,
+	// which a capture before assignment must not pick up.
+	vm.ensureStackCapacity(base + fn.NumLocals)
+	clear(vm.stack[base+argc : base+fn.NumLocals])
+	if rest != nil {
+		vm.stack[base+fn.NumParams] = vm.encryptForStorage(rest)
 	}
 
-	frame := NewFrame(cl, vm.stackPointer-numArgs)
+	frame := NewFrame(cl, base)
+	frame.argc = argc
 	vm.pushFrame(frame)
-	vm.ensureStackCapacity(frame.bp + cl.Fn.NumLocals)
-	vm.stackPointer = frame.bp + cl.Fn.NumLocals
+	vm.stackPointer = base + fn.NumLocals
 	return nil
 }
 
+// boundMethod looks up a method of the receiver's struct type and binds it
+func (vm *VM) boundMethod(receiver *object.Struct, name string) (*object.BoundMethod, bool) {
+	constIndex, ok := vm.structMethods[receiver.TypeName][name]
+	if !ok || constIndex < 0 || constIndex >= len(vm.constants) {
+		return nil, false
+	}
+	fn, ok := vm.constants[constIndex].(*object.CompiledFunction)
+	if !ok {
+		return nil, false
+	}
+	return &object.BoundMethod{Receiver: receiver, Name: name, Method: &object.Closure{Fn: fn}}, true
+}
+
+// callBoundMethod inserts the receiver below the arguments so it lands in the
+// method's `self` slot, then calls the method like any closure.
+func (vm *VM) callBoundMethod(stored *object.BoundMethod, numArgs int) error {
+	bound, ok := vm.decryptForUse(stored).(*object.BoundMethod)
+	if !ok {
+		return fmt.Errorf("calling non-function and non-built-in")
+	}
+	method, ok := bound.Method.(*object.Closure)
+	if !ok {
+		return fmt.Errorf("calling non-function and non-built-in")
+	}
+	if msg := object.ArityError(method.Fn.NumParams-1, method.Fn.NumDefaults, method.Fn.Variadic, numArgs); msg != "" {
+		return errors.New(msg)
+	}
+
+	base := vm.stackPointer - numArgs
+	vm.ensureStackCapacity(vm.stackPointer + 1)
+	copy(vm.stack[base+1:vm.stackPointer+1], vm.stack[base:vm.stackPointer])
+	vm.stack[base] = vm.encryptForStorage(bound.Receiver)
+	vm.stackPointer++
+
+	return vm.callClosure(method, numArgs+1)
+}
+
 func (vm *VM) registerFrameIntegrity(fn *object.CompiledFunction) {
 	if vm.frameIntegrity == nil {
 		vm.frameIntegrity = make(map[*object.CompiledFunction][32]byte)
@@ -1417,14 +1879,35 @@ func (vm *VM) verifyFrameIntegrity(frame *Frame, stage string) error {
 }
 
 func (vm *VM) callBuiltin(builtin *builtin.BuiltIn, numArgs int) error {
+	if err := vm.checkBuiltinCapability(builtin); err != nil {
+		return err
+	}
+
 	storedArgs := vm.stack[vm.stackPointer-numArgs : vm.stackPointer]
 	args := make([]object.Object, len(storedArgs))
 	for i, arg := range storedArgs {
 		args[i] = vm.decryptForUse(arg)
 	}
-	result := builtin.Fn(args...)
+	var result object.Object
+	var err error
+	if builtin.Callback != nil {
+		result, err = builtin.Callback(vm, args...)
+	} else {
+		result = builtin.Fn(args...)
+	}
 
 	vm.stackPointer = vm.stackPointer - numArgs - 1
+	if err != nil {
+		return err
+	}
+
+	// A builtin failure is raised when a try is active in this function or
+	// any function that called it, so a helper wrapping a builtin fails into
+	// its caller's catch; outside any try it stays a plain value for the
+	// caller to inspect.
+	if errObj, ok := result.(*object.Error); ok && vm.inTry() {
+		return &thrownError{value: errObj}
+	}
 
 	if result != nil {
 		vm.push(result)
@@ -1435,6 +1918,20 @@ func (vm *VM) callBuiltin(builtin *builtin.BuiltIn, numArgs int) error {
 	return nil
 }
 
+func (vm *VM) checkBuiltinCapability(fn *builtin.BuiltIn) error {
+	if vm.capabilityPolicy == nil {
+		return nil
+	}
+
+	name, capability, ok := builtin.LookupBuiltin(fn)
+	if !ok || security.BuiltinCapabilityAllowed(vm.capabilityPolicy, capability) {
+		return nil
+	}
+
+	security.RecordCapabilityDenied("vm-builtin:" + name)
+	return fmt.Errorf("%w: %s requires %q", security.ErrCapabilityDenied, name, capability)
+}
+
 func nativeBoolToBooleanObject(native bool) *object.Boolean {
 	if native {
 		return global.True
@@ -1452,3 +1949,30 @@ func isTruthy(obj object.Object) bool {
 		return true
 	}
 }
+
+// readStringOperands decodes two 2-byte operands that both index string
+// constants, such as the type and tag of the enum opcodes.
+func (vm *VM) readStringOperands(name string, ins code.Instructions, ip int) (string, string, error) {
+	if ip+4 >= len(ins) {
+		return "", "", fmt.Errorf("%s: not enough bytes for operands at ip=%d, len=%d", name, ip, len(ins))
+	}
+	firstIndex, err := vm.readUint16(ins, ip+1)
+	if err != nil {
+		return "", "", err
+	}
+	secondIndex, err := vm.readUint16(ins, ip+3)
+	if err != nil {
+		return "", "", err
+	}
+	vm.currentFrame().ip += 4
+
+	first, ok := vm.decryptForUse(vm.constants[firstIndex]).(*object.String)
+	if !ok {
+		return "", "", fmt.Errorf("%s: constant is not string at index=%d", name, firstIndex)
+	}
+	second, ok := vm.decryptForUse(vm.constants[secondIndex]).(*object.String)
+	if !ok {
+		return "", "", fmt.Errorf("%s: constant is not string at index=%d", name, secondIndex)
+	}
+	return first.Value, second.Value, nil
+}
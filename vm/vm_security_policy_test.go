@@ -2,6 +2,7 @@ package vm
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"strings"
 	"testing"
@@ -10,6 +11,7 @@ import (
 	"mutant/code"
 	"mutant/compiler"
 	"mutant/mutil"
+	"mutant/object"
 	"mutant/security"
 )
 
@@ -209,3 +211,179 @@ func TestControlFlowIntegrityAllowsInitialFrameSentinel(t *testing.T) {
 		t.Fatalf("expected no integrity failure telemetry for initial sentinel ip")
 	}
 }
+
+func TestBuiltinCapabilityPolicyDeniesUngrantedBuiltin(t *testing.T) {
+	security.ResetSecurityTelemetry()
+
+	vm := capabilityPolicyVM(t, `fs_exists("mutant-capability-probe");`, map[string]struct{}{security.CapabilityNetwork: {}})
+	err := vm.Run()
+	if !errors.Is(err, security.ErrCapabilityDenied) {
+		t.Fatalf("expected ErrCapabilityDenied, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "fs_exists") || !strings.Contains(err.Error(), security.CapabilityFilesystem) {
+		t.Fatalf("expected error to name builtin and capability, got: %v", err)
+	}
+
+	snapshot := security.SecurityTelemetrySnapshot()
+	if snapshot["capability_denied"] != 1 {
+		t.Fatalf("expected capability_denied=1, got %d", snapshot["capability_denied"])
+	}
+}
+
+func TestBuiltinCapabilityPolicyAllowsGrantedAndUngatedBuiltins(t *testing.T) {
+	vm := capabilityPolicyVM(t, `len("abc");`, map[string]struct{}{})
+	if err := vm.Run(); err != nil {
+		t.Fatalf("expected ungated builtin to run under empty policy, got: %v", err)
+	}
+	if err := testIntegerObject(3, vm.LastPoppedStackElement()); err != nil {
+		t.Fatal(err)
+	}
+
+	vm = capabilityPolicyVM(t, `fs_exists("mutant-capability-probe");`, map[string]struct{}{security.CapabilityAll: {}})
+	if err := vm.Run(); err != nil {
+		t.Fatalf("expected all policy to grant filesystem builtin, got: %v", err)
+	}
+}
+
+func capabilityPolicyVM(t *testing.T, input string, policy map[string]struct{}) *VM {
+	t.Helper()
+	comp := compiler.New()
+	if err := comp.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	bc := mutil.EncryptByteCode(comp.ByteCode(), "testpwd")
+	vm := NewWithGlobalStoreAndPassword(bc, nil, "testpwd")
+	vm.SetBuiltinCapabilityPolicy(policy)
+	return vm
+}
+
+func TestCapabilityDenialIsNotCatchable(t *testing.T) {
+	security.ResetSecurityTelemetry()
+
+	vm := capabilityPolicyVM(t, `try { fs_exists("mutant-capability-probe"); } catch (e) { 1; }`, map[string]struct{}{})
+	err := vm.Run()
+	if !errors.Is(err, security.ErrCapabilityDenied) {
+		t.Fatalf("expected ErrCapabilityDenied to escape try, got: %v", err)
+	}
+}
+
+func TestCapabilityDenialReachesThroughCallbacks(t *testing.T) {
+	security.ResetSecurityTelemetry()
+
+	vm := capabilityPolicyVM(t, `try { map(["mutant-capability-probe"], fs_exists); } catch (e) { 1; }`, map[string]struct{}{})
+	err := vm.Run()
+	if !errors.Is(err, security.ErrCapabilityDenied) {
+		t.Fatalf("expected ErrCapabilityDenied from a builtin passed as callback, got: %v", err)
+	}
+}
+
+func TestTamperedHandlerTableIsRejected(t *testing.T) {
+	security.ResetSecurityTelemetry()
+	t.Setenv(security.TamperResponseEnv, security.TamperResponseTerminate)
+
+	comp := compiler.New()
+	if err := comp.Compile(parse(`try { throw 1; } catch (e) { e; }`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bc := comp.ByteCode()
+	bc.Handlers[0].Target = 1 // inside the OpTry operand
+
+	vm := NewWithGlobalStoreAndPassword(mutil.EncryptByteCode(bc, "testpwd"), nil, "testpwd")
+	if err := vm.Run(); err == nil {
+		t.Fatalf("expected tampered handler table to fail")
+	}
+
+	snapshot := security.SecurityTelemetrySnapshot()
+	if snapshot["integrity_failed"] == 0 {
+		t.Fatalf("expected integrity failure telemetry for tampered handler table")
+	}
+}
+
+func TestIntegrityFailureInsideTryIsNotCatchable(t *testing.T) {
+	security.ResetSecurityTelemetry()
+	t.Setenv(security.TamperResponseEnv, security.TamperResponseTerminate)
+
+	comp := compiler.New()
+	if err := comp.Compile(parse(`try { try { throw 1; } catch (e) { e; } } catch (e) { 99; }`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bc := comp.ByteCode()
+	inner := 0
+	for i, handler := range bc.Handlers {
+		if handler.Start > bc.Handlers[inner].Start {
+			inner = i
+		}
+	}
+	bc.Handlers[inner].Target = bc.Handlers[inner].Start + 1 // inside the OpTry operand
+
+	vm := NewWithGlobalStoreAndPassword(mutil.EncryptByteCode(bc, "testpwd"), nil, "testpwd")
+	err := vm.Run()
+	if !errors.Is(err, security.ErrIntegrityFailed) {
+		t.Fatalf("expected ErrIntegrityFailed to escape the outer try, got: %v", err)
+	}
+}
+
+func TestUnwindVerifiesHandlerFrame(t *testing.T) {
+	security.ResetSecurityTelemetry()
+	t.Setenv(security.TamperResponseEnv, security.TamperResponseTerminate)
+
+	comp := compiler.New()
+	if err := comp.Compile(parse(`let f = fn() { throw 1 }; try { f(); } catch (e) { e; }`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	vm := NewWithGlobalStoreAndPassword(mutil.EncryptByteCode(comp.ByteCode(), "testpwd"), nil, "testpwd")
+	vm.integrityEvery = 0 // leave detection to the unwinder
+
+	// Corrupt the main frame after its integrity hash was recorded; the throw
+	// inside f must not resume it.
+	main := vm.currentFrame().cl.Fn
+	last := len(main.Instructions) - 1
+	main.Instructions[last] ^= 0xFF
+
+	if err := vm.Run(); err == nil {
+		t.Fatalf("expected unwinding into a tampered frame to fail")
+	}
+
+	snapshot := security.SecurityTelemetrySnapshot()
+	if snapshot["integrity_failed"] == 0 {
+		t.Fatalf("expected integrity failure telemetry from unwinding")
+	}
+}
+
+func TestTailCallFromTamperedFrameIsNotCatchable(t *testing.T) {
+	security.ResetSecurityTelemetry()
+	t.Setenv(security.TamperResponseEnv, security.TamperResponseTerminate)
+
+	comp := compiler.New()
+	input := `let g = fn(n) { n }; let f = fn(n) { g(n) }; let r = 0; try { r = f(1); } catch (e) { r = 99; }; r`
+	if err := comp.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bc := mutil.EncryptByteCode(comp.ByteCode(), "testpwd")
+	vm := NewWithGlobalStoreAndPassword(bc, nil, "testpwd")
+	vm.integrityEvery = 0 // leave detection to the tail call
+
+	var fns []*object.CompiledFunction
+	for _, constant := range bc.Constants {
+		if fn, ok := constant.(*object.CompiledFunction); ok {
+			fns = append(fns, fn)
+		}
+	}
+	if len(fns) != 2 {
+		t.Fatalf("expected 2 compiled functions, got %d", len(fns))
+	}
+	// Record f's hash as loaded, then corrupt the return it never reaches
+	// because it leaves through the tail call to g.
+	f := fns[1]
+	vm.registerFrameIntegrity(f)
+	f.Instructions[len(f.Instructions)-1] ^= 0xFF
+
+	err := vm.Run()
+	if !errors.Is(err, security.ErrIntegrityFailed) {
+		t.Fatalf("expected ErrIntegrityFailed to escape the try, got: %v", err)
+	}
+	if security.SecurityTelemetrySnapshot()["integrity_failed"] == 0 {
+		t.Fatalf("expected integrity failure telemetry from the tail call")
+	}
+}
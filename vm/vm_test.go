@@ -1,6 +1,8 @@
 package vm
 
 import (
+	"bytes"
+	"encoding/gob"
 	"fmt"
 	"mutant/ast"
 	"mutant/compiler"
@@ -10,6 +12,9 @@ import (
 	"mutant/object"
 	"mutant/parser"
 	"mutant/security"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -143,6 +148,10 @@ func testExpectedObject(t *testing.T, expected interface{}, actual object.Object
 		if err := testIntegerObject(int64(expected), actual); err != nil {
 			t.Errorf("testIntegerObject failed: %s", err)
 		}
+	case float64:
+		if err := testFoatObject(expected, actual); err != nil {
+			t.Errorf("testFoatObject failed: %s", err)
+		}
 	case bool:
 		if err := testBooleanObject(bool(expected), actual); err != nil {
 			t.Errorf("testBooleanObject failed: %s", err)
@@ -255,6 +264,113 @@ func TestBooleanExpressions(t *testing.T) {
 	runVMTests(t, tests)
 }
 
+func TestLogicalOperators(t *testing.T) {
+	tests := []vmTestCase{
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"1 && 2", 2},
+		{"false && 1", false},
+		{"0 || 7", 0},
+		{"false || 7", 7},
+		{"let calls = 0; let bump = fn() { calls = calls + 1; true }; false && bump(); true || bump(); calls", 0},
+		{"let calls = 0; let bump = fn() { calls = calls + 1; true }; true && bump(); false || bump(); calls", 2},
+		{"if (false || 1 > 0) { 10 } else { 20 }", 10},
+	}
+	runVMTests(t, tests)
+}
+
+func TestExtendedOperators(t *testing.T) {
+	tests := []vmTestCase{
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"(0 - 2) ** 63 == pow(0 - 2, 63)", true},
+		{"let r = 0; try { 10 ** 19; } catch (e) { r = e.message; }; r", "10 ** 19 is out of range for INTEGER"},
+		{"2 ** -1", 0.5},
+		{"7.5 % 2", 1.5},
+		{"2.0 ** 0.5 * 2.0 ** 0.5 > 1.99", true},
+		{"12 & 10", 8},
+		{"12 | 3", 15},
+		{"12 ^ 10", 6},
+		{"1 << 4", 16},
+		{"-16 >> 2", -4},
+		{"1 << 2 + 1", 8},
+		{"6 & 3 == 2", true},
+		{"1 <= 1", true},
+		{"2 <= 1", false},
+		{"1 >= 1", true},
+		{"1 >= 2", false},
+		{"1.5 >= 1", true},
+		{"1 <= 0.5", false},
+		{`"a" <= "b"`, true},
+		{`"b" < "a"`, false},
+		{`"ab" > "a"`, true},
+		{`"a" >= "a"`, true},
+		{"let x = 5; x += 3; x", 8},
+		{"let x = 5; x -= 3; x", 2},
+		{"let x = 5; x *= 3; x", 15},
+		{"let x = 9; x /= 3; x", 3},
+		{"let f = fn() { let n = 1; n += 41; n }; f()", 42},
+	}
+	runVMTests(t, tests)
+}
+
+func TestExtendedOperatorErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 / 0", "division by zero"},
+		{"1 % 0", "modulo by zero"},
+		{"1 << -1", "negative shift count: -1"},
+		{"2 ** 64", "2 ** 64 is out of range for INTEGER"},
+		{"1.5 & 1", "bitwise operators require integer operands: FLOAT, INTEGER"},
+		{"true > false", "unknown operator: BOOLEAN>BOOLEAN"},
+		{`"a" >= 1`, "type mismatch: STRING>=INTEGER"},
+		{`1 < "a"`, "type mismatch: STRING>INTEGER"},
+	}
+
+	for _, tt := range tests {
+		_, err := runEncryptedVM(tt.input)
+		if err == nil {
+			t.Fatalf("expected error for %q", tt.input)
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
+
+func TestForInLoops(t *testing.T) {
+	tests := []vmTestCase{
+		{"let sum = 0; for (x in [1, 2, 3]) { sum += x; } sum", 6},
+		{"let sum = 0; for (i, x in [5, 6, 7]) { sum += i * x; } sum", 20},
+		{`let out = ""; for (k, v in {"b": 2, "a": 1}) { out = out + k + str(v); } out`, "b2a1"},
+		{`let out = ""; for (k in {"b": 2, "a": 1}) { out = out + k; } out`, "ba"},
+		{`let out = ""; for (ch in "héllo") { if (ch == "l") { break; } out = out + ch; } out`, "hé"},
+		{"let sum = 0; for (i in 0..10) { if (i % 2 == 0) { continue; } sum += i; } sum", 25},
+		{"let n = 0; for (i in 0..4) { for (j in 0..i) { n += 1; } } n", 6},
+		{"let n = 0; for (i in 0..3) { n += 1; } for (i in 0..2) { n += 10; } n", 23},
+		{"let n = 0; let end = 3; for (i in 0..end + 1) { n += i; } n", 6},
+		{"let f = fn(xs) { let total = 0; for (x in xs) { total += x; } total }; f([4, 5]) + f([])", 9},
+		{"let f = fn() { for (i in 1..100) { if (i * i > 50) { return i; } } }; f()", 8},
+		{"let xs = [1, 2]; let n = 0; for (x in xs) { xs = push(xs, x); n += 1; } n", 2},
+	}
+	runVMTests(t, tests)
+}
+
+func TestForInErrors(t *testing.T) {
+	_, err := runEncryptedVM("for (x in 5) { x }")
+	if err == nil || err.Error() != "cannot iterate over INTEGER" {
+		t.Fatalf("expected iteration error, got=%v", err)
+	}
+}
+
 func TestConditionals(t *testing.T) {
 	tests := []vmTestCase{
 		{"if (true) { 10 }", 10},
@@ -289,11 +405,26 @@ func TestStringExpressions(t *testing.T) {
 	runVMTests(t, tests)
 }
 
+func TestStringEscapesAndInterpolation(t *testing.T) {
+	tests := []vmTestCase{
+		{`"a\tb\n\"c\"\\"`, "a\tb\n\"c\"\\"},
+		{`"\u{48}\u{e9}"`, "H\u00e9"},
+		{"`raw \\n\nline`", "raw \\n\nline"},
+		{`let n = 3; "n=${n}, next=${n + 1}"`, "n=3, next=4"},
+		{`let name = "mut"; "${name}${name}"`, "mutmut"},
+		{`let str = 1; "${[1, 2]} \${skip}"`, "[1, 2] ${skip}"},
+		{`let f = fn(x) { x * 2 }; "${f(2)} ${"in${1}ner"}"`, "4 in1ner"},
+	}
+	runVMTests(t, tests)
+}
+
 func TestArrayLiterals(t *testing.T) {
 	tests := []vmTestCase{
 		{"[]", []int{}},
 		{"[1, 2, 3]", []int{1, 2, 3}},
 		{"[1 + 2, 3 * 4, 5 + 6]", []int{3, 12, 11}},
+		// The elements are consumed, so the outer call still finds its callee.
+		{"let f = fn(xs, a, b) { a + b }; [f([1, 2], 3, 4)]", []int{7}},
 	}
 	runVMTests(t, tests)
 }
@@ -487,6 +618,9 @@ func TestCallingFunctionsWithWrongArguments(t *testing.T) {
 		{input: "fn() { 1; }(1);", expected: "wrong number of arguments. want=0, got=1"},
 		{input: "fn(a) { a; }();", expected: "wrong number of arguments. want=1, got=0"},
 		{input: "fn(a, b) { a + b; }(1);", expected: "wrong number of arguments. want=2, got=1"},
+		{input: "fn(a, b = 1) { a + b; }();", expected: "wrong number of arguments. want=1 to 2, got=0"},
+		{input: "fn(a, b = 1) { a + b; }(1, 2, 3);", expected: "wrong number of arguments. want=1 to 2, got=3"},
+		{input: "fn(a, ...r) { a; }();", expected: "wrong number of arguments. want at least 1, got=0"},
 	}
 
 	for _, tt := range tests {
@@ -598,6 +732,7 @@ func TestEncryptObjectSupportsCompositeRuntimeObjects(t *testing.T) {
 	strct := &object.Struct{TypeName: "Point", Fields: map[string]object.Object{"x": &object.Integer{Value: 4}, "y": &object.Integer{Value: 5}}}
 	enumVal := &object.EnumValue{TypeName: "Color", Tag: "Green", Value: &object.Integer{Value: 7}}
 	closure := &object.Closure{Fn: &object.CompiledFunction{Instructions: []byte{1, 2, 3}}, Free: []object.Object{&object.Integer{Value: 11}, hash}}
+	bound := &object.BoundMethod{Receiver: strct, Name: "norm", Method: closure}
 
 	for name, value := range map[string]object.Object{
 		"float":   &object.Float{Value: 3.25},
@@ -607,6 +742,7 @@ func TestEncryptObjectSupportsCompositeRuntimeObjects(t *testing.T) {
 		"struct":  strct,
 		"enum":    enumVal,
 		"closure": closure,
+		"bound":   bound,
 	} {
 		enc, err := mutil.EncryptObject(value, 3, "pwd")
 		if err != nil {
@@ -629,6 +765,16 @@ func TestEncryptObjectSupportsCompositeRuntimeObjects(t *testing.T) {
 			}
 			continue
 		}
+		if name == "bound" {
+			decryptedBound := dec.(*object.BoundMethod)
+			if decryptedBound.Name != "norm" || decryptedBound.Method.Type() != object.CLOSURE_OBJ {
+				t.Fatalf("wrong decrypted bound method: %s %s", decryptedBound.Name, decryptedBound.Method.Type())
+			}
+			if err := testIntegerObject(4, decryptedBound.Receiver.(*object.Struct).Fields["x"]); err != nil {
+				t.Fatalf("wrong decrypted bound receiver: %s", err)
+			}
+			continue
+		}
 		if name == "struct" {
 			decryptedStruct := dec.(*object.Struct)
 			if decryptedStruct.TypeName != "Point" {
@@ -757,8 +903,23 @@ func TestClosureFreeVarsRemainEncryptedAtRest(t *testing.T) {
 		t.Fatalf("wrong number of free vars: got=%d want=1", len(closure.Free))
 	}
 
-	if closure.Free[0].Type() != object.ENCRYPTED_OBJ {
-		t.Fatalf("closure free var stored decrypted: got=%s", closure.Free[0].Type())
+	cell, ok := closure.Free[0].(*object.Cell)
+	if !ok {
+		t.Fatalf("closure free var is not a cell: got=%T", closure.Free[0])
+	}
+	if cell.Value.Type() != object.ENCRYPTED_OBJ {
+		t.Fatalf("closure free var stored decrypted: got=%s", cell.Value.Type())
+	}
+
+	// Encrypting or decrypting the closure copies it but keeps the cell shared.
+	for _, convert := range []func(object.Object, int, string) (object.Object, error){mutil.EncryptObject, mutil.DecryptObject} {
+		converted, err := convert(closure, vm.inslen, vm.password)
+		if err != nil {
+			t.Fatalf("closure conversion failed: %s", err)
+		}
+		if converted.(*object.Closure).Free[0] != cell {
+			t.Fatalf("closure conversion replaced the shared cell")
+		}
 	}
 }
 
@@ -816,7 +977,14 @@ func TestCleanupSensitiveDataWipesRuntimeBuffers(t *testing.T) {
 		t.Fatalf("vm error: %s", err)
 	}
 
-	if vm.stackPointer == 0 {
+	populated := false
+	for _, entry := range vm.stack {
+		if entry != nil {
+			populated = true
+			break
+		}
+	}
+	if !populated {
 		t.Fatalf("expected stack to have entries before cleanup")
 	}
 
@@ -1003,6 +1171,93 @@ func TestRecursiveFunctions(t *testing.T) {
 	runVMTests(t, tests)
 }
 
+func TestMutableCapturedVariables(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			// Closures over the same variable share one cell.
+			`let counter = fn() {
+				let n = 0;
+				[fn() { n = n + 1; n }, fn() { n }]
+			};
+			let c = counter();
+			c[0](); c[0]();
+			c[1]()`,
+			2,
+		},
+		{
+			// Each call of the outer function gets its own cell.
+			`let make = fn() { let n = 0; fn() { n = n + 1 } };
+			let a = make(); let b = make();
+			a(); a(); b();
+			[a(), b()]`,
+			[]int{3, 2},
+		},
+		{
+			// Assignments two closures deep reach the declaring function.
+			`let outer = fn() {
+				let total = 0;
+				let add = fn(k) { let apply = fn() { total = total + k }; apply(); total };
+				add(2); add(3);
+				total
+			};
+			outer()`,
+			5,
+		},
+		{
+			// The declaring function sees later assignments made by closures
+			// and closures see later assignments made by the function.
+			`let f = fn() {
+				let x = 1;
+				let read = fn() { x };
+				x = 10;
+				let before = read();
+				let write = fn() { x = x + 1 };
+				write();
+				[before, x]
+			};
+			f()`,
+			[]int{10, 11},
+		},
+		{
+			`let acc = fn() { let seen = []; fn(v) { seen = push(seen, v); len(seen) } }();
+			acc(1); acc(2);
+			acc(3)`,
+			3,
+		},
+		{
+			// A let in a loop body binds a fresh variable on every iteration.
+			`let fns = fn() {
+				let out = [];
+				for (i in 0..3) { let j = i * 10; out = push(out, fn() { j }) }
+				out
+			}();
+			[fns[0](), fns[1](), fns[2]()]`,
+			[]int{0, 10, 20},
+		},
+		{
+			`struct P { x; }
+			let f = fn() {
+				let p = P { x: 1 };
+				let move = fn() { p.x = p.x + 5 };
+				move(); move();
+				p.x
+			};
+			f()`,
+			11,
+		},
+		{
+			// Parameters are captured the same way as locals.
+			`let make = fn(start) { fn() { start = start * 2 } };
+			let double = make(3);
+			double();
+			double()`,
+			12,
+		},
+	}
+
+	runVMTests(t, tests)
+}
+
 func TestRecursiveFibonacci(t *testing.T) {
 	tests := []vmTestCase{
 		{
@@ -1090,3 +1345,828 @@ func TestPhase3LoopStructEnumFeatures(t *testing.T) {
 
 	runVMTests(t, tests)
 }
+
+func TestImportedModules(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.mut": `
+		import "lib/counter.mut"
+		import "lib/counter.mut" as c2
+		let bump = 100;
+		counter.bump();
+		c2.bump();
+		counter.count = counter.count + 10;
+		[counter.count, counter.twice(bump), bump];
+		`,
+		"lib/counter.mut": `
+		import math
+		let count = 0;
+		let bump = fn() { count = count + 1; };
+		let twice = fn(x) { math.double(x) };
+		`,
+		"lib/math.mut": `let double = fn(x) { x * 2 };`,
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	comp := compiler.New()
+	if err := comp.SetSourceFile(filepath.Join(dir, "main.mut")); err != nil {
+		t.Fatal(err)
+	}
+	if err := comp.Compile(parse(files["main.mut"])); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	byteCode := comp.ByteCode()
+	password := fmt.Sprint(security.DerivePasswordFromInstructions(byteCode.Instructions))
+	byteCode = mutil.EncryptByteCode(byteCode, password)
+
+	vm := NewWithGlobalStoreAndPassword(byteCode, make([]object.Object, global.GlobalSize), password)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	testExpectedObject(t, []int{12, 200, 100}, vm.LastPoppedStackElement())
+}
+
+func TestTryCatchFinally(t *testing.T) {
+	tests := []vmTestCase{
+		{`let r = 0; try { throw 5; } catch (e) { r = e; }; r`, 5},
+		{`let m = ""; try { 1 / 0; } catch (e) { m = e.message; }; m`, "division by zero"},
+		{`let m = ""; try { len(1); } catch (e) { m = e.message; }; m`, "argument to `len` not supported, got INTEGER"},
+		{
+			`
+			let f = fn(n) { if (n == 0) { throw "bottom"; } f(n - 1) + 1 };
+			let r = "";
+			try { f(3); } catch (e) { r = e; };
+			r
+			`,
+			"bottom",
+		},
+		{
+			// The partially built array is discarded when the handler runs.
+			`
+			let g = fn() { throw 1 };
+			let r = 0;
+			try { r = 1 + [2, 3, g()][0]; } catch (e) { r = 10; };
+			r + 1
+			`,
+			11,
+		},
+		{
+			`
+			let s = "";
+			let f = fn() { try { s = s + "t"; return 1; } finally { s = s + "f"; } };
+			let r = f();
+			s + str(r)
+			`,
+			"tf1",
+		},
+		{`let s = ""; try { try { throw "x"; } finally { s = s + "f"; } } catch (e) { s = s + e; }; s`, "fx"},
+		{`let s = ""; try { throw "a"; } catch (e) { s = e; } finally { s = s + "f"; }; s`, "af"},
+		{`let s = ""; try { try { throw "a"; } catch (e) { throw e + "b"; } finally { s = "f"; } } catch (e) { s = s + e; }; s`, "fab"},
+		{`let r = ""; try { try { 1 / 0; } catch (e) { throw e; } } catch (e) { r = e.message; }; r`, "division by zero"},
+		{
+			`
+			let s = "";
+			for (i in 0..4) {
+				try {
+					if (i == 1) { continue; }
+					if (i == 3) { break; }
+					s = s + str(i);
+				} finally {
+					s = s + "f";
+				}
+			}
+			s
+			`,
+			"0ff2ff",
+		},
+		{`let r = 0; try { r = 1; } catch { r = 2; }; r`, 1},
+		{`let x = if (true) { try { 1 } catch { 2 } }; x`, global.Null},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestUncaughtExceptions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`throw "boom";`, "uncaught exception: boom"},
+		{`throw [1, 2];`, "uncaught exception: [1, 2]"},
+		{`try { throw 1; } catch (e) { 1 / 0; }`, "division by zero"},
+		{`try { 1 / 0; } finally { 2; }`, "division by zero"},
+	}
+
+	for _, tt := range tests {
+		_, err := runEncryptedVM(tt.input)
+		if err == nil {
+			t.Fatalf("expected VM error for %q", tt.input)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong VM error for %q. want=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+
+	// Without an active handler builtin failures remain plain values.
+	vm, err := runEncryptedVM(`len(1);`)
+	if err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	if _, ok := vm.LastPoppedStackElement().(*object.Error); !ok {
+		t.Errorf("expected builtin error value outside try. got=%T", vm.LastPoppedStackElement())
+	}
+}
+
+func TestBuiltinErrorsRaiseInsideTryBlocks(t *testing.T) {
+	safe := `let safe = fn(s) { let r = int(s); if (is_error(r)) { return -1; } r };`
+	tests := []vmTestCase{
+		{safe + `safe("zz")`, -1},
+		{safe + `let r = 0; try { r = safe("zz"); } catch (e) { r = 99; }; r`, 99},
+		{`let g = fn() { fs_read("/missing/file") }; let r = 0; try { g(); } catch (e) { r = 99; }; r`, 99},
+		{`let g = fn() { let v = 0; try { v = int("zz"); } catch (e) { v = 7; }; v }; let r = 0; try { r = g(); } catch (e) { r = 99; }; r`, 7},
+		{`let r = 0; try { r = len(map(["1", "zz"], fn(s) { int(s) })); } catch (e) { r = 99; }; r`, 99},
+		{safe + `let r = 0; try { r = int("zz"); } catch (e) { r = 99; }; r`, 99},
+		{`let r = 0; try { throw 1; } catch (e) { r = is_error(int("zz")); }; r`, true},
+		{`let r = 0; try { r = len(map(["1", "zz"], int)); } catch (e) { r = 99; }; r`, 99},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestEnumPayloadsAndMatch(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			`
+			enum Shape { Circle(r), Square(side), Empty }
+			let area = fn(s) {
+				match (s) {
+					Shape.Circle(r) => 3 * r * r,
+					Shape.Square(n) => n * n,
+					Shape.Empty => 0,
+				}
+			};
+			area(Shape.Circle(2)) + area(Shape.Square(3)) + area(Shape.Empty)
+			`,
+			21,
+		},
+		{
+			`
+			enum Result { Ok(value), Err(message) }
+			let r = Result.Err("bad");
+			match (r) { Result.Ok(v) => v, Result.Err(m) => "error: " + m }
+			`,
+			"error: bad",
+		},
+		{`enum Opt { Some(v), None } match (Opt.Some(4)) { Opt.Some(_) => "some", _ => "other" }`, "some"},
+		{`enum Opt { Some(v), None } match (Opt.None) { Opt.Some(v) => v, _ => "none" }`, "none"},
+		{`match (2) { 1 => "one", 2 => "two", _ => "many" }`, "two"},
+		{`match ("x") { "y" => 1, _ => { let z = 5; z * 2 } }`, 10},
+		{`match (-1) { -1 => "neg", _ => "pos" }`, "neg"},
+		{`let n = 3; let r = match (n) { 3 => { n = 4; }, _ => 0 }; n`, 4},
+		{
+			`
+			enum Opt { Some(v), None }
+			let sum = 0;
+			for (x in [Opt.Some(1), Opt.None, Opt.Some(5)]) {
+				sum = sum + match (x) { Opt.Some(v) => match (v) { 1 => 10, _ => v }, Opt.None => 0 };
+			}
+			sum
+			`,
+			15,
+		},
+	}
+
+	runVMTests(t, tests)
+
+	vm, err := runEncryptedVM(`enum Opt { Some(v), None } let o = Opt.Some(42); o`)
+	if err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	stored, ok := vm.globals[0].(*object.EnumValue)
+	if !ok || stored.Value == nil || stored.Value.Type() != object.ENCRYPTED_OBJ {
+		t.Fatalf("expected enum payload to remain encrypted at rest, got=%#v", vm.globals[0])
+	}
+	if got := vm.LastPoppedStackElement().Inspect(); got != "Opt.Some(42)" {
+		t.Errorf("wrong enum inspect. want=%q, got=%q", "Opt.Some(42)", got)
+	}
+}
+
+func TestMatchSubjectTypeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`enum Opt { Some(v), None } match (1) { Opt.Some(v) => v, _ => 0 }`, "cannot match 1 against Opt"},
+		{`enum A { X } enum B { Y } match (B.Y) { A.X => 1, _ => 0 }`, "cannot match B.Y(0) against A"},
+	}
+
+	for _, tt := range tests {
+		_, err := runEncryptedVM(tt.input)
+		if err == nil {
+			t.Fatalf("expected VM error for %q", tt.input)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong VM error for %q. want=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
+
+func TestStructMethods(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			`
+			struct Point {
+				x; y;
+				fn norm2() { self.x * self.x + self.y * self.y }
+				fn scaled(k) { Point { x: self.x * k, y: self.y * k } }
+			}
+			let p = Point { x: 3, y: 4 };
+			p.norm2() + p.scaled(2).norm2()
+			`,
+			125,
+		},
+		{
+			// Methods can call each other through self and recurse.
+			`
+			struct Counter {
+				n;
+				fn sum() { if (self.n == 0) { 0 } else { self.n + Counter { n: self.n - 1 }.sum() } }
+				fn twice() { self.sum() * 2 }
+			}
+			Counter { n: 4 }.twice()
+			`,
+			20,
+		},
+		{
+			// A method read without calling it keeps its receiver.
+			`
+			struct Greeter { name; fn greet(greeting) { greeting + ", " + self.name } }
+			let g = Greeter { name: "ann" };
+			let f = g.greet;
+			g.name = "bob";
+			f("hi") + " / " + g.greet("yo")
+			`,
+			"hi, ann / yo, bob",
+		},
+		{
+			// The receiver is passed by value, like any struct assignment.
+			`
+			struct Box { v; fn bump() { self.v = self.v + 1; self } }
+			let b = Box { v: 1 };
+			let c = b.bump();
+			[b.v, c.v]
+			`,
+			[]int{1, 2},
+		},
+		{
+			`
+			let limit = 10;
+			struct Clamp { v; fn get() { if (self.v > limit) { limit } else { self.v } } }
+			let f = fn(c) { c.get() };
+			f(Clamp { v: 50 }) + f(Clamp { v: 3 })
+			`,
+			13,
+		},
+		{
+			// A field holding a function is called as before.
+			`struct Holder { f; } let h = Holder { f: fn(x) { x * 2 } }; let g = h.f; g(21)`,
+			42,
+		},
+	}
+
+	runVMTests(t, tests)
+
+	_, err := runEncryptedVM(`struct P { x; fn m(a) { a } } P { x: 1 }.m()`)
+	if err == nil || err.Error() != "wrong number of arguments. want=1, got=0" {
+		t.Fatalf("wrong method arity error: %v", err)
+	}
+}
+
+func TestDefaultAndRestParameters(t *testing.T) {
+	tests := []vmTestCase{
+		{`let f = fn(x, timeout = 3000) { x + timeout }; [f(1), f(1, 2)]`, []int{3001, 3}},
+		{
+			// Defaults are evaluated at call time and may use earlier parameters.
+			`let n = 0;
+			let next = fn() { n = n + 1; n };
+			let f = fn(a, b = a * 10, c = next()) { [a, b, c] };
+			let r1 = f(1); let r2 = f(2, 3); let r3 = f(4, 5, 6);
+			[r1[0], r1[1], r1[2], r2[1], r2[2], r3[2]]`,
+			[]int{1, 10, 1, 3, 2, 6},
+		},
+		{`let f = fn(first, ...rest) { len(rest) * 10 + first }; [f(1), f(1, 2, 3)]`, []int{1, 21}},
+		{`let f = fn(...xs) { push(xs, len(xs)) }; f(7, 8)`, []int{7, 8, 2}},
+		{
+			`let total = fn(first, ...rest) { let t = first; for (x in rest) { t = t + x } t };
+			total(1, 2, 3, 4)`,
+			10,
+		},
+		{`let f = fn(a, b = 2, ...r) { a * 100 + b * 10 + len(r) }; [f(1), f(1, 5), f(1, 5, 9, 9)]`, []int{120, 150, 152}},
+		{
+			// A default can close over variables of the enclosing function.
+			`let make = fn(base) { fn(x = base) { x * 2 } }; let g = make(21); [g(), g(1)]`,
+			[]int{42, 2},
+		},
+		{
+			`struct Range { lo; fn span(hi = self.lo + 10, ...extra) { hi - self.lo + len(extra) } }
+			let r = Range { lo: 5 };
+			[r.span(), r.span(8), r.span(8, 0, 0)]`,
+			[]int{10, 3, 5},
+		},
+	}
+
+	runVMTests(t, tests)
+
+	_, err := runEncryptedVM(`struct P { x; fn m(a, b = 1) { a } } P { x: 1 }.m()`)
+	if err == nil || err.Error() != "wrong number of arguments. want=1 to 2, got=0" {
+		t.Fatalf("wrong method arity error: %v", err)
+	}
+}
+
+func TestStructMethodsSurviveGobEncoding(t *testing.T) {
+	comp := compiler.New()
+	if err := comp.Compile(parse(`struct P { x; fn double() { self.x * 2 } } P { x: 21 }.double()`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	byteCode := comp.ByteCode()
+	password := fmt.Sprint(security.DerivePasswordFromInstructions(byteCode.Instructions))
+	byteCode = mutil.EncryptByteCode(byteCode, password)
+
+	for _, typ := range []object.Object{&object.Integer{}, &object.String{}, &object.CompiledFunction{}, &object.Encrypted{}} {
+		gob.Register(typ)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(byteCode); err != nil {
+		t.Fatalf("gob encode failed: %s", err)
+	}
+	var decoded *compiler.ByteCode
+	if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+		t.Fatalf("gob decode failed: %s", err)
+	}
+	if _, ok := decoded.StructMethods["P"]["double"]; !ok {
+		t.Fatalf("struct methods lost in gob round trip: %v", decoded.StructMethods)
+	}
+
+	vm := NewWithGlobalStoreAndPassword(decoded, make([]object.Object, global.GlobalSize), password)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	if err := testIntegerObject(42, vm.LastPoppedStackElement()); err != nil {
+		t.Fatalf("wrong method result: %s", err)
+	}
+}
+
+func TestDestructuringLet(t *testing.T) {
+	tests := []vmTestCase{
+		{`let [a, b, ...rest] = [1, 2, 3, 4]; a + b + len(rest) * 10 + rest[1]`, 27},
+		{`let [first, ...others] = ["x"]; first + str(len(others))`, "x0"},
+		{`let [_, second, _] = [1, 2, 3]; second`, 2},
+		{`let {status, body} = {"status": 200, "body": "ok", "extra": 1}; str(status) + body`, "200ok"},
+		{`struct Point { x; y; } let Point {y, x} = Point { x: 3, y: 4 }; x * 10 + y`, 34},
+		{
+			`
+			let f = fn(pair) {
+				let [a, b] = pair;
+				let {n} = {"n": a * b};
+				n
+			};
+			f([6, 7])
+			`,
+			42,
+		},
+		{`let r = ""; try { let {missing} = {"a": 1}; } catch (e) { r = e.message; }; r`, `missing key "missing" in destructured hash`},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let [a, b] = [1];`, "array pattern expects 2 elements, got 1"},
+		{`let [a] = [1, 2];`, "array pattern expects 1 elements, got 2"},
+		{`let [a, b, ...c] = [1];`, "array pattern expects at least 2 elements, got 1"},
+		{`let [a] = "ab";`, "cannot destructure STRING as an array"},
+		{`let {a} = [1];`, "cannot destructure ARRAY as a hash"},
+		{`let {a} = {"b": 1};`, `missing key "a" in destructured hash`},
+		{`struct P { x; } struct Q { x; } let P {x} = Q { x: 1 };`, "cannot destructure Q as P"},
+		{`struct P { x; } let P {x} = 5;`, "cannot destructure INTEGER as P"},
+	}
+
+	for _, tt := range tests {
+		_, err := runEncryptedVM(tt.input)
+		if err == nil {
+			t.Fatalf("expected VM error for %q", tt.input)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong VM error for %q. want=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			`let sum = fn(n, acc) { if (n == 0) { return acc; } sum(n - 1, acc + n) };
+			sum(20000, 0)`,
+			200010000,
+		},
+		{
+			`let isOdd = fn(n, even) { if (n == 0) { false } else { even(n - 1, isOdd) } };
+			let isEven = fn(n, odd) { if (n == 0) { true } else { odd(n - 1, isEven) } };
+			if (isEven(10001, isOdd)) { 1 } else { 0 }`,
+			0,
+		},
+		{
+			`struct Counter {
+				n;
+				fn down(k) { if (k == 0) { return self.n; } Counter { n: self.n + 1 }.down(k - 1) }
+			}
+			Counter { n: 0 }.down(10000)`,
+			10000,
+		},
+		{
+			`let walk = fn(xs, i, acc) {
+				if (i == len(xs)) { return acc; }
+				return walk(xs, i + 1, push(acc, xs[i] * 2));
+			};
+			walk([1, 2, 3], 0, [])`,
+			[]int{2, 4, 6},
+		},
+		{
+			// A builtin in tail position returns its result to the caller.
+			`let size = fn(xs) { len(xs) }; size([1, 2, 3]) + 1`,
+			4,
+		},
+		{
+			// Defaults and closures still work for the frame that is reused.
+			`let count = fn(n, step = 1) { if (n <= 0) { return 0; } 1 + count(n - step, step) };
+			let loop = fn(n, acc = 0) { if (n == 0) { acc } else { loop(n - 1, acc + 2) } };
+			[count(10, 2), loop(10000)]`,
+			[]int{5, 20000},
+		},
+		{
+			// A handler in a caller still catches errors from a tail callee.
+			`let fail = fn(n) { if (n == 0) { throw "done"; } fail(n - 1) };
+			let r = "";
+			try { fail(10000); } catch (e) { r = e; };
+			r`,
+			"done",
+		},
+	}
+
+	runVMTests(t, tests)
+
+	vm, err := runEncryptedVM(`let down = fn(n) { if (n == 0) { return 0; } down(n - 1) }; down(20000)`)
+	if err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	if len(vm.frames) > global.MaxFrames {
+		t.Errorf("frames grew during tail recursion. want<=%d, got=%d", global.MaxFrames, len(vm.frames))
+	}
+}
+
+func TestOptimizedProgramsBehaveTheSame(t *testing.T) {
+	inputs := []string{
+		`let x = 2 * 3 + 1; if (x > 5) { x * (10 - 8) } else { 0 }`,
+		`let f = fn(n) { if (true) { return n + 1 - 1; } n * 1000 }; f(41) + 1`,
+		`let out = []; for (let i = 0; i < 10; i = i + 1) { if (i % 2 == 0) { continue; } if (i > 7) { break; } out = push(out, i * (1 + 1)); } out`,
+		`let total = 0; for (k, v in {"a": 1 + 1, "b": 3}) { total = total + v; } total`,
+		`let g = fn(a, b) { if (a) { if (b) { 1 } else { 2 } } else { 3 } }; [g(true, true), g(true, false), g(false, true)]`,
+		`let r = ""; try { throw "x" + "y"; r = "unreachable"; } catch (e) { r = e + "!" + "!"; } finally { r = r + "-" + "done"; }; r`,
+		`enum Shape { Circle(r), Square(s) } let area = fn(shape) { match (shape) { Shape.Circle(r) => 3 * r * r, Shape.Square(side) => side * side } }; area(Shape.Square(2 + 2))`,
+		`let sum = fn(n, acc = 0) { if (n == 0) { return acc; } sum(n - 1, acc + n) }; sum(100)`,
+		`let c = fn() { let n = 0; fn() { n = n + (2 - 1); n } }(); c(); c(); c()`,
+		`if (false) { 1 }`,
+		`let s = "a" + "b"; s + "${1 + 2}"`,
+	}
+
+	run := func(input string, level int) (string, error) {
+		comp := compiler.New()
+		comp.EnableOptimization(level)
+		if err := comp.Compile(parse(input)); err != nil {
+			return "", err
+		}
+		byteCode := comp.ByteCode()
+		password := fmt.Sprint(security.DerivePasswordFromInstructions(byteCode.Instructions))
+		byteCode = mutil.EncryptByteCode(byteCode, password)
+
+		vm := NewWithGlobalStoreAndPassword(byteCode, make([]object.Object, global.GlobalSize), password)
+		if err := vm.Run(); err != nil {
+			return "", err
+		}
+		return vm.LastPoppedStackElement().Inspect(), nil
+	}
+
+	for _, input := range inputs {
+		want, err := run(input, compiler.OptimizeNone)
+		if err != nil {
+			t.Fatalf("unoptimized run of %q failed: %s", input, err)
+		}
+		for _, level := range []int{compiler.OptimizeBasic, compiler.OptimizeFull} {
+			got, err := run(input, level)
+			if err != nil {
+				t.Fatalf("run of %q at -O%d failed: %s", input, level, err)
+			}
+			if got != want {
+				t.Errorf("%q at -O%d: want=%s, got=%s", input, level, want, got)
+			}
+		}
+	}
+}
+
+func TestHigherOrderBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`map([1, 2, 3], fn(x) { x * 10 })`, []int{10, 20, 30}},
+		{`filter([1, 2, 3, 4], fn(x) { x % 2 == 0 })`, []int{2, 4}},
+		{`reduce([1, 2, 3, 4], fn(acc, x) { acc + x }, 0)`, 10},
+		{`reduce([], fn(acc, x) { acc + x }, 7)`, 7},
+		{`sort_by([3, 1, 2], fn(x) { x })`, []int{1, 2, 3}},
+		{`sort_by([3, 1, 2], fn(x) { 0 - x })`, []int{3, 2, 1}},
+		{`sort_by([[2, 1], [1, 2], [1, 3]], fn(p) { p[0] })[1][1]`, 3},
+		{`sort_by(["ccc", "a", "bb"], fn(s) { s })[0]`, "a"},
+		{`any([1, 2, 3], fn(x) { x == 2 })`, true},
+		{`any([], fn(x) { true })`, false},
+		{`all([1, 2, 3], fn(x) { x > 0 })`, true},
+		{`all([1, 2, 3], fn(x) { x > 1 })`, false},
+		{`let n = 0; each([1, 2, 3], fn(x) { n = n + x; }); n`, 6},
+		{`let n = 0; any([1, 2, 3, 4], fn(x) { n = n + 1; x == 2 }); n`, 2},
+		{`map([[1], [2, 3]], len)`, []int{1, 2}},
+		{
+			// Callbacks may recurse, call builtins and nest other callbacks.
+			`
+			let fact = fn(n) { if (n < 2) { return 1; } n * fact(n - 1) };
+			let sums = map([[1, 2], [3, 4]], fn(a) { reduce(a, fn(s, x) { s + x }, 0) });
+			reduce(map(sums, fact), fn(acc, x) { acc + x }, 0)
+			`,
+			6 + 5040,
+		},
+		{
+			`
+			struct Scale { k; fn apply(x) { x * self.k } }
+			map([1, 2], Scale { k: 5 }.apply)
+			`,
+			[]int{5, 10},
+		},
+		{
+			// Tail calls inside a callback stay within the callback.
+			`
+			let count = fn(n, acc) { if (n == 0) { return acc; } count(n - 1, acc + 1) };
+			map([100, 200], fn(n) { count(n, 0) })
+			`,
+			[]int{100, 200},
+		},
+		{`map([1, 2], fn(x) { let r = 0; try { throw x; } catch (e) { r = e * 2; }; r })`, []int{2, 4}},
+		{`let r = ""; try { map([1, 2], fn(x) { if (x == 2) { throw "bad"; } x }); } catch (e) { r = e; }; r`, "bad"},
+		{`let r = ""; try { each([0], fn(x) { 1 / x }); } catch (e) { r = e.message; }; r`, "division by zero"},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestHigherOrderBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`map(1, fn(x) { x })`, "argument to `map` must be ARRAY, got INTEGER"},
+		{`filter([1], 2)`, "second argument to `filter` must be a function, got INTEGER"},
+		{`reduce([1], fn(a, b) { a })`, "wrong number of arguments. got=2, want=3"},
+		{`sort_by([1, "a"], fn(x) { x })`, "sort_by keys must be all numbers or all strings, got INTEGER and STRING"},
+	}
+
+	for _, tt := range tests {
+		vm, err := runEncryptedVM(tt.input)
+		if err != nil {
+			t.Fatalf("vm error for %q: %s", tt.input, err)
+		}
+		errObj, ok := vm.LastPoppedStackElement().(*object.Error)
+		if !ok {
+			t.Fatalf("expected error value for %q. got=%T", tt.input, vm.LastPoppedStackElement())
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		}
+	}
+
+	// An uncaught exception in a callback ends the program and leaves no
+	// callback frames behind.
+	_, err := runEncryptedVM(`map([1], fn(x) { throw "boom" });`)
+	if err == nil || err.Error() != "uncaught exception: boom" {
+		t.Fatalf("expected uncaught exception from callback, got %v", err)
+	}
+	_, err = runEncryptedVM(`map([1], fn(x, y) { x });`)
+	if err == nil || !strings.Contains(err.Error(), "wrong number of arguments") {
+		t.Fatalf("expected arity error from callback, got %v", err)
+	}
+}
+
+func TestCallFunctionRestoresStateAfterErrors(t *testing.T) {
+	vm, err := runEncryptedVM(`let boom = fn(x) { let y = x + 1; throw y }; let ok = fn(x) { x * 2 }; 0`)
+	if err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	boom := vm.globals[0]
+	ok := vm.globals[1]
+
+	sp, frames := vm.stackPointer, vm.frameIndex
+	if _, err := vm.CallFunction(boom, &object.Integer{Value: 1}); err == nil {
+		t.Fatalf("expected thrown value to be returned as error")
+	} else if value := exceptionValue(err); value.Inspect() != "2" {
+		t.Fatalf("wrong thrown value. got=%s", value.Inspect())
+	}
+	if vm.stackPointer != sp || vm.frameIndex != frames || vm.unwindFloor != 0 {
+		t.Fatalf("state not restored: sp=%d/%d frames=%d/%d floor=%d",
+			vm.stackPointer, sp, vm.frameIndex, frames, vm.unwindFloor)
+	}
+
+	result, err := vm.CallFunction(ok, &object.Integer{Value: 21})
+	if err != nil {
+		t.Fatalf("call error: %s", err)
+	}
+	if err := testIntegerObject(42, result); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := vm.CallFunction(&object.Integer{Value: 1}); err == nil {
+		t.Fatalf("expected calling a non-function to fail")
+	}
+}
+
+func TestStringBuiltinsInPrograms(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			`
+			let out = "PORT   STATE\n22/tcp open\n80/tcp closed\n443/tcp open\n";
+			let open = filter(split(trim(out), "\n"), fn(l) { ends_with(l, "open") });
+			join(map(open, fn(l) { substr(l, 0, index_of(l, "/")) }), ",")
+			`,
+			"22,443",
+		},
+		{`pad(upper("ok"), -4, ".") + "|" + repeat("=", 3)`, "OK..|==="},
+		{`let r = ""; try { repeat("x", -1); } catch (e) { r = e.message; }; r`, "repeat count must not be negative, got -1"},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestJsonBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`json_encode(json_decode("{\"z\": 1, \"a\": [1, 2.5], \"m\": {\"k\": null}}"))`, `{"z":1,"a":[1,2.5],"m":{"k":null}}`},
+		{`let h = json_decode("{\"hosts\": [{\"ip\": \"10.0.0.1\", \"open\": [22, 80]}]}"); h["hosts"][0]["open"][1]`, 80},
+		{`struct P { y; x; } json_encode([P { x: 1, y: 2 }, true])`, `[{"struct":"P","fields":{"y":2,"x":1}},true]`},
+		{`enum Shape { Dot, Circle(r), Square(side), Empty } json_encode([Shape.Dot, Shape.Circle(2), Shape.Square(2), Shape.Empty])`,
+			`[{"enum":"Shape","tag":"Dot"},{"enum":"Shape","tag":"Circle","value":2},{"enum":"Shape","tag":"Square","value":2},{"enum":"Shape","tag":"Empty"}]`},
+		{`let r = ""; try { json_decode("{oops"); } catch (e) { r = e.message; }; r`, "json_decode: invalid character 'o' looking for beginning of value at offset 2"},
+		{`let r = ""; try { json_encode([fn() { 1 }]); } catch (e) { r = e.message; }; r`, "json_encode: cannot encode CLOSURE"},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestHashOrderSurvivesEncryptionAndGob(t *testing.T) {
+	hash := &object.Hash{}
+	for _, key := range []string{"zeta", "alpha", "mid"} {
+		keyObj := &object.String{Value: key}
+		hash.Set(keyObj.HashKey(), object.HashPair{Key: keyObj, Value: &object.Integer{Value: int64(len(key))}})
+	}
+
+	enc, err := mutil.EncryptObject(hash, 3, "pwd")
+	if err != nil {
+		t.Fatalf("encrypt failed: %s", err)
+	}
+
+	for _, typ := range []object.Object{&object.Hash{}, &object.Encrypted{}} {
+		gob.Register(typ)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&enc); err != nil {
+		t.Fatalf("gob encode failed: %s", err)
+	}
+	var decoded object.Object
+	if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+		t.Fatalf("gob decode failed: %s", err)
+	}
+
+	dec, err := mutil.DecryptObject(decoded, 3, "pwd")
+	if err != nil {
+		t.Fatalf("decrypt failed: %s", err)
+	}
+
+	var keys []string
+	for _, pair := range dec.(*object.Hash).OrderedPairs() {
+		keys = append(keys, pair.Key.Inspect())
+	}
+	if got := strings.Join(keys, ","); got != "zeta,alpha,mid" {
+		t.Fatalf("wrong key order after encryption and gob: %s", got)
+	}
+}
+
+func TestRegexpBuiltinsInPrograms(t *testing.T) {
+	tests := []vmTestCase{
+		{`re_match("^SSH-2\\.0-OpenSSH_(\\d+)", "SSH-2.0-OpenSSH_8.9p1")`, true},
+		{`len(re_find_all("\\b\\d{1,3}(\\.\\d{1,3}){3}\\b", "from 10.0.0.1 to 192.168.1.20"))`, 2},
+		{`re_captures("(?P<user>\\w+)@(?P<host>[\\w.]+)", "mail root@example.org now")["host"]`, "example.org"},
+		{`join(re_split(",\\s*", "a, b,c"), "|")`, "a|b|c"},
+		{`let r = ""; try { re_match("(", "x"); } catch (e) { r = e.message; }; r`, "re_match: error parsing regexp: missing closing ): `(`"},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestRegexpCacheIsPerProgram(t *testing.T) {
+	input := `
+	let n = 0;
+	for (let i = 0; i < 50; i = i + 1) {
+		if (re_match("^\\d+$", str(i))) { n = n + 1; }
+		if (re_match("[a-z]", str(i))) { n = n - 1; }
+	}
+	n
+	`
+	first, err := runEncryptedVM(input)
+	if err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	if err := testIntegerObject(50, first.LastPoppedStackElement()); err != nil {
+		t.Fatal(err)
+	}
+	if got := first.Regexps().Len(); got != 2 {
+		t.Fatalf("expected 2 cached patterns, got %d", got)
+	}
+
+	second, err := runEncryptedVM(`1`)
+	if err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	if got := second.Regexps().Len(); got != 0 {
+		t.Fatalf("expected a new program to start with an empty cache, got %d", got)
+	}
+}
+
+func TestMathAndConversionBuiltinsInPrograms(t *testing.T) {
+	tests := []vmTestCase{
+		{`int("40") + 2`, 42},
+		{`parse_int("ff", 16) + int(2.9)`, 257},
+		{`max(map([3, -8, 5], abs))`, 8},
+		{`floor(sqrt(float("50")))`, 7},
+		{`pow(3, 4) - round(log(100, 10))`, 79},
+		{`let max = 3; max`, 3},
+		{`let r = ""; try { int("4x"); } catch (e) { r = e.message; }; r`, `int: cannot parse "4x" as INTEGER`},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestIntrospectionBuiltinsInPrograms(t *testing.T) {
+	structs := `
+	struct Point { x, y }
+	enum Shape { Circle(r), Dot }
+	`
+	tests := []vmTestCase{
+		{structs + `type_of(Point { x: 1, y: 2 })`, "Point"},
+		{structs + `type_of(Shape.Dot)`, "Shape"},
+		{structs + `type_of([1])`, "ARRAY"},
+		{structs + `join(map([1, 2.5, "a", first([]), true], type_of), ",")`, "INTEGER,FLOAT,STRING,NULL,BOOLEAN"},
+		{structs + `is_number(2.5) && is_struct(Point { x: 1, y: 2 }) && !is_enum(1) && is_function(len)`, true},
+		{structs + `join(fields(Point { y: 2, x: 1 }), ",")`, "x,y"},
+		{structs + `let p = Point { x: 1, y: 2 }; let q = set_field(p, "x", 5); get_field(q, "x") + p.x`, 6},
+		{structs + `has_field(Point { x: 1, y: 2 }, "z")`, false},
+		{structs + `join(enum_variants(Shape), ",")`, "Circle,Dot"},
+		{structs + `join(enum_variants(Shape.Circle(2)), ",") == join(enum_variants("Shape"), ",")`, true},
+		{structs + `enum_tag(Shape.Circle(3))`, "Circle"},
+		{structs + `let r = ""; try { get_field(Point { x: 1, y: 2 }, "z"); } catch (e) { r = e.message; }; r`, "struct Point has no field z"},
+		{structs + `let r = ""; try { enum_variants("Color"); } catch (e) { r = e.message; }; r`, "unknown enum type Color"},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestHashBuiltinsInPrograms(t *testing.T) {
+	tests := []vmTestCase{
+		{`join(keys({"zeta": 1, "alpha": 2, "mid": 3}), ",")`, "zeta,alpha,mid"},
+		{`values({"b": 2, "a": 1, "c": 3})`, []int{2, 1, 3}},
+		{`let h = {"x": 1, "y": 2}; let out = ""; for (pair in items(h)) { out = out + pair[0] + str(pair[1]); } out`, "x1y2"},
+		{`let h = {"a": 1}; has_key(h, "a") && !has_key(h, "b")`, true},
+		{`let h = {"a": 1, "b": 2, "c": 3}; let d = delete(h, "b"); join(keys(d), ",") + str(hash_len(h))`, "a,c3"},
+		{`join(keys(merge({"a": 1, "b": 2}, {"c": 3, "a": 9})), ",")`, "a,b,c"},
+		{`merge({"a": 1, "b": 2}, {"a": 9})["a"]`, 9},
+		{`json_encode({"b": 1, "a": {"d": 2, "c": 3}})`, `{"b":1,"a":{"d":2,"c":3}}`},
+		{`str({"b": 1, "a": 2})`, "{b: 1, a: 2}"},
+		{`let h = {"b": 1, "a": 2, "b": 3}; join(map(items(h), fn(p) { p[0] + str(p[1]) }), ",")`, "b3,a2"},
+		{`let r = ""; try { keys([1]); } catch (e) { r = e.message; }; r`, "argument 1 to `keys` must be HASH, got ARRAY"},
+	}
+
+	runVMTests(t, tests)
+}